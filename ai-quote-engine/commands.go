@@ -0,0 +1,2154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const progName = "quote-search"
+
+// Command is a CLI subcommand with its own flag set and help text
+type Command struct {
+	Name    string
+	Args    string
+	Summary string
+	Run     func(fs *flag.FlagSet, args []string) error
+	Setup   func(fs *flag.FlagSet)
+}
+
+// Exit codes used by the CLI
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage signals a command-line mistake that has already been reported
+var errUsage = errors.New("usage error")
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		searchCommand(),
+		replCommand(),
+		tuiCommand(),
+		serveCommand(),
+		indexCommand(),
+		evalCommand(),
+		lexiconCommand(),
+		importCommand(),
+		storeCommand(),
+		feedbackCommand(),
+		trainCommand(),
+		profileCommand(),
+		collectionsCommand(),
+		journalCommand(),
+		trendsCommand(),
+		dailyCommand(),
+		usersCommand(),
+		privacyCommand(),
+		lintCommand(),
+	}
+}
+
+func findCommand(name string) *Command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runCommand dispatches to a subcommand and returns the process exit code
+func runCommand(args []string) int {
+	name := "repl"
+	if len(args) > 0 {
+		switch {
+		case args[0] == "help" || args[0] == "-h" || args[0] == "--help" || args[0] == "-help":
+			return runHelp(args[1:])
+		case strings.HasPrefix(args[0], "-"):
+			// Flags without a command keep the old "[options]" invocation working
+			name = defaultCommand(args)
+		default:
+			name = args[0]
+			args = args[1:]
+		}
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	fs := newFlagSet(cmd)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if err := cmd.Run(fs, positional); err != nil {
+		if errors.Is(err, errUsage) {
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// defaultCommand picks search when a query flag is present, otherwise repl
+func defaultCommand(args []string) string {
+	for _, arg := range args {
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		if name == "q" || name == "query" {
+			return "search"
+		}
+	}
+	return "repl"
+}
+
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	fs := newFlagSet(cmd)
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+	return exitOK
+}
+
+func newFlagSet(cmd *Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "%s\n\n", cmd.Summary)
+		fmt.Fprintln(out, "Usage:")
+		fmt.Fprintf(out, "  %s %s [flags] %s\n\n", progName, cmd.Name, cmd.Args)
+		fmt.Fprintln(out, "Flags:")
+		fs.PrintDefaults()
+	}
+	if cmd.Setup != nil {
+		cmd.Setup(fs)
+	}
+	return fs
+}
+
+// parseFlags parses flags that may be interleaved with positional arguments.
+// Everything after a "--" separator is treated as positional.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			rest = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	return append(positional, rest...), nil
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "Movie Quote Search Engine - Find inspiration in cinema")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintf(out, "  %s <command> [flags] [arguments]\n", progName)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Run \"%s help <command>\" for the flags of a command.\n", progName)
+	fmt.Fprintf(out, "With no command, %s starts the interactive mode.\n", progName)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Examples:")
+	fmt.Fprintf(out, "  %s\n", progName)
+	fmt.Fprintf(out, "  %s search \"I just got rejected and feel like giving up\"\n", progName)
+	fmt.Fprintf(out, "  %s search --top 5 --quotes my_quotes.json \"I need motivation\"\n", progName)
+	fmt.Fprintf(out, "  %s serve --addr :8080\n", progName)
+}
+
+// loadFlags choose how quote files are read
+type loadFlags struct {
+	format         string
+	columns        string
+	nearDuplicates float64
+	keepDuplicates bool
+	strict         bool
+}
+
+func (f *loadFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", "auto", "quotes file format: auto, json, jsonl, csv, tsv or store")
+	fs.StringVar(&f.columns, "columns", "", "CSV/TSV column mapping, e.g. text=Quote,movie=Film,character=3")
+	fs.Float64Var(&f.nearDuplicates, "near-duplicates", DefaultNearDuplicateThreshold, "similarity (0-1) at which quotes are merged as duplicates; 1 merges exact duplicates only")
+	fs.BoolVar(&f.keepDuplicates, "keep-duplicates", false, "load duplicate quotes instead of merging them")
+	fs.BoolVar(&f.strict, "strict", false, "refuse to load quote files with lint errors instead of skipping the bad quotes")
+}
+
+func (f *loadFlags) options() (LoadOptions, error) {
+	format, err := ParseQuoteFormat(f.format)
+	if err != nil {
+		return LoadOptions{}, err
+	}
+	columns, err := ParseColumnMapping(f.columns)
+	if err != nil {
+		return LoadOptions{}, err
+	}
+	if f.nearDuplicates <= 0 || f.nearDuplicates > 1 {
+		return LoadOptions{}, fmt.Errorf("--near-duplicates must be greater than 0 and at most 1")
+	}
+	return LoadOptions{
+		Format:                 format,
+		Columns:                columns,
+		NearDuplicateThreshold: f.nearDuplicates,
+		KeepDuplicates:         f.keepDuplicates,
+		Strict:                 f.strict,
+	}, nil
+}
+
+func (f *loadFlags) newRepository() (*FileQuoteRepository, error) {
+	options, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileQuoteRepositoryWithOptions(options), nil
+}
+
+// serviceFlags are shared by every command that searches the corpus
+type serviceFlags struct {
+	loadFlags
+	quotesFile  string
+	lexiconFile string
+	weightsFile string
+	workers     int
+}
+
+func (f *serviceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.quotesFile, "quotes", "quotes.json", "path to the quotes file (JSON, JSONL, CSV or TSV)")
+	fs.StringVar(&f.lexiconFile, "lexicon", "", "path to a custom lexicon JSON file (default: built-in lexicon)")
+	fs.StringVar(&f.weightsFile, "weights", "", "path to a ranking weights file written by train (default: built-in weights)")
+	fs.IntVar(&f.workers, "workers", 0, "goroutines used to score large corpora (0 = one per CPU)")
+	f.loadFlags.register(fs)
+}
+
+func (f *serviceFlags) newService() (*SemanticQuoteService, error) {
+	options, err := f.options()
+	if err != nil {
+		return nil, err
+	}
+	// A quote store is searched like a file but can also be edited in place
+	var repo QuoteRepository = NewFileQuoteRepositoryWithOptions(options)
+	if strings.EqualFold(filepath.Ext(f.quotesFile), storeExtension) {
+		if repo, err = OpenQuoteStore(f.quotesFile, options); err != nil {
+			return nil, err
+		}
+	}
+	if f.workers < 0 {
+		return nil, fmt.Errorf("--workers must not be negative")
+	}
+	service := NewSemanticQuoteService(repo)
+	service.UseWorkers(f.workers)
+
+	if f.weightsFile != "" {
+		weights, err := LoadRankingWeights(f.weightsFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := service.UseWeights(*weights); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.lexiconFile != "" {
+		if err := service.UseLexiconFile(f.lexiconFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := service.Initialize(f.quotesFile); err != nil {
+		return nil, err
+	}
+	printIssueSummary(os.Stderr, f.quotesFile, service.Issues())
+	return service, nil
+}
+
+// printIssueSummary points at the lint command when loading found problems
+func printIssueSummary(out io.Writer, path string, issues []LintIssue) {
+	errs, warnings := countSeverity(issues, SeverityError), countSeverity(issues, SeverityWarning)
+	if errs == 0 && warnings == 0 {
+		return
+	}
+	fmt.Fprintf(out, "⚠ %s: %d error(s) (those quotes were skipped), %d warning(s); run \"quote-search lint %s\" for details\n",
+		path, errs, warnings, path)
+}
+
+// rankingFlags control how many results are shown and how confident they must be
+type rankingFlags struct {
+	topN     int
+	minScore float64
+}
+
+func (f *rankingFlags) register(fs *flag.FlagSet, defaultTop int) {
+	fs.IntVar(&f.topN, "top", defaultTop, "number of quotes to return")
+	fs.Float64Var(&f.minScore, "min-score", DefaultMinScore, "hide quotes scoring below this confidence (0-1)")
+}
+
+func (f *rankingFlags) validate(fs *flag.FlagSet) error {
+	if f.topN < 1 {
+		fmt.Fprintln(fs.Output(), "Error: --top must be at least 1")
+		return errUsage
+	}
+	if f.minScore < 0 || f.minScore > 1 {
+		fmt.Fprintln(fs.Output(), "Error: --min-score must be between 0 and 1")
+		return errUsage
+	}
+	return nil
+}
+
+// filterFlags restrict the corpus by quote metadata before scoring
+type filterFlags struct {
+	genres    string
+	maxRating string
+	years     string
+	language  string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.genres, "genre", "", "only quotes from these genres, e.g. drama,sports")
+	fs.StringVar(&f.maxRating, "max-rating", "", "only quotes rated at most this: G, PG, PG-13, R or NC-17")
+	fs.StringVar(&f.years, "year", "", "only quotes from films released in this year or range, e.g. 1990-2010")
+	fs.StringVar(&f.language, "language", "", "only quotes in this language, e.g. en")
+}
+
+func (f *filterFlags) filter(fs *flag.FlagSet) (QuoteFilter, error) {
+	filter := QuoteFilter{
+		Genres:   ParseGenres(f.genres),
+		Language: strings.TrimSpace(f.language),
+	}
+	var err error
+	if f.maxRating != "" {
+		if filter.MaxRating, err = ParseContentRating(f.maxRating); err != nil {
+			fmt.Fprintf(fs.Output(), "Error: --max-rating: %v\n", err)
+			return filter, errUsage
+		}
+	}
+	if filter.YearFrom, filter.YearTo, err = ParseYearRange(f.years); err != nil {
+		fmt.Fprintf(fs.Output(), "Error: --year: %v\n", err)
+		return filter, errUsage
+	}
+	return filter, nil
+}
+
+// noveltyFlags control how quotes already shown give way to fresh ones
+type noveltyFlags struct {
+	exposureFile string
+	penalty      float64
+	halfLife     time.Duration
+}
+
+func (f *noveltyFlags) register(fs *flag.FlagSet, usage string) {
+	fs.StringVar(&f.exposureFile, "exposure", "", usage)
+	fs.Float64Var(&f.penalty, "novelty-penalty", DefaultNoveltyPenalty, "most a score can lose for quotes seen often and lately (0-1)")
+	fs.DurationVar(&f.halfLife, "novelty-half-life", DefaultNoveltyHalfLife, "time for a quote seen before to count half as much, e.g. 72h")
+}
+
+func (f *noveltyFlags) options(fs *flag.FlagSet) (NoveltyOptions, error) {
+	options := NoveltyOptions{Penalty: f.penalty, HalfLife: f.halfLife}
+	if err := options.validate(); err != nil {
+		fmt.Fprintf(fs.Output(), "Error: %v\n", err)
+		return options, errUsage
+	}
+	return options, nil
+}
+
+// tracker follows what the user is shown, or is nil when --exposure is empty
+func (f *noveltyFlags) tracker(fs *flag.FlagSet, user string, policy PrivacyPolicy) (*ExposureTracker, error) {
+	options, err := f.options(fs)
+	if err != nil || f.exposureFile == "" {
+		return nil, err
+	}
+	return NewExposureTracker(policy.Exposure(NewFileExposureStore(f.exposureFile)), user, options), nil
+}
+
+// profileFlags turn on ranking by the preferences learned from favorites
+// and feedback
+type profileFlags struct {
+	personalize bool
+	strength    float64
+}
+
+func (f *profileFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.personalize, "personalize", false, "favor the genres, movies and tones of your favorites and helpful ratings")
+	fs.Float64Var(&f.strength, "profile-strength", DefaultProfileStrength, "most your preferences can raise or lower a score, as a share (0-1)")
+}
+
+func (f *profileFlags) validate(fs *flag.FlagSet) error {
+	if f.strength < 0 || f.strength > 1 {
+		fmt.Fprintln(fs.Output(), "Error: --profile-strength must be between 0 and 1")
+		return errUsage
+	}
+	return nil
+}
+
+// privacyFlags control what of the user's own words is stored, and for how
+// long
+type privacyFlags struct {
+	redact       bool
+	featuresOnly bool
+	retention    string
+}
+
+func (f *privacyFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.redact, "redact", true, "replace names, emails, phone numbers and addresses in queries and comments before storing them")
+	fs.BoolVar(&f.featuresOnly, "features-only", false, "store the emotions and themes read in queries and comments instead of their text")
+	f.registerRetention(fs)
+}
+
+// registerRetention is for commands that only read stored data
+func (f *privacyFlags) registerRetention(fs *flag.FlagSet) {
+	fs.StringVar(&f.retention, "retention", "", "purge feedback, journal entries and quotes shown once older than this, e.g. 90d (empty keeps them)")
+}
+
+func (f *privacyFlags) policy(fs *flag.FlagSet) (PrivacyPolicy, error) {
+	retention, err := ParseRetention(f.retention)
+	if err != nil {
+		fmt.Fprintf(fs.Output(), "Error: --retention: %v\n", err)
+		return PrivacyPolicy{}, errUsage
+	}
+	return PrivacyPolicy{Redact: f.redact, FeaturesOnly: f.featuresOnly, Retention: retention}, nil
+}
+
+// userFlags keep the data of the user named with --user in a folder of
+// their own
+type userFlags struct {
+	user string
+	dir  string
+}
+
+func (f *userFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.user, "user", "", "keep your data apart from other users', in a folder of its own under --users")
+	fs.StringVar(&f.dir, "users", DefaultUsersDir, "folder holding the data of each --user")
+}
+
+// resolve moves relative data files into the user's folder, so that
+// --user sam reads and writes users/sam/favorites.json and so on. Absolute
+// paths and empty names, which disable a store, are left alone, as is
+// everything without --user.
+func (f *userFlags) resolve(fs *flag.FlagSet, files ...*string) error {
+	if f.user == "" {
+		return nil
+	}
+	dir, err := NewUserData(f.dir, UserFiles{}).Dir(f.user)
+	if err != nil {
+		fmt.Fprintf(fs.Output(), "Error: --user: %v\n", err)
+		return errUsage
+	}
+	f.user = filepath.Base(dir)
+	for _, file := range files {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+	return nil
+}
+
+// builder learns from the given stores, or is nil without --personalize
+func (f *profileFlags) builder(service *SemanticQuoteService, favorites FavoritesStore, feedback FeedbackStore) *ProfileBuilder {
+	if !f.personalize {
+		return nil
+	}
+	return NewProfileBuilder(service, favorites, feedback, f.strength)
+}
+
+func searchCommand() *Command {
+	var sf serviceFlags
+	var rf rankingFlags
+	var ff filterFlags
+	var nf noveltyFlags
+	var pf profileFlags
+	var uf userFlags
+	var pv privacyFlags
+	var favoritesFile string
+	var feedbackFile string
+	var query string
+
+	return &Command{
+		Name:    "search",
+		Args:    "<query>",
+		Summary: "Find quotes for a single query and exit",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			rf.register(fs, 3)
+			ff.register(fs)
+			nf.register(fs, "file to remember the quotes shown here in, so later searches favor new ones (off unless set)")
+			pf.register(fs)
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "favorites to learn your preferences from with --personalize")
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "ratings to learn your preferences from with --personalize")
+			uf.register(fs)
+			pv.register(fs)
+			fs.StringVar(&query, "query", "", "query to search (alternative to the positional argument)")
+			fs.StringVar(&query, "q", "", "shorthand for --query")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if err := rf.validate(fs); err != nil {
+				return err
+			}
+			filter, err := ff.filter(fs)
+			if err != nil {
+				return err
+			}
+			if err := uf.resolve(fs, &favoritesFile, &feedbackFile, &nf.exposureFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			tracker, err := nf.tracker(fs, uf.user, policy)
+			if err != nil {
+				return err
+			}
+			if err := pf.validate(fs); err != nil {
+				return err
+			}
+			if query != "" && len(args) > 0 {
+				fmt.Fprintln(fs.Output(), "Error: give the query either as --query or as arguments, not both")
+				return errUsage
+			}
+			if query == "" {
+				query = strings.Join(args, " ")
+			}
+			if strings.TrimSpace(query) == "" {
+				fmt.Fprintln(fs.Output(), "Error: search requires a query")
+				fs.Usage()
+				return errUsage
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			cli := NewCLI(service, rf.topN, rf.minScore)
+			if err := cli.UseFilter(filter); err != nil {
+				return err
+			}
+			if err := cli.UseExposure(tracker); err != nil {
+				return err
+			}
+			feedback := policy.Feedback(NewFileFeedbackStore(feedbackFile))
+			if err := cli.UseProfile(pf.builder(service, NewFileFavoritesStore(favoritesFile), feedback)); err != nil {
+				return err
+			}
+			cli.RunSingleQuery(query)
+			return nil
+		},
+	}
+}
+
+func replCommand() *Command {
+	var sf serviceFlags
+	var rf rankingFlags
+	var ff filterFlags
+	var nf noveltyFlags
+	var pf profileFlags
+	var uf userFlags
+	var pv privacyFlags
+	var favoritesFile string
+	var feedbackFile string
+	var journalFile string
+	var withContext bool
+	var contextDecay float64
+	var watch time.Duration
+
+	return &Command{
+		Name:    "repl",
+		Summary: "Describe how you feel interactively (default)",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			rf.register(fs, 3)
+			ff.register(fs)
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "where :save stores favorite quotes")
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "where :rate stores feedback on results")
+			fs.StringVar(&journalFile, "journal", "", "keep a mood journal of your queries, the quotes shown and your ratings in this file")
+			nf.register(fs, "file to remember the quotes shown in, so later queries and sessions favor new ones (off unless set)")
+			pf.register(fs)
+			uf.register(fs)
+			pv.register(fs)
+			fs.BoolVar(&withContext, "context", false, "carry emotional context between messages and avoid repeating quotes")
+			fs.Float64Var(&contextDecay, "context-decay", DefaultContextDecay, "share of each earlier message kept per turn (0-1)")
+			fs.DurationVar(&watch, "watch", 0, "check the quotes and lexicon files this often and reload on change, e.g. 5s (0 disables)")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if err := rf.validate(fs); err != nil {
+				return err
+			}
+			filter, err := ff.filter(fs)
+			if err != nil {
+				return err
+			}
+			if err := uf.resolve(fs, &favoritesFile, &feedbackFile, &journalFile, &nf.exposureFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			tracker, err := nf.tracker(fs, uf.user, policy)
+			if err != nil {
+				return err
+			}
+			if err := pf.validate(fs); err != nil {
+				return err
+			}
+			if contextDecay < 0 || contextDecay > 1 {
+				fmt.Fprintln(fs.Output(), "Error: --context-decay must be between 0 and 1")
+				return errUsage
+			}
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q (use --quotes to choose a quotes file)\n", args[0])
+				return errUsage
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			favorites, feedback := NewFileFavoritesStore(favoritesFile), policy.Feedback(NewFileFeedbackStore(feedbackFile))
+			cli := NewCLI(service, rf.topN, rf.minScore)
+			cli.UseFavorites(favorites)
+			cli.UseFeedback(feedback)
+			if journalFile != "" {
+				cli.UseJournal(policy.Journal(NewFileJournal(journalFile)))
+			}
+			if err := cli.UseFilter(filter); err != nil {
+				return err
+			}
+			if err := cli.UseExposure(tracker); err != nil {
+				return err
+			}
+			if err := cli.UseProfile(pf.builder(service, favorites, feedback)); err != nil {
+				return err
+			}
+			if withContext {
+				if err := cli.UseConversation(NewConversation(contextDecay)); err != nil {
+					return err
+				}
+			}
+			stop := autoReload(service, watch, func(err error) {
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\n⚠ Reload failed, keeping the current quotes: %v\n", err)
+					return
+				}
+				fmt.Fprintf(os.Stderr, "\n🔄 Quotes reloaded (%d quotes).\n", len(service.Index()))
+			})
+			defer stop()
+			cli.Run()
+			return nil
+		},
+	}
+}
+
+func tuiCommand() *Command {
+	var sf serviceFlags
+	var rf rankingFlags
+	var ff filterFlags
+	var nf noveltyFlags
+	var pf profileFlags
+	var uf userFlags
+	var pv privacyFlags
+	var favoritesFile string
+	var feedbackFile string
+
+	return &Command{
+		Name:    "tui",
+		Summary: "Browse quotes in a full-screen terminal interface",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			rf.register(fs, 10)
+			ff.register(fs)
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "where favorite quotes are stored")
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "where helpful / not helpful / inappropriate ratings are stored")
+			nf.register(fs, "file to remember the quotes shown in, so later searches and sessions favor new ones (off unless set)")
+			pf.register(fs)
+			uf.register(fs)
+			pv.register(fs)
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if err := rf.validate(fs); err != nil {
+				return err
+			}
+			filter, err := ff.filter(fs)
+			if err != nil {
+				return err
+			}
+			if err := uf.resolve(fs, &favoritesFile, &feedbackFile, &nf.exposureFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			tracker, err := nf.tracker(fs, uf.user, policy)
+			if err != nil {
+				return err
+			}
+			if err := pf.validate(fs); err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			favorites, feedback := NewFileFavoritesStore(favoritesFile), policy.Feedback(NewFileFeedbackStore(feedbackFile))
+			tui := NewTUI(service, rf.topN, rf.minScore)
+			tui.UseFavorites(favorites)
+			tui.UseFeedback(feedback)
+			if err := tui.UseFilter(filter); err != nil {
+				return err
+			}
+			if err := tui.UseExposure(tracker); err != nil {
+				return err
+			}
+			if err := tui.UseProfile(pf.builder(service, favorites, feedback)); err != nil {
+				return err
+			}
+			return tui.Run()
+		},
+	}
+}
+
+func serveCommand() *Command {
+	var sf serviceFlags
+	var rf rankingFlags
+	var ff filterFlags
+	var nf noveltyFlags
+	var pf profileFlags
+	var addr string
+	var watch time.Duration
+	var timeout time.Duration
+	var allowEdits bool
+	var feedbackFile string
+	var favoritesFile string
+	var journalFile string
+	var usersDir string
+	var pv privacyFlags
+
+	return &Command{
+		Name:    "serve",
+		Summary: "Serve the search engine as a JSON HTTP API",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			rf.register(fs, 3)
+			ff.register(fs)
+			fs.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+			fs.DurationVar(&watch, "watch", 0, "check the quotes and lexicon files this often and reload on change, e.g. 5s (0 disables)")
+			fs.BoolVar(&allowEdits, "allow-edits", false, "allow adding, updating and deleting quotes through the API (needs a .qdb quote store)")
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "where POST /feedback stores ratings (empty disables feedback)")
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "where the /collections endpoints store saved quotes (empty disables them)")
+			fs.StringVar(&journalFile, "journal", "", "mood journal for searches that ask to be logged (empty disables the journal)")
+			nf.register(fs, "file to remember the quotes shown to each search's user in, so they rotate (off unless set)")
+			pf.register(fs)
+			pv.register(fs)
+			fs.StringVar(&usersDir, "users", "", "keep each user's data apart in a folder of its own here, under the names given by the file flags; requests then name their user. "+
+				"This is isolation, not authentication: any client can act as any user")
+			fs.DurationVar(&timeout, "search-timeout", 10*time.Second, "give up on a search after this long (0 disables)")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if err := rf.validate(fs); err != nil {
+				return err
+			}
+			if timeout < 0 {
+				return fmt.Errorf("--search-timeout must not be negative")
+			}
+			filter, err := ff.filter(fs)
+			if err != nil {
+				return err
+			}
+			novelty, err := nf.options(fs)
+			if err != nil {
+				return err
+			}
+			if err := pf.validate(fs); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			server := NewServer(service, rf.topN, rf.minScore)
+			if err := server.UseFilter(filter); err != nil {
+				return err
+			}
+			server.UseTimeout(timeout)
+			// With --users the exposure file is only the name within each
+			// user's folder
+			var exposure ExposureStore
+			if nf.exposureFile != "" {
+				exposure = policy.Exposure(NewFileExposureStore(nf.exposureFile))
+			}
+			var purge func(before time.Time) (int, error) // of expired user data
+			if usersDir != "" {
+				users := NewUserData(usersDir, UserFiles{
+					Favorites: favoritesFile,
+					Feedback:  feedbackFile,
+					Journal:   journalFile,
+					Exposure:  nf.exposureFile,
+				})
+				users.UsePrivacy(policy)
+				server.UseUsers(users)
+				log.Printf("Keeping user data apart in %s; requests are not authenticated, so any client can act as any user", usersDir)
+				purge = users.Purge
+			} else {
+				stores := UserStores{Exposure: exposure}
+				if feedbackFile != "" {
+					stores.Feedback = policy.Feedback(NewFileFeedbackStore(feedbackFile))
+					server.UseFeedback(stores.Feedback)
+				}
+				if favoritesFile != "" {
+					server.UseCollections(NewFileFavoritesStore(favoritesFile))
+				}
+				if journalFile != "" {
+					stores.Journal = policy.Journal(NewFileJournal(journalFile))
+					server.UseJournal(stores.Journal)
+				}
+				purge = stores.Purge
+			}
+			if pf.personalize {
+				if err := server.UseProfile(pf.strength); err != nil {
+					return err
+				}
+			}
+			if exposure != nil {
+				if err := server.UseExposure(exposure, novelty); err != nil {
+					return err
+				}
+			}
+			if policy.Retention > 0 {
+				stop := autoPurge(purge, policy.Retention, func(n int, err error) {
+					if err != nil {
+						log.Printf("Purge of expired user data failed: %v", err)
+					} else if n > 0 {
+						log.Printf("Purged %d expired records of user data", n)
+					}
+				})
+				defer stop()
+			}
+			if allowEdits {
+				editor, ok := service.Writable()
+				if !ok {
+					return fmt.Errorf("--allow-edits needs a quote store; create one with \"%s store import\"", progName)
+				}
+				server.UseEditor(editor)
+			}
+			stop := autoReload(service, watch, func(err error) {
+				if err != nil {
+					log.Printf("Reload failed, keeping the current quotes: %v", err)
+					return
+				}
+				log.Printf("Reloaded %d quotes", len(service.Index()))
+				printIssueSummary(log.Writer(), sf.quotesFile, service.Issues())
+			})
+			defer stop()
+			return server.ListenAndServe(addr)
+		},
+	}
+}
+
+func indexCommand() *Command {
+	var sf serviceFlags
+	var outFile string
+	var stats bool
+	var duplicates bool
+
+	return &Command{
+		Name:    "index",
+		Summary: "Analyze every quote and write the feature index as JSON",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			fs.StringVar(&outFile, "out", "", "write the index to this file instead of stdout")
+			fs.BoolVar(&stats, "stats", false, "print per-file load statistics instead of the index")
+			fs.BoolVar(&duplicates, "duplicates", false, "print the duplicate quotes merged while loading instead of the index")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			if stats {
+				printSourceStats(os.Stdout, service.Sources())
+				return nil
+			}
+			if duplicates {
+				service.Duplicates().Print(os.Stdout)
+				return nil
+			}
+			return writeJSONOutput(outFile, service.Index())
+		},
+	}
+}
+
+func evalCommand() *Command {
+	var sf serviceFlags
+	var topN int
+	var casesFile string
+	var verbose bool
+
+	return &Command{
+		Name:    "eval",
+		Summary: "Measure ranking quality against a file of judged queries",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			fs.IntVar(&topN, "top", 3, "rank cutoff used for the metrics")
+			fs.StringVar(&casesFile, "cases", "eval_cases.json", "path to the evaluation cases JSON file")
+			fs.BoolVar(&verbose, "v", false, "print the outcome of every case")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if topN < 1 {
+				fmt.Fprintln(fs.Output(), "Error: --top must be at least 1")
+				return errUsage
+			}
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+
+			cases, err := LoadEvalCases(casesFile)
+			if err != nil {
+				return err
+			}
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+
+			report := Evaluate(service, cases, topN)
+			report.Print(os.Stdout, verbose)
+			return nil
+		},
+	}
+}
+
+func lexiconCommand() *Command {
+	var lexiconFile string
+	var outFile string
+	var summary bool
+
+	return &Command{
+		Name:    "lexicon",
+		Summary: "Print the emotional lexicon as JSON (a starting point for --lexicon)",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&lexiconFile, "lexicon", "", "path to a custom lexicon JSON file (default: built-in lexicon)")
+			fs.StringVar(&outFile, "out", "", "write the lexicon to this file instead of stdout")
+			fs.BoolVar(&summary, "summary", false, "print category counts instead of the full lexicon")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+
+			lexicon := NewEmotionalLexicon()
+			if lexiconFile != "" {
+				var err error
+				if lexicon, err = LoadLexicon(lexiconFile); err != nil {
+					return err
+				}
+			}
+
+			if summary {
+				fmt.Printf("Emotions:         %d\n", len(lexicon.EmotionKeywords))
+				fmt.Printf("Emotion links:    %d\n", len(lexicon.EmotionRelations))
+				fmt.Printf("Themes:           %d\n", len(lexicon.ThemeKeywords))
+				fmt.Printf("Positive words:   %d\n", len(lexicon.PositiveWords))
+				fmt.Printf("Negative words:   %d\n", len(lexicon.NegativeWords))
+				fmt.Printf("Action words:     %d\n", len(lexicon.ActionWords))
+				fmt.Printf("Reflective words: %d\n", len(lexicon.ReflectiveWords))
+				return nil
+			}
+
+			if outFile == "" {
+				return lexicon.WriteJSON(os.Stdout)
+			}
+			file, err := os.Create(outFile)
+			if err != nil {
+				return fmt.Errorf("failed to create lexicon file: %w", err)
+			}
+			defer file.Close()
+			return lexicon.WriteJSON(file)
+		},
+	}
+}
+
+func importCommand() *Command {
+	var lf loadFlags
+	var quotesFile string
+	var dryRun bool
+
+	return &Command{
+		Name:    "import",
+		Args:    "<source>...",
+		Summary: "Merge quotes from JSON, JSONL, CSV or TSV files into the quotes file",
+		Setup: func(fs *flag.FlagSet) {
+			lf.register(fs)
+			fs.StringVar(&quotesFile, "quotes", "quotes.json", "JSON quotes file to import into (created if missing)")
+			fs.BoolVar(&dryRun, "dry-run", false, "report what would be imported without writing")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Error: import requires at least one source file")
+				fs.Usage()
+				return errUsage
+			}
+
+			// --format and --columns describe the sources; the target is always JSON
+			sourceRepo, err := lf.newRepository()
+			if err != nil {
+				return err
+			}
+			// The target is rewritten, so it must load exactly as it is: no
+			// merged duplicates and no silently skipped quotes
+			repo := NewFileQuoteRepositoryWithOptions(LoadOptions{KeepDuplicates: true, Strict: true})
+			target, err := repo.LoadQuotes(quotesFile)
+			if errors.Is(err, os.ErrNotExist) {
+				target = &QuoteData{}
+			} else if err != nil {
+				return err
+			}
+
+			seen := make(map[string]bool)
+			ids := make(map[string]bool)
+			for _, quote := range target.Quotes {
+				seen[quoteKey(quote)] = true
+				ids[quote.ID] = true
+			}
+
+			added := 0
+			for _, source := range args {
+				data, err := sourceRepo.LoadQuotes(source)
+				if err != nil {
+					return fmt.Errorf("%s: %w", source, err)
+				}
+				printIssueSummary(os.Stderr, source, data.Issues)
+				loadedFrom := make(map[string]bool)
+				for _, stats := range data.Sources {
+					loadedFrom[stats.Path] = true
+				}
+				sourceAdded := 0
+				for _, quote := range data.Quotes {
+					key := quoteKey(quote)
+					if seen[key] {
+						continue
+					}
+					if ids[quote.ID] {
+						return fmt.Errorf("%w %q: %q from %s is already used in %s", ErrDuplicateQuoteID, quote.ID, quote.Text, source, quotesFile)
+					}
+					seen[key] = true
+					ids[quote.ID] = true
+					// Once merged, the quote belongs to the target file
+					if loadedFrom[quote.Source] {
+						quote.Source = ""
+					}
+					target.Quotes = append(target.Quotes, quote)
+					sourceAdded++
+				}
+				fmt.Printf("%s: %d new of %d quotes\n", source, sourceAdded, len(data.Quotes))
+				added += sourceAdded
+			}
+
+			if dryRun {
+				fmt.Printf("Dry run: %d quotes would be added to %s\n", added, quotesFile)
+				return nil
+			}
+			if added == 0 {
+				fmt.Println("Nothing to import.")
+				return nil
+			}
+			if err := repo.SaveQuotes(quotesFile, target); err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d quotes into %s (%d total)\n", added, quotesFile, len(target.Quotes))
+			return nil
+		},
+	}
+}
+
+// quoteFlags describe one quote for the store command
+type quoteFlags struct {
+	id, text, movie, character string
+	year                       int
+	genres, rating, language   string
+	context                    string
+}
+
+func (f *quoteFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.id, "id", "", "quote id (default: derived from the text)")
+	fs.StringVar(&f.text, "text", "", "quote text")
+	fs.StringVar(&f.movie, "movie", "", "movie the quote is from")
+	fs.StringVar(&f.character, "character", "", "character who says it")
+	fs.IntVar(&f.year, "year", 0, "release year")
+	fs.StringVar(&f.genres, "genres", "", "genres, e.g. drama,sports")
+	fs.StringVar(&f.rating, "rating", "", "content rating: G, PG, PG-13, R or NC-17")
+	fs.StringVar(&f.language, "language", "", "language code, e.g. en")
+	fs.StringVar(&f.context, "context", "", "the scene the quote comes from")
+}
+
+// apply sets the fields whose flags were given on the command line
+func (f *quoteFlags) apply(fs *flag.FlagSet, quote *Quote) {
+	fs.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "id":
+			quote.ID = f.id
+		case "text":
+			quote.Text = f.text
+		case "movie":
+			quote.Movie = f.movie
+		case "character":
+			quote.Character = f.character
+		case "year":
+			quote.Year = f.year
+		case "genres":
+			quote.Genres = ParseGenres(f.genres)
+		case "rating":
+			quote.Rating = ContentRating(f.rating)
+		case "language":
+			quote.Language = f.language
+		case "context":
+			quote.Context = f.context
+		}
+	})
+}
+
+func storeCommand() *Command {
+	var lf loadFlags
+	var qf quoteFlags
+	var storeFile string
+	var asJSON bool
+
+	return &Command{
+		Name:    "store",
+		Args:    "<list|get|add|update|delete|import|compact> [id|source...]",
+		Summary: "Add, update, delete and list quotes in an editable quote store",
+		Setup: func(fs *flag.FlagSet) {
+			lf.register(fs)
+			qf.register(fs)
+			fs.StringVar(&storeFile, "store", "quotes"+storeExtension, "quote store to edit (created if missing)")
+			fs.BoolVar(&asJSON, "json", false, "print quotes as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Error: store requires an action")
+				fs.Usage()
+				return errUsage
+			}
+			action, args := args[0], args[1:]
+			wantArgs := func(n int, what string) error {
+				if len(args) != n {
+					fmt.Fprintf(fs.Output(), "Error: store %s takes %s\n", action, what)
+					return errUsage
+				}
+				return nil
+			}
+			printQuote := func(verb string, quote Quote) error {
+				if asJSON {
+					return writeJSON(os.Stdout, quote)
+				}
+				fmt.Printf("%s %s: \"%s\" — %s (%s)\n", verb, quote.ID, quote.Text, quote.Character, quote.Movie)
+				return nil
+			}
+
+			options, err := lf.options()
+			if err != nil {
+				return err
+			}
+			store, err := OpenQuoteStore(storeFile, options)
+			if err != nil {
+				return err
+			}
+
+			switch action {
+			case "list":
+				if err := wantArgs(0, "no arguments"); err != nil {
+					return err
+				}
+				quotes, err := store.ListQuotes()
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					return writeJSON(os.Stdout, QuoteData{Quotes: quotes})
+				}
+				for _, quote := range quotes {
+					fmt.Printf("%s  \"%s\" — %s (%s)\n", quote.ID, quote.Text, quote.Character, quote.Movie)
+				}
+				fmt.Printf("%d quote(s) in %s\n", len(quotes), storeFile)
+				return nil
+
+			case "get":
+				if err := wantArgs(1, "a quote id"); err != nil {
+					return err
+				}
+				quote, err := store.GetQuote(args[0])
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					return writeJSON(os.Stdout, quote)
+				}
+				fmt.Printf("%s\n\"%s\"\n— %s (%s)\n", quote.ID, quote.Text, quote.Character, quote.Movie)
+				if summary := metadataSummary(quote); summary != "" {
+					fmt.Println(summary)
+				}
+				if quote.Context != "" {
+					fmt.Println(quote.Context)
+				}
+				return nil
+
+			case "add":
+				if err := wantArgs(0, "its fields as flags, e.g. --text"); err != nil {
+					return err
+				}
+				var quote Quote
+				qf.apply(fs, &quote)
+				added, err := store.AddQuote(quote)
+				if err != nil {
+					return err
+				}
+				return printQuote("Added", added)
+
+			case "update":
+				if err := wantArgs(1, "a quote id and the fields to change as flags"); err != nil {
+					return err
+				}
+				quote, err := store.GetQuote(args[0])
+				if err != nil {
+					return err
+				}
+				qf.apply(fs, &quote)
+				if quote.ID != args[0] {
+					return fmt.Errorf("quote ids cannot be changed; delete %s and add the quote again", args[0])
+				}
+				updated, err := store.UpdateQuote(quote)
+				if err != nil {
+					return err
+				}
+				return printQuote("Updated", updated)
+
+			case "delete":
+				if err := wantArgs(1, "a quote id"); err != nil {
+					return err
+				}
+				if err := store.DeleteQuote(args[0]); err != nil {
+					return err
+				}
+				fmt.Printf("Deleted %s\n", args[0])
+				return nil
+
+			case "import":
+				if len(args) == 0 {
+					return wantArgs(1, "at least one source file")
+				}
+				// --format and --columns describe the sources
+				sources := NewFileQuoteRepositoryWithOptions(options)
+				added := 0
+				for _, source := range args {
+					data, err := sources.LoadQuotes(source)
+					if err != nil {
+						return fmt.Errorf("%s: %w", source, err)
+					}
+					printIssueSummary(os.Stderr, source, data.Issues)
+					sourceAdded := 0
+					for _, quote := range data.Quotes {
+						_, err := store.AddQuote(quote)
+						if errors.Is(err, ErrQuoteExists) {
+							continue
+						}
+						if err != nil {
+							return fmt.Errorf("%s: %w", source, err)
+						}
+						sourceAdded++
+					}
+					fmt.Printf("%s: %d new of %d quotes\n", source, sourceAdded, len(data.Quotes))
+					added += sourceAdded
+				}
+				fmt.Printf("Imported %d quotes into %s\n", added, storeFile)
+				return nil
+
+			case "compact":
+				if err := wantArgs(0, "no arguments"); err != nil {
+					return err
+				}
+				return store.Compact()
+			}
+
+			fmt.Fprintf(fs.Output(), "Error: unknown store action %q\n", action)
+			fs.Usage()
+			return errUsage
+		},
+	}
+}
+
+func feedbackCommand() *Command {
+	var uf userFlags
+	var pv privacyFlags
+	var feedbackFile string
+	var top int
+	var format string
+
+	return &Command{
+		Name:    "feedback",
+		Summary: "Report the quotes most often rated unhelpful or inappropriate, per emotion",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "feedback file to report on")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.IntVar(&top, "top", 5, "flagged quotes listed per emotion (0 lists all)")
+			fs.StringVar(&format, "format", "text", "report format: text, json or csv")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			if top < 0 {
+				fmt.Fprintln(fs.Output(), "Error: --top must not be negative")
+				return errUsage
+			}
+			if err := uf.resolve(fs, &feedbackFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+
+			entries, err := policy.Feedback(NewFileFeedbackStore(feedbackFile)).List()
+			if err != nil {
+				return err
+			}
+			report := BuildFeedbackReport(entries, top)
+
+			switch format {
+			case "text":
+				report.Print(os.Stdout)
+				return nil
+			case "json":
+				return writeJSON(os.Stdout, report)
+			case "csv":
+				return report.WriteCSV(os.Stdout)
+			}
+			fmt.Fprintf(fs.Output(), "Error: unknown format %q (expected text, json or csv)\n", format)
+			return errUsage
+		},
+	}
+}
+
+func trainCommand() *Command {
+	var sf serviceFlags
+	var uf userFlags
+	var pv privacyFlags
+	var feedbackFile, outFile, casesFile string
+	var holdout float64
+	var asJSON bool
+
+	return &Command{
+		Name:    "train",
+		Summary: "Fit the ranking weights to collected feedback and write a weights file",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "feedback file to learn from")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.StringVar(&outFile, "out", "weights.json", "where to write the fitted weights (empty: don't write)")
+			fs.Float64Var(&holdout, "holdout", 0.2, "share of the feedback kept aside to measure the fit (0-1)")
+			fs.StringVar(&casesFile, "cases", "eval_cases.json", "also compare eval metrics on these cases, if the file exists")
+			fs.BoolVar(&asJSON, "json", false, "print the report as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			if holdout < 0 || holdout >= 1 {
+				fmt.Fprintln(fs.Output(), "Error: --holdout must be at least 0 and below 1")
+				return errUsage
+			}
+			if err := uf.resolve(fs, &feedbackFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+
+			entries, err := policy.Feedback(NewFileFeedbackStore(feedbackFile)).List()
+			if err != nil {
+				return err
+			}
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			report, err := TrainRankingWeights(service, entries, TrainOptions{Holdout: holdout})
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				if err := writeJSON(os.Stdout, report); err != nil {
+					return err
+				}
+			} else {
+				report.Print(os.Stdout)
+			}
+
+			// The judged queries are a second, independent check on the fit
+			if cases, err := LoadEvalCases(casesFile); err == nil && !asJSON {
+				before := Evaluate(service, cases, 3)
+				if err := service.UseWeights(report.Weights); err != nil {
+					return err
+				}
+				after := Evaluate(service, cases, 3)
+				fmt.Printf("\n%-22s %8s %8s\n", "Eval cases", "before", "after")
+				fmt.Printf("%-22s %8.3f %8.3f\n", "  Hit rate@3", before.HitRate, after.HitRate)
+				fmt.Printf("%-22s %8.3f %8.3f\n", "  MRR", before.MRR, after.MRR)
+				fmt.Printf("%-22s %8.3f %8.3f\n", "  Precision@3", before.Precision, after.Precision)
+			}
+
+			if outFile == "" {
+				return nil
+			}
+			if err := saveJSONFile(outFile, 0o644, report.Weights); err != nil {
+				return err
+			}
+			if !asJSON {
+				fmt.Printf("\nWeights written to %s; search with --weights %s to use them\n", outFile, outFile)
+			}
+			return nil
+		},
+	}
+}
+
+func profileCommand() *Command {
+	var sf serviceFlags
+	var uf userFlags
+	var pv privacyFlags
+	var favoritesFile string
+	var feedbackFile string
+	var asJSON bool
+
+	return &Command{
+		Name:    "profile",
+		Summary: "Show the preferences --personalize learns from your favorites and feedback",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "favorites and collections to learn from")
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "ratings to learn from")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.BoolVar(&asJSON, "json", false, "print the profile as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			if err := uf.resolve(fs, &favoritesFile, &feedbackFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			feedback := policy.Feedback(NewFileFeedbackStore(feedbackFile))
+			builder := NewProfileBuilder(service, NewFileFavoritesStore(favoritesFile), feedback, DefaultProfileStrength)
+			profile, err := builder.Profile()
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return writeJSON(os.Stdout, profile)
+			}
+			profile.Print(os.Stdout)
+			return nil
+		},
+	}
+}
+
+func collectionsCommand() *Command {
+	var sf serviceFlags
+	var uf userFlags
+	var favoritesFile string
+	var asCSV bool
+	var outFile string
+
+	return &Command{
+		Name:    "collections",
+		Args:    "<list|show|add|remove|export> [collection] [id...]",
+		Summary: "Manage your favorites and named quote collections",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			fs.StringVar(&favoritesFile, "favorites", "favorites.json", "file holding your favorites and collections")
+			uf.register(fs)
+			fs.BoolVar(&asCSV, "csv", false, "export as CSV instead of JSON (implied by an --out file ending in .csv)")
+			fs.StringVar(&outFile, "out", "", "write the export to this file instead of stdout")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Error: collections requires an action")
+				fs.Usage()
+				return errUsage
+			}
+			action, args := args[0], args[1:]
+			if err := uf.resolve(fs, &favoritesFile); err != nil {
+				return err
+			}
+			store := NewFileFavoritesStore(favoritesFile)
+
+			// The collection defaults to the favorites; add and remove then
+			// take one or more quote ids
+			name := FavoritesCollection
+			if len(args) > 0 && action != "list" {
+				name, args = args[0], args[1:]
+			}
+			switch action {
+			case "list":
+				if len(args) > 0 {
+					fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+					return errUsage
+				}
+				summaries, err := store.Collections()
+				if err != nil {
+					return err
+				}
+				for _, summary := range summaries {
+					fmt.Printf("%-20s %d quote(s)\n", summary.Name, summary.Quotes)
+				}
+				return nil
+
+			case "show":
+				if len(args) > 0 {
+					fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+					return errUsage
+				}
+				quotes, err := store.Collection(name)
+				if err != nil {
+					return err
+				}
+				for _, quote := range quotes {
+					fmt.Printf("%s  \"%s\" — %s (%s)\n", quote.ID, quote.Text, quote.Character, quote.Movie)
+				}
+				fmt.Printf("%d quote(s) in %s\n", len(quotes), name)
+				return nil
+
+			case "add":
+				if len(args) == 0 {
+					fmt.Fprintln(fs.Output(), "Error: collections add takes a collection and at least one quote id")
+					return errUsage
+				}
+				// Quotes are saved as they are in the corpus, so load it
+				service, err := sf.newService()
+				if err != nil {
+					return err
+				}
+				for _, id := range args {
+					quote, found := service.QuoteByID(id)
+					if !found {
+						return fmt.Errorf("no quote with id %q in %s", id, sf.quotesFile)
+					}
+					added, err := store.AddToCollection(name, quote)
+					if err != nil {
+						return err
+					}
+					if added {
+						fmt.Printf("Saved %s to %s\n", id, name)
+					} else {
+						fmt.Printf("%s is already in %s\n", id, name)
+					}
+				}
+				return nil
+
+			case "remove":
+				if len(args) == 0 {
+					fmt.Fprintln(fs.Output(), "Error: collections remove takes a collection and at least one quote id")
+					return errUsage
+				}
+				for _, id := range args {
+					if err := store.RemoveFromCollection(name, id); err != nil {
+						return err
+					}
+					fmt.Printf("Removed %s from %s\n", id, name)
+				}
+				return nil
+
+			case "export":
+				if len(args) > 0 {
+					fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+					return errUsage
+				}
+				format := "json"
+				if asCSV || strings.EqualFold(filepath.Ext(outFile), ".csv") {
+					format = "csv"
+				}
+				quotes, err := store.Collection(name)
+				if err != nil {
+					return err
+				}
+				if outFile == "" {
+					return ExportCollection(os.Stdout, quotes, format)
+				}
+				file, err := os.Create(outFile)
+				if err != nil {
+					return fmt.Errorf("failed to create export file: %w", err)
+				}
+				if err := ExportCollection(file, quotes, format); err != nil {
+					file.Close()
+					return err
+				}
+				if err := file.Close(); err != nil {
+					return fmt.Errorf("failed to write export file: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Exported %d quote(s) from %s to %s\n", len(quotes), name, outFile)
+				return nil
+			}
+
+			fmt.Fprintf(fs.Output(), "Error: unknown collections action %q\n", action)
+			fs.Usage()
+			return errUsage
+		},
+	}
+}
+
+func journalCommand() *Command {
+	var uf userFlags
+	var pv privacyFlags
+	var journalFile string
+	var since string
+	var asJSON bool
+
+	return &Command{
+		Name:    "journal",
+		Summary: "Show your mood journal as a timeline",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&journalFile, "journal", "journal.jsonl", "journal file to show")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.StringVar(&since, "since", "", "only entries from this date or period on, e.g. 2026-10-01, 30d or 4w")
+			fs.BoolVar(&asJSON, "json", false, "print the entries as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			from, err := ParseSince(since, time.Now())
+			if err != nil {
+				fmt.Fprintf(fs.Output(), "Error: --since: %v\n", err)
+				return errUsage
+			}
+			if err := uf.resolve(fs, &journalFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+
+			entries, err := policy.Journal(NewFileJournal(journalFile)).Entries(from)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				if entries == nil {
+					entries = []JournalEntry{}
+				}
+				return writeJSON(os.Stdout, entries)
+			}
+			PrintJournalTimeline(os.Stdout, entries)
+			return nil
+		},
+	}
+}
+
+func trendsCommand() *Command {
+	var uf userFlags
+	var pv privacyFlags
+	var journalFile string
+	var since string
+	var options TrendOptions
+	var asJSON bool
+
+	return &Command{
+		Name:    "trends",
+		Summary: "Chart weekly mood, recurring themes and persistent low mood from your journal",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&journalFile, "journal", "journal.jsonl", "journal file to analyze")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.StringVar(&since, "since", "12w", "only entries from this date or period on, e.g. 2026-10-01, 30d or 12w (empty for all)")
+			fs.IntVar(&options.AlertWeeks, "alert-weeks", DefaultAlertWeeks, "alert after this many negative weeks in a row")
+			fs.Float64Var(&options.AlertValence, "alert-valence", DefaultAlertValence, "a week whose mean valence (-1 to 1) is at or below this counts as negative")
+			fs.BoolVar(&asJSON, "json", false, "print the trends as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			if options.AlertWeeks < 1 {
+				fmt.Fprintln(fs.Output(), "Error: --alert-weeks must be at least 1")
+				return errUsage
+			}
+			from, err := ParseSince(since, time.Now())
+			if err != nil {
+				fmt.Fprintf(fs.Output(), "Error: --since: %v\n", err)
+				return errUsage
+			}
+			if err := uf.resolve(fs, &journalFile); err != nil {
+				return err
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+
+			entries, err := policy.Journal(NewFileJournal(journalFile)).Entries(from)
+			if err != nil {
+				return err
+			}
+			trends := BuildMoodTrends(entries, time.Now(), options)
+			if asJSON {
+				return writeJSON(os.Stdout, trends)
+			}
+			trends.Print(os.Stdout)
+			return nil
+		},
+	}
+}
+
+func dailyCommand() *Command {
+	var sf serviceFlags
+	var ff filterFlags
+	var uf userFlags
+	var pv privacyFlags
+	var journalFile string
+	var date string
+	var options DailyOptions
+	var asJSON bool
+
+	return &Command{
+		Name:    "daily",
+		Summary: "Pick a quote of the day for your recent mood",
+		Setup: func(fs *flag.FlagSet) {
+			sf.register(fs)
+			ff.register(fs)
+			fs.StringVar(&journalFile, "journal", "journal.jsonl", "journal to read the recent mood and shown quotes from (a missing file is fine)")
+			uf.register(fs)
+			pv.registerRetention(fs)
+			fs.StringVar(&date, "date", "", "day to pick for, e.g. 2026-10-18 (default today)")
+			fs.IntVar(&options.MoodDays, "mood-days", DefaultDailyMoodDays, "days of journal entries that make up the recent mood")
+			fs.IntVar(&options.AvoidDays, "avoid-days", DefaultDailyAvoidDays, "skip quotes shown in this many days before (0 allows repeats)")
+			fs.BoolVar(&asJSON, "json", false, "print the pick as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+				return errUsage
+			}
+			if options.MoodDays < 1 || options.MoodDays > MaxDailyDays || options.AvoidDays < 0 || options.AvoidDays > MaxDailyDays {
+				fmt.Fprintf(fs.Output(), "Error: --mood-days must be from 1 to %d and --avoid-days from 0 to %d\n", MaxDailyDays, MaxDailyDays)
+				return errUsage
+			}
+			options.Date = time.Now()
+			if date != "" {
+				var err error
+				if options.Date, err = time.ParseInLocation("2006-01-02", date, time.Local); err != nil {
+					fmt.Fprintf(fs.Output(), "Error: --date: expected a date like 2026-10-18\n")
+					return errUsage
+				}
+			}
+			var err error
+			if options.Filter, err = ff.filter(fs); err != nil {
+				return err
+			}
+			// Each user gets their own pick, from their own journal
+			if err := uf.resolve(fs, &journalFile); err != nil {
+				return err
+			}
+			options.User = uf.user
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+
+			service, err := sf.newService()
+			if err != nil {
+				return err
+			}
+			daily, err := service.DailyQuote(policy.Journal(NewFileJournal(journalFile)), options)
+			if err != nil {
+				return err
+			}
+			daily.Quote.Source = ""
+			if asJSON {
+				return writeJSON(os.Stdout, daily)
+			}
+			daily.Print(os.Stdout)
+			return nil
+		},
+	}
+}
+
+func usersCommand() *Command {
+	var pv privacyFlags
+	var dir string
+	var files UserFiles
+	var outFile string
+
+	return &Command{
+		Name:    "users",
+		Args:    "<list|export|delete> [user]",
+		Summary: "List the users with data stored, or export or delete all of one user's data",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&dir, "users", DefaultUsersDir, "folder holding the data of each user")
+			fs.StringVar(&files.Favorites, "favorites", DefaultUserFiles.Favorites, "name of each user's favorites file")
+			fs.StringVar(&files.Feedback, "feedback", DefaultUserFiles.Feedback, "name of each user's feedback file")
+			fs.StringVar(&files.Journal, "journal", DefaultUserFiles.Journal, "name of each user's journal file")
+			fs.StringVar(&files.Exposure, "exposure", DefaultUserFiles.Exposure, "name of each user's record of the quotes shown")
+			fs.StringVar(&outFile, "out", "", "write the export to this file instead of stdout")
+			pv.registerRetention(fs)
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Error: users requires an action")
+				fs.Usage()
+				return errUsage
+			}
+			action, args := args[0], args[1:]
+			wantArgs := func(n int, what string) error {
+				if len(args) != n {
+					fmt.Fprintf(fs.Output(), "Error: users %s takes %s\n", action, what)
+					return errUsage
+				}
+				return nil
+			}
+			policy, err := pv.policy(fs)
+			if err != nil {
+				return err
+			}
+			data := NewUserData(dir, files)
+			data.UsePrivacy(policy)
+
+			switch action {
+			case "list":
+				if err := wantArgs(0, "no arguments"); err != nil {
+					return err
+				}
+				users, err := data.Users()
+				if err != nil {
+					return err
+				}
+				if len(users) == 0 {
+					fmt.Printf("No user data in %s\n", dir)
+				}
+				for _, user := range users {
+					fmt.Println(user)
+				}
+				return nil
+
+			case "export":
+				if err := wantArgs(1, "a user id"); err != nil {
+					return err
+				}
+				export, err := data.Export(args[0])
+				if err != nil {
+					return err
+				}
+				if outFile == "" {
+					return writeJSON(os.Stdout, export)
+				}
+				// The export holds everything the user told the engine
+				file, err := os.OpenFile(outFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+				if err != nil {
+					return fmt.Errorf("failed to create export file: %w", err)
+				}
+				if err := writeJSON(file, export); err != nil {
+					file.Close()
+					return err
+				}
+				if err := file.Close(); err != nil {
+					return fmt.Errorf("failed to write export file: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Exported the data of %s (%d file(s)) to %s\n", export.User, len(export.Files), outFile)
+				return nil
+
+			case "delete":
+				if err := wantArgs(1, "a user id"); err != nil {
+					return err
+				}
+				user, err := NormalizeUserID(args[0])
+				if err != nil {
+					return err
+				}
+				if err := data.Delete(user); err != nil {
+					return err
+				}
+				fmt.Printf("Deleted all data of %s\n", user)
+				return nil
+			}
+
+			fmt.Fprintf(fs.Output(), "Error: unknown users action %q\n", action)
+			fs.Usage()
+			return errUsage
+		},
+	}
+}
+
+func privacyCommand() *Command {
+	var uf userFlags
+	var pv privacyFlags
+	var feedbackFile, journalFile, exposureFile string
+	var allUsers bool
+	var asJSON bool
+
+	return &Command{
+		Name:    "privacy",
+		Args:    "<check|purge> [text]",
+		Summary: "Show the personal information found in a text, or purge data older than --retention",
+		Setup: func(fs *flag.FlagSet) {
+			fs.StringVar(&feedbackFile, "feedback", "feedback.jsonl", "feedback file to purge")
+			fs.StringVar(&journalFile, "journal", "journal.jsonl", "journal file to purge")
+			fs.StringVar(&exposureFile, "exposure", "", "record of the quotes shown to purge, if one is kept (exposure.json in each folder with --all-users)")
+			uf.register(fs)
+			fs.BoolVar(&allUsers, "all-users", false, "purge the data of every user under --users")
+			pv.registerRetention(fs)
+			fs.BoolVar(&asJSON, "json", false, "print what check finds as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				fmt.Fprintln(fs.Output(), "Error: privacy requires an action")
+				fs.Usage()
+				return errUsage
+			}
+			action, args := args[0], args[1:]
+
+			switch action {
+			case "check":
+				if len(args) == 0 {
+					fmt.Fprintln(fs.Output(), "Error: privacy check takes the text to check")
+					return errUsage
+				}
+				redacted, matches := RedactPII(strings.Join(args, " "))
+				if asJSON {
+					if matches == nil {
+						matches = []PIIMatch{}
+					}
+					return writeJSON(os.Stdout, map[string]any{"redacted": redacted, "found": matches})
+				}
+				fmt.Println(redacted)
+				for _, match := range matches {
+					fmt.Printf("   %-8s %s\n", match.Kind, match.Text)
+				}
+				return nil
+
+			case "purge":
+				if len(args) > 0 {
+					fmt.Fprintf(fs.Output(), "Error: unexpected argument %q\n", args[0])
+					return errUsage
+				}
+				policy, err := pv.policy(fs)
+				if err != nil {
+					return err
+				}
+				if policy.Retention == 0 {
+					fmt.Fprintln(fs.Output(), "Error: privacy purge needs a --retention period")
+					return errUsage
+				}
+				before := time.Now().Add(-policy.Retention)
+
+				if allUsers {
+					// The users command and serve --users keep a record of
+					// the quotes shown in every folder
+					files := UserFiles{Feedback: feedbackFile, Journal: journalFile, Exposure: DefaultUserFiles.Exposure}
+					fs.Visit(func(set *flag.Flag) {
+						if set.Name == "exposure" {
+							files.Exposure = exposureFile
+						}
+					})
+					n, err := NewUserData(uf.dir, files).Purge(before)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Purged %d record(s) from before %s in %s\n", n, before.Format("2006-01-02 15:04"), uf.dir)
+					return nil
+				}
+				if err := uf.resolve(fs, &feedbackFile, &journalFile, &exposureFile); err != nil {
+					return err
+				}
+				for _, target := range []struct {
+					file  string
+					store Purger
+				}{
+					{feedbackFile, NewFileFeedbackStore(feedbackFile)},
+					{journalFile, NewFileJournal(journalFile)},
+					{exposureFile, NewFileExposureStore(exposureFile)},
+				} {
+					if target.file == "" {
+						continue
+					}
+					n, err := target.store.Purge(before)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("%s: purged %d record(s) from before %s\n", target.file, n, before.Format("2006-01-02 15:04"))
+				}
+				return nil
+			}
+
+			fmt.Fprintf(fs.Output(), "Error: unknown privacy action %q\n", action)
+			fs.Usage()
+			return errUsage
+		},
+	}
+}
+
+func lintCommand() *Command {
+	var lf loadFlags
+	var asJSON bool
+
+	return &Command{
+		Name:    "lint",
+		Args:    "[path]...",
+		Summary: "Check quote files for empty fields, encoding problems and other mistakes",
+		Setup: func(fs *flag.FlagSet) {
+			lf.register(fs)
+			fs.BoolVar(&asJSON, "json", false, "print the issues as JSON")
+		},
+		Run: func(fs *flag.FlagSet, args []string) error {
+			if len(args) == 0 {
+				args = []string{"quotes.json"}
+			}
+			repo, err := lf.newRepository()
+			if err != nil {
+				return err
+			}
+
+			report := &LintReport{Issues: []LintIssue{}}
+			for _, path := range args {
+				pathReport, err := repo.Lint(path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				report.Issues = append(report.Issues, pathReport.Issues...)
+				report.Quotes += pathReport.Quotes
+			}
+
+			if asJSON {
+				if err := writeJSON(os.Stdout, report); err != nil {
+					return err
+				}
+			} else {
+				report.Print(os.Stdout)
+			}
+
+			// --strict also fails the check on warnings
+			if report.Errors() > 0 || (lf.strict && report.Warnings() > 0) {
+				return fmt.Errorf("lint found %d error(s) and %d warning(s)", report.Errors(), report.Warnings())
+			}
+			return nil
+		},
+	}
+}
+
+func printSourceStats(out io.Writer, sources []SourceStats) {
+	total := 0
+	for _, source := range sources {
+		fmt.Fprintf(out, "%6d  %-5s  %s\n", source.Quotes, source.Format, source.Path)
+		total += source.Quotes
+	}
+	fmt.Fprintf(out, "%6d  quotes from %d file(s)\n", total, len(sources))
+}
+
+// quoteKey identifies a quote by its normalized text
+func quoteKey(quote Quote) string {
+	return normalizeQuoteText(quote.Text)
+}
+
+func writeJSONOutput(filename string, value any) error {
+	out := os.Stdout
+	if filename != "" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	return writeJSON(out, value)
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
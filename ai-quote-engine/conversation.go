@@ -0,0 +1,105 @@
+package main
+
+import "context"
+
+// DefaultContextDecay is how much of each earlier turn carries into the next
+const DefaultContextDecay = 0.5
+
+// maxContextTurns bounds how far back a conversation remembers
+const maxContextTurns = 5
+
+// Conversation carries emotional context across interactive turns, so a
+// follow-up like "and it's getting worse" keeps what was said before. It also
+// remembers which quotes were shown so they are not repeated. A Conversation
+// belongs to one session and is not safe for concurrent use.
+type Conversation struct {
+	decay float64
+	turns []conversationTurn
+	shown map[string]bool
+}
+
+type conversationTurn struct {
+	query    string
+	features map[string]float64
+}
+
+func NewConversation(decay float64) *Conversation {
+	return &Conversation{
+		decay: decay,
+		shown: make(map[string]bool),
+	}
+}
+
+// ConversationSearcher is implemented by services that can search in context
+type ConversationSearcher interface {
+	SearchConversation(conv *Conversation, query string, topN int, filter QuoteFilter) ([]SearchResult, error)
+}
+
+// Reset forgets earlier turns and the quotes already shown
+func (c *Conversation) Reset() {
+	c.turns = nil
+	c.shown = make(map[string]bool)
+}
+
+// MarkShown records quotes that should not be suggested again
+func (c *Conversation) MarkShown(results []SearchResult) {
+	for _, result := range results {
+		c.shown[quoteRef(result.Quote)] = true
+	}
+}
+
+func (c *Conversation) Decay() float64 {
+	return c.decay
+}
+
+func (c *Conversation) Turns() int {
+	return len(c.turns)
+}
+
+func (c *Conversation) ShownCount() int {
+	return len(c.shown)
+}
+
+func (c *Conversation) hasShown(quote Quote) bool {
+	return c.shown[quoteRef(quote)]
+}
+
+// addTurn records a query unless it repeats the latest turn (e.g. paging
+// through results for the same message)
+func (c *Conversation) addTurn(query string, features map[string]float64) {
+	if n := len(c.turns); n > 0 && c.turns[n-1].query == query {
+		return
+	}
+	c.turns = append(c.turns, conversationTurn{query: query, features: features})
+	if len(c.turns) > maxContextTurns {
+		c.turns = c.turns[len(c.turns)-maxContextTurns:]
+	}
+}
+
+// blend adds the decayed features of the turns before the latest one to the
+// latest turn's features. The newest earlier turn counts decay, the one before
+// decay², and so on.
+func (c *Conversation) blend() map[string]float64 {
+	latest := c.turns[len(c.turns)-1]
+
+	blended := make(map[string]float64, len(latest.features))
+	for feature, value := range latest.features {
+		blended[feature] = value
+	}
+
+	weight := 1.0
+	for i := len(c.turns) - 2; i >= 0; i-- {
+		weight *= c.decay
+		for feature, value := range c.turns[i].features {
+			blended[feature] += value * weight
+		}
+	}
+
+	return blended
+}
+
+// SearchConversation searches with the query blended into the conversation's
+// earlier turns, skipping quotes the conversation has already shown
+func (s *SemanticQuoteService) SearchConversation(conv *Conversation, query string, topN int, filter QuoteFilter) ([]SearchResult, error) {
+	return s.Search(context.Background(), SearchRequest{Query: query, TopN: topN, Filter: filter, Conversation: conv})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestConversationBlend(t *testing.T) {
+	tests := []struct {
+		name  string
+		decay float64
+		turns []map[string]float64
+		want  map[string]float64
+	}{
+		{
+			name:  "one turn",
+			decay: 0.5,
+			turns: []map[string]float64{{"emotion:sadness": 1}},
+			want:  map[string]float64{"emotion:sadness": 1},
+		},
+		{
+			name:  "earlier turns fade",
+			decay: 0.5,
+			turns: []map[string]float64{{"emotion:sadness": 1}, {"emotion:fear": 1}, {"theme:work": 1}},
+			want:  map[string]float64{"emotion:sadness": 0.25, "emotion:fear": 0.5, "theme:work": 1},
+		},
+		{
+			name:  "shared features add up",
+			decay: 0.5,
+			turns: []map[string]float64{{"emotion:sadness": 0.8}, {"emotion:sadness": 0.4}},
+			want:  map[string]float64{"emotion:sadness": 0.8},
+		},
+		{
+			name:  "no decay forgets",
+			decay: 0,
+			turns: []map[string]float64{{"emotion:sadness": 1}, {"emotion:joy": 1}},
+			want:  map[string]float64{"emotion:sadness": 0, "emotion:joy": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewConversation(tt.decay)
+			for i, features := range tt.turns {
+				conv.addTurn(fmt.Sprintf("turn %d", i), features)
+			}
+			got := conv.blend()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for feature, want := range tt.want {
+				if math.Abs(got[feature]-want) > 1e-9 {
+					t.Errorf("%s: got %.3f, want %.3f", feature, got[feature], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConversationTurns(t *testing.T) {
+	conv := NewConversation(DefaultContextDecay)
+	conv.addTurn("I feel lost", nil)
+	conv.addTurn("I feel lost", nil) // paging through the same message
+	if n := conv.Turns(); n != 1 {
+		t.Errorf("got %d turns after repeating a message, want 1", n)
+	}
+	for i := 0; i < 2*maxContextTurns; i++ {
+		conv.addTurn(fmt.Sprintf("turn %d", i), map[string]float64{fmt.Sprintf("turn:%d", i): 1})
+	}
+	if n := conv.Turns(); n != maxContextTurns {
+		t.Errorf("got %d turns, want the last %d", n, maxContextTurns)
+	}
+	if _, ok := conv.blend()["turn:0"]; ok {
+		t.Errorf("the oldest turn is still remembered")
+	}
+}
+
+func TestSearchConversation(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+	conv := NewConversation(DefaultContextDecay)
+
+	first, err := service.SearchConversation(conv, testQueries[0], 3, QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv.MarkShown(first)
+
+	// A follow-up keeps the mood of the first message and skips what was shown
+	followUp := "and it's getting worse"
+	second, err := service.SearchConversation(conv, followUp, 3, QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Turns() != 2 {
+		t.Errorf("got %d turns, want 2", conv.Turns())
+	}
+	for feature, value := range service.AnalyzeQuery(testQueries[0]) {
+		if got := conv.blend()[feature]; got < value*DefaultContextDecay-1e-9 {
+			t.Errorf("%s: got %.3f in the follow-up, want at least %.3f carried over", feature, got, value*DefaultContextDecay)
+		}
+	}
+	for _, result := range second {
+		if conv.hasShown(result.Quote) {
+			t.Errorf("%s was shown again", result.Quote.ID)
+		}
+	}
+
+	// Reset starts over, so the first results come back
+	conv.Reset()
+	again, err := service.SearchConversation(conv, testQueries[0], 3, QuoteFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.ShownCount() != 0 || len(again) != len(first) || again[0].Quote.ID != first[0].Quote.ID {
+		t.Errorf("got %d results led by %s after a reset, want %d led by %s", len(again), again[0].Quote.ID, len(first), first[0].Quote.ID)
+	}
+}
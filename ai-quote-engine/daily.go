@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults for the quote of the day
+const (
+	DefaultDailyMoodDays  = 14
+	DefaultDailyAvoidDays = 30
+
+	// dailyMoodHalfLife is how many days it takes an entry's weight in the
+	// recent mood to halve
+	dailyMoodHalfLife = 7.0
+	// dailyCandidates is how many of the best-fitting quotes the pick is
+	// drawn from, so the quote varies from day to day
+	dailyCandidates = 5
+	// maxCachedDailyPicks bounds the quotes of the day kept in memory
+	maxCachedDailyPicks = 4096
+	// MaxDailyDays bounds the mood and avoidance windows: every day of the
+	// avoidance window is picked in turn, each ranking the whole corpus
+	MaxDailyDays = 365
+)
+
+// ErrInvalidDailyOptions is returned for mood or avoidance windows out of range
+var ErrInvalidDailyOptions = errors.New("invalid quote of the day options")
+
+// DailyOptions describe whose quote of the day to pick, and when
+type DailyOptions struct {
+	User      string
+	Date      time.Time // only the calendar day, in Date's location, matters
+	MoodDays  int       // days of journal entries that make up the recent mood
+	AvoidDays int       // quotes shown in this many days before are skipped
+	Filter    QuoteFilter
+}
+
+// DailyQuote is the quote picked for a user and day
+type DailyQuote struct {
+	Date    string           `json:"date"`
+	User    string           `json:"user,omitempty"`
+	Quote   Quote            `json:"quote"`
+	Score   float64          `json:"score"`   // fit to the recent mood; 0 when there was no mood to fit
+	Mood    EmotionalContext `json:"mood"`    // the recent mood the quote was picked for
+	Entries int              `json:"entries"` // journal entries that made up the mood
+}
+
+// DailyPicker is implemented by services that can pick a quote of the day
+type DailyPicker interface {
+	DailyQuote(journal Journal, options DailyOptions) (*DailyQuote, error)
+}
+
+// DailyQuote picks a quote for the user's recent mood, as read from the
+// journal entries of the days before, avoiding quotes the journal shows were
+// seen recently and the quotes of the day of recent days. When every quote
+// was seen, the ones seen longest ago are picked from.
+//
+// Only entries from before the day count, and the pick is drawn with a hash
+// of the user and date, so every call for the same user and day returns the
+// same quote until the corpus changes. The journal may be nil.
+func (s *SemanticQuoteService) DailyQuote(journal Journal, options DailyOptions) (*DailyQuote, error) {
+	snap := s.current()
+	if snap == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	if options.MoodDays < 1 || options.MoodDays > MaxDailyDays {
+		return nil, fmt.Errorf("%w: mood days must be from 1 to %d, got %d", ErrInvalidDailyOptions, MaxDailyDays, options.MoodDays)
+	}
+	if options.AvoidDays < 0 || options.AvoidDays > MaxDailyDays {
+		return nil, fmt.Errorf("%w: avoid days must be from 0 to %d, got %d", ErrInvalidDailyOptions, MaxDailyDays, options.AvoidDays)
+	}
+	if options.Date.IsZero() {
+		options.Date = time.Now()
+	}
+	y, m, d := options.Date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, options.Date.Location())
+
+	// Earlier quotes of the day are recomputed rather than stored: picking
+	// forward from the start of the avoidance window gives the same answers
+	// on every call. Each day's pick is cached, so only the first call for a
+	// user ranks the whole window.
+	first := day.AddDate(0, 0, -options.AvoidDays)
+	window := max(options.MoodDays, options.AvoidDays)
+	var entries []JournalEntry
+	if journal != nil {
+		var err error
+		if entries, err = journal.Entries(first.AddDate(0, 0, -window)); err != nil {
+			return nil, err
+		}
+	}
+
+	picked := make(map[string]time.Time)
+	var pick *DailyQuote
+	for current := first; !current.After(day); current = current.AddDate(0, 0, 1) {
+		key := dailyKey(options, current, entries, window)
+		if cached, ok := s.daily.get(snap, s.weights, key); ok {
+			if pick = cached; pick != nil {
+				picked[pick.Quote.ID] = current
+			}
+			continue
+		}
+
+		avoidFrom := current.AddDate(0, 0, -options.AvoidDays)
+		lastShown := make(map[string]time.Time)
+		for _, entry := range entries {
+			if !entry.Time.Before(avoidFrom) && entry.Time.Before(current) {
+				for _, quote := range entry.Quotes {
+					lastShown[quote.ID] = entry.Time
+				}
+			}
+		}
+		for id, when := range picked {
+			if !when.Before(avoidFrom) && when.After(lastShown[id]) {
+				lastShown[id] = when
+			}
+		}
+
+		var err error
+		pick, err = s.pickDaily(snap, entries, current, options, func(q Quote) bool {
+			_, shown := lastShown[q.ID]
+			return shown
+		})
+		if errors.Is(err, ErrNoMatches) && len(lastShown) > 0 {
+			// Everything the filter allows was shown recently: repeat the
+			// quotes shown longest ago
+			oldest := current
+			for _, entry := range snap.index {
+				if when, shown := lastShown[entry.Quote.ID]; shown && when.Before(oldest) && options.Filter.Matches(entry.Quote) {
+					oldest = when
+				}
+			}
+			pick, err = s.pickDaily(snap, entries, current, options, func(q Quote) bool {
+				return lastShown[q.ID].After(oldest)
+			})
+		}
+		if errors.Is(err, ErrNoMatches) && current.Before(day) {
+			// A filter may leave nothing for a past day; that only frees
+			// more quotes for the days after it
+			s.daily.put(snap, s.weights, key, nil)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.daily.put(snap, s.weights, key, pick)
+		picked[pick.Quote.ID] = current
+	}
+	if pick == nil {
+		// An earlier call found nothing for this day while picking ahead
+		return nil, ErrNoMatches
+	}
+	// The cached pick is shared; callers get their own copy
+	result := *pick
+	return &result, nil
+}
+
+// dailyCache remembers the quote picked for each user, day and set of
+// options. Picks only hold while the corpus, the ranking weights and the
+// journal entries they were drawn from stay the same.
+type dailyCache struct {
+	mu      sync.Mutex
+	snap    *corpusSnapshot
+	weights *RankingWeights
+	picks   map[string]*DailyQuote // nil when a past day had no pick
+}
+
+func (c *dailyCache) get(snap *corpusSnapshot, weights *RankingWeights, key string) (*DailyQuote, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snap != snap || c.weights != weights {
+		return nil, false
+	}
+	pick, ok := c.picks[key]
+	return pick, ok
+}
+
+func (c *dailyCache) put(snap *corpusSnapshot, weights *RankingWeights, key string, pick *DailyQuote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snap != snap || c.weights != weights || len(c.picks) >= maxCachedDailyPicks {
+		c.snap, c.weights, c.picks = snap, weights, make(map[string]*DailyQuote)
+	}
+	c.picks[key] = pick
+}
+
+// dailyKey identifies the pick for a day: the user, the options, and the
+// journal entries of the days before it that its mood and avoided quotes
+// come from
+func dailyKey(options DailyOptions, day time.Time, entries []JournalEntry, window int) string {
+	h := fnv.New64a()
+	from := day.AddDate(0, 0, -window)
+	for _, entry := range entries {
+		if entry.Time.Before(from) || !entry.Time.Before(day) {
+			continue
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", entry.ID, entry.Time.UnixNano(), len(entry.Features))
+		for _, quote := range entry.Quotes {
+			fmt.Fprintf(h, "%s\x00", quote.ID)
+		}
+	}
+	return fmt.Sprintf("%s\x00%d\x00%d\x00%d\x00%v\x00%x", options.User, day.Unix(),
+		options.MoodDays, options.AvoidDays, options.Filter, h.Sum64())
+}
+
+// pickDaily chooses the quote for one day from the mood of the entries
+// before it
+func (s *SemanticQuoteService) pickDaily(snap *corpusSnapshot, entries []JournalEntry, day time.Time, options DailyOptions, skip func(Quote) bool) (*DailyQuote, error) {
+	mood := make(map[string]float64)
+	moodFrom := day.AddDate(0, 0, -options.MoodDays)
+	count, totalWeight := 0, 0.0
+	for _, entry := range entries {
+		if entry.Time.Before(moodFrom) || !entry.Time.Before(day) {
+			continue
+		}
+		count++
+		age := day.Sub(entry.Time).Hours() / 24
+		weight := math.Pow(0.5, age/dailyMoodHalfLife)
+		totalWeight += weight
+		for feature, value := range entry.Features {
+			mood[feature] += value * weight
+		}
+	}
+	// Scale back to whole entries, so a feature every entry shared reads as
+	// strongly as it would in a single query
+	for feature := range mood {
+		mood[feature] *= float64(count) / totalWeight
+	}
+
+	choice := dailyHash(options.User, day)
+	daily := &DailyQuote{
+		Date:    day.Format("2006-01-02"),
+		User:    options.User,
+		Mood:    NewEmotionalContext(mood),
+		Entries: count,
+	}
+
+	candidates, err := s.rank(context.Background(), snap, mood, dailyCandidates, options.Filter, skip, rankStages{})
+	if err == nil {
+		total := 0.0
+		for _, candidate := range candidates {
+			total += candidate.Score
+		}
+		// Better-fitting quotes are proportionally more likely
+		target := choice * total
+		for _, candidate := range candidates {
+			daily.Quote, daily.Score = candidate.Quote, candidate.Score
+			if target < candidate.Score {
+				break
+			}
+			target -= candidate.Score
+		}
+		return daily, nil
+	}
+	if !errors.Is(err, ErrNoMatches) {
+		return nil, err
+	}
+
+	// No mood to go by, or nothing fits it: any quote will do
+	var eligible []Quote
+	for _, entry := range snap.index {
+		if options.Filter.Matches(entry.Quote) && !skip(entry.Quote) {
+			eligible = append(eligible, entry.Quote)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, ErrNoMatches
+	}
+	daily.Quote = eligible[int(choice*float64(len(eligible)))]
+	return daily, nil
+}
+
+// dailyHash turns a user and day into a number in [0, 1) that never changes
+func dailyHash(user string, day time.Time) float64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s", user, day.Format("2006-01-02"))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// Print shows the quote and the mood it was picked for
+func (d *DailyQuote) Print(w io.Writer) {
+	date, _ := time.Parse("2006-01-02", d.Date)
+	if d.User != "" {
+		fmt.Fprintf(w, "Quote of the day for %s, %s\n\n", d.User, date.Format("Mon 2 Jan 2006"))
+	} else {
+		fmt.Fprintf(w, "Quote of the day, %s\n\n", date.Format("Mon 2 Jan 2006"))
+	}
+	fmt.Fprintf(w, "   \"%s\"\n", d.Quote.Text)
+	fmt.Fprintf(w, "   — %s (%s)\n\n", d.Quote.Character, d.Quote.Movie)
+
+	switch {
+	case d.Entries == 0:
+		fmt.Fprintln(w, "No recent journal entries, so this one is a surprise.")
+	case d.Mood.PrimaryEmotion != "" && d.Score > 0:
+		fmt.Fprintf(w, "Picked for your recent mood: %s (from %d journal entries)\n", d.Mood.PrimaryEmotion, d.Entries)
+	default:
+		fmt.Fprintf(w, "Picked from %d recent journal entries.\n", d.Entries)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDailyQuote(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+	journal := NewFileJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	day := time.Date(2026, 10, 14, 9, 0, 0, 0, time.Local)
+
+	// A week of sad entries, each shown the two quotes that fit best
+	shown := make(map[string]bool)
+	for i := 7; i >= 1; i-- {
+		query := testQueries[0]
+		results, err := service.SearchQuotes(query, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var quotes []JournalQuote
+		for _, result := range results {
+			quotes = append(quotes, JournalQuote{ID: result.Quote.ID, Text: result.Quote.Text})
+			shown[result.Quote.ID] = true
+		}
+		entry := JournalEntry{Time: day.AddDate(0, 0, -i), Query: query, Features: service.AnalyzeQuery(query), Quotes: quotes}
+		if _, err := journal.Log(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options := DailyOptions{User: "sam", Date: day, MoodDays: DefaultDailyMoodDays, AvoidDays: DefaultDailyAvoidDays}
+	pick, err := service.DailyQuote(journal, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pick.Date != "2026-10-14" || pick.Entries != 7 {
+		t.Errorf("got date %s from %d entries, want 2026-10-14 from 7", pick.Date, pick.Entries)
+	}
+	if shown[pick.Quote.ID] {
+		t.Errorf("picked %s, which the journal shows was seen this week", pick.Quote.ID)
+	}
+	if n := len(service.daily.picks); n != DefaultDailyAvoidDays+1 {
+		t.Errorf("cached %d picks, want one per day of the avoidance window", n)
+	}
+
+	// Later calls for the day reuse the picks and hand out copies
+	pick.Quote.Source = "changed"
+	again, err := service.DailyQuote(journal, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Quote.ID != pick.Quote.ID || again.Quote.Source == "changed" {
+		t.Errorf("got %s (source %q) on the second call, want %s unchanged", again.Quote.ID, again.Quote.Source, pick.Quote.ID)
+	}
+	if n := len(service.daily.picks); n != DefaultDailyAvoidDays+1 {
+		t.Errorf("cached %d picks after the second call, want %d", n, DefaultDailyAvoidDays+1)
+	}
+
+	// Another user, or a new entry before the day, is picked afresh
+	other := options
+	other.User = "alex"
+	if _, err := service.DailyQuote(journal, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := journal.Log(JournalEntry{Time: day.Add(-13 * time.Hour), Query: "a good day", Features: service.AnalyzeQuery("a good day")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DailyQuote(journal, options); err != nil {
+		t.Fatal(err)
+	}
+	if n, want := len(service.daily.picks), 2*(DefaultDailyAvoidDays+1)+1; n != want {
+		t.Errorf("cached %d picks, want %d", n, want)
+	}
+
+	// A reload starts over
+	if err := service.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DailyQuote(journal, options); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(service.daily.picks); n != DefaultDailyAvoidDays+1 {
+		t.Errorf("cached %d picks after a reload, want %d", n, DefaultDailyAvoidDays+1)
+	}
+}
+
+func TestDailyQuoteNoMatches(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 1))
+	options := DailyOptions{
+		Date:      time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local),
+		MoodDays:  DefaultDailyMoodDays,
+		AvoidDays: DefaultDailyAvoidDays,
+		Filter:    QuoteFilter{Genres: []string{"nosuchgenre"}},
+	}
+	if _, err := service.DailyQuote(nil, options); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("got %v, want ErrNoMatches", err)
+	}
+	// The day before was picked, and found empty, on the way to the first day
+	options.Date = options.Date.AddDate(0, 0, -1)
+	if _, err := service.DailyQuote(nil, options); !errors.Is(err, ErrNoMatches) {
+		t.Fatalf("got %v for the day before, want ErrNoMatches", err)
+	}
+}
+
+func TestDailyQuoteValidation(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 1))
+	tests := []struct {
+		moodDays, avoidDays int
+		ok                  bool
+	}{
+		{1, 0, true},
+		{MaxDailyDays, MaxDailyDays, true},
+		{0, 0, false},
+		{1, -1, false},
+		{MaxDailyDays + 1, 0, false},
+		{1, MaxDailyDays + 1, false},
+		{1, 100000000, false},
+	}
+	for _, tt := range tests {
+		options := DailyOptions{Date: time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), MoodDays: tt.moodDays, AvoidDays: tt.avoidDays}
+		_, err := service.DailyQuote(nil, options)
+		if tt.ok && err != nil {
+			t.Errorf("mood %d, avoid %d: %v", tt.moodDays, tt.avoidDays, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidDailyOptions) {
+			t.Errorf("mood %d, avoid %d: got %v, want ErrInvalidDailyOptions", tt.moodDays, tt.avoidDays, err)
+		}
+	}
+}
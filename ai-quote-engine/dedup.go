@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// DefaultNearDuplicateThreshold is the shingle Jaccard similarity above which
+// two quotes are considered the same line
+const DefaultNearDuplicateThreshold = 0.8
+
+// shingleSize is the length of the character shingles compared for near duplicates
+const shingleSize = 3
+
+// maxShinglePostings caps how many kept quotes a shingle finds as candidates.
+// Shingles like " th" occur in most quotes and would make every quote a
+// candidate for every other; near duplicates share plenty of rarer ones.
+const maxShinglePostings = 64
+
+// DuplicateMatch is a quote merged into the kept quote of its group
+type DuplicateMatch struct {
+	Quote      Quote   `json:"quote"`
+	Similarity float64 `json:"similarity"`
+	Exact      bool    `json:"exact"`
+}
+
+// DuplicateGroup is one kept quote and everything merged into it
+type DuplicateGroup struct {
+	Kept       Quote            `json:"kept"`
+	Duplicates []DuplicateMatch `json:"duplicates"`
+	Conflicts  []string         `json:"conflicts,omitempty"`
+}
+
+// DedupReport describes the collisions found while loading a corpus
+type DedupReport struct {
+	Groups  []DuplicateGroup `json:"groups"`
+	Removed int              `json:"removed"`
+}
+
+// Conflicts counts the groups whose members disagree on attribution
+func (r *DedupReport) Conflicts() int {
+	n := 0
+	for _, group := range r.Groups {
+		if len(group.Conflicts) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Print lists each group with the similarity of every merged quote
+func (r *DedupReport) Print(w io.Writer) {
+	if len(r.Groups) == 0 {
+		fmt.Fprintln(w, "No duplicate quotes found.")
+		return
+	}
+
+	for _, group := range r.Groups {
+		fmt.Fprintf(w, "\"%s\" — %s (%s)%s\n", group.Kept.Text, group.Kept.Character, group.Kept.Movie, sourceSuffix(group.Kept))
+		for _, dup := range group.Duplicates {
+			kind := "exact"
+			if !dup.Exact {
+				kind = fmt.Sprintf("%.2f", dup.Similarity)
+			}
+			fmt.Fprintf(w, "   %-5s \"%s\" — %s (%s)%s\n", kind, dup.Quote.Text, dup.Quote.Character, dup.Quote.Movie, sourceSuffix(dup.Quote))
+		}
+		for _, conflict := range group.Conflicts {
+			fmt.Fprintf(w, "   ⚠ conflicting %s\n", conflict)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d duplicate(s) merged into %d quote(s), %d with conflicting attributions\n",
+		r.Removed, len(r.Groups), r.Conflicts())
+}
+
+func sourceSuffix(quote Quote) string {
+	if quote.Source == "" {
+		return ""
+	}
+	return " [" + quote.Source + "]"
+}
+
+// normalizeQuoteText folds case, typographic quotes and punctuation so that
+// trivially different renderings of a line compare equal
+func normalizeQuoteText(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '’' || r == '‘' || r == '\'' || r == '`':
+			// Drop apostrophes so "can't" and "cant" match
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+func shingles(normalized string) map[string]bool {
+	runes := []rune(normalized)
+	set := make(map[string]bool)
+	if len(runes) <= shingleSize {
+		set[normalized] = true
+		return set
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		set[string(runes[i:i+shingleSize])] = true
+	}
+	return set
+}
+
+// dedupQuotes merges exact and near-duplicate quotes. The first quote of each
+// group in corpus order is kept, so the result only depends on file order;
+// it borrows a movie or character from its duplicates when its own is empty.
+func dedupQuotes(quotes []Quote, threshold float64) ([]Quote, *DedupReport) {
+	n := len(quotes)
+	normalized := make([]string, n)
+	for i, quote := range quotes {
+		normalized[i] = normalizeQuoteText(quote.Text)
+	}
+
+	// into is the index of the quote each quote is merged into, its own
+	// index when it is kept
+	into := make([]int, n)
+	similarity := make([]float64, n)
+
+	// Exact duplicates after normalization
+	first := make(map[string]int)
+	for i, key := range normalized {
+		if j, ok := first[key]; ok {
+			into[i], similarity[i] = j, 1
+		} else {
+			first[key] = i
+			into[i] = i
+		}
+	}
+
+	// Near duplicates: each quote is compared with the kept quotes before it
+	// and joins the most similar one. Matching only kept quotes means a group
+	// never chains through quotes that are each a little different.
+	if threshold > 0 && threshold < 1 {
+		sets := make([]map[string]bool, n)
+		postings := make(map[string][]int)
+		for i := range quotes {
+			if first[normalized[i]] != i {
+				continue // exact duplicates are already grouped
+			}
+			sets[i] = shingles(normalized[i])
+
+			// Candidates share a shingle that is not too common to tell
+			// quotes apart; the similarity is then measured in full
+			candidates := make(map[int]bool)
+			for shingle := range sets[i] {
+				if len(postings[shingle]) < maxShinglePostings {
+					for _, j := range postings[shingle] {
+						candidates[j] = true
+					}
+				}
+			}
+			best := -1
+			for j := range candidates {
+				s := jaccard(sets[j], sets[i])
+				if s >= threshold && (s > similarity[i] || s == similarity[i] && j < best) {
+					best, similarity[i] = j, s
+				}
+			}
+			if best >= 0 {
+				into[i] = best
+				continue
+			}
+
+			for shingle := range sets[i] {
+				if len(postings[shingle]) < maxShinglePostings {
+					postings[shingle] = append(postings[shingle], i)
+				}
+			}
+		}
+		for i := range quotes {
+			if j := first[normalized[i]]; j != i {
+				into[i] = into[j]
+				if into[i] != j {
+					similarity[i] = similarity[j]
+				}
+			}
+		}
+	}
+
+	// Merge each group into its root
+	report := &DedupReport{}
+	groupIndex := make(map[int]int)
+	var kept []Quote
+	keptIndex := make(map[int]int)
+	for i, quote := range quotes {
+		root := into[i]
+		if root == i {
+			keptIndex[i] = len(kept)
+			kept = append(kept, quote)
+			continue
+		}
+
+		gi, ok := groupIndex[root]
+		if !ok {
+			gi = len(report.Groups)
+			groupIndex[root] = gi
+			report.Groups = append(report.Groups, DuplicateGroup{Kept: quotes[root]})
+		}
+		group := &report.Groups[gi]
+
+		exact := normalized[i] == normalized[root]
+		group.Duplicates = append(group.Duplicates, DuplicateMatch{Quote: quote, Similarity: similarity[i], Exact: exact})
+		report.Removed++
+
+		target := &kept[keptIndex[root]]
+		group.Conflicts = append(group.Conflicts, attributionConflicts(target, quote)...)
+		group.Kept = *target
+	}
+
+	return kept, report
+}
+
+// attributionConflicts fills empty attribution fields of kept from dup and
+// describes any field where both are set but disagree
+func attributionConflicts(kept *Quote, dup Quote) []string {
+	var conflicts []string
+	switch {
+	case dup.ID == "":
+	case kept.ID == "":
+		kept.ID = dup.ID
+	case kept.ID != dup.ID:
+		// The duplicate's ID no longer resolves once it is merged
+		conflicts = append(conflicts, fmt.Sprintf("id: %q vs %q%s", kept.ID, dup.ID, sourceSuffix(dup)))
+	}
+	fields := []struct {
+		name      string
+		kept, dup *string
+	}{
+		{"movie", &kept.Movie, &dup.Movie},
+		{"character", &kept.Character, &dup.Character},
+	}
+	for _, field := range fields {
+		switch {
+		case strings.TrimSpace(*field.dup) == "":
+		case strings.TrimSpace(*field.kept) == "":
+			*field.kept = *field.dup
+		case normalizeQuoteText(*field.kept) != normalizeQuoteText(*field.dup):
+			conflicts = append(conflicts, fmt.Sprintf("%s: %q vs %q%s", field.name, *field.kept, *field.dup, sourceSuffix(dup)))
+		}
+	}
+	return conflicts
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for shingle := range a {
+		if b[shingle] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
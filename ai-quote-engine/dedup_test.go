@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupQuotes(t *testing.T) {
+	fox := "the quick brown fox jumps over the lazy dog"
+	cat := "the quick brown fox jumps over the lazy cat"   // 0.86 like fox
+	leaps := "the quick brown fox leaps over the lazy cat" // 0.77 like cat, 0.66 like fox
+
+	tests := []struct {
+		name      string
+		threshold float64
+		quotes    []Quote
+		kept      []string   // texts kept, in order
+		groups    [][]string // texts merged into each group, in order
+		exact     []bool     // whether each merged quote was an exact match, flattened
+		conflicts int
+	}{
+		{
+			name:      "exact after normalization",
+			threshold: DefaultNearDuplicateThreshold,
+			quotes: []Quote{
+				{Text: "I'll be back.", Movie: "The Terminator", Character: "T-800"},
+				{Text: "Ill be BACK", Movie: "The Terminator"},
+				{Text: "Hasta la vista, baby.", Movie: "Terminator 2", Character: "T-800"},
+			},
+			kept:   []string{"I'll be back.", "Hasta la vista, baby."},
+			groups: [][]string{{"Ill be BACK"}},
+			exact:  []bool{true},
+		},
+		{
+			name:      "near duplicate",
+			threshold: DefaultNearDuplicateThreshold,
+			quotes:    []Quote{{Text: fox}, {Text: cat}},
+			kept:      []string{fox},
+			groups:    [][]string{{cat}},
+			exact:     []bool{false},
+		},
+		{
+			name:      "near duplicates do not chain",
+			threshold: 0.7,
+			quotes:    []Quote{{Text: fox}, {Text: cat}, {Text: leaps}},
+			kept:      []string{fox, leaps},
+			groups:    [][]string{{cat}},
+			exact:     []bool{false},
+		},
+		{
+			name:      "exact copy of a near duplicate joins its group",
+			threshold: DefaultNearDuplicateThreshold,
+			quotes:    []Quote{{Text: fox}, {Text: cat}, {Text: cat + "!"}},
+			kept:      []string{fox},
+			groups:    [][]string{{cat, cat + "!"}},
+			exact:     []bool{false, false},
+		},
+		{
+			name:      "threshold 1 merges exact duplicates only",
+			threshold: 1,
+			quotes:    []Quote{{Text: fox}, {Text: cat}, {Text: fox}},
+			kept:      []string{fox, cat},
+			groups:    [][]string{{fox}},
+			exact:     []bool{true},
+		},
+		{
+			name:      "conflicting attribution",
+			threshold: DefaultNearDuplicateThreshold,
+			quotes: []Quote{
+				{ID: "a", Text: "Play it again, Sam.", Movie: "Casablanca"},
+				{ID: "b", Text: "Play it again Sam", Movie: "Casablanca", Character: "Rick"},
+			},
+			kept:      []string{"Play it again, Sam."},
+			groups:    [][]string{{"Play it again Sam"}},
+			exact:     []bool{true},
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, report := dedupQuotes(tt.quotes, tt.threshold)
+
+			if len(kept) != len(tt.kept) {
+				t.Fatalf("kept %d quotes, want %d", len(kept), len(tt.kept))
+			}
+			for i, quote := range kept {
+				if quote.Text != tt.kept[i] {
+					t.Errorf("kept quote %d: got %q, want %q", i+1, quote.Text, tt.kept[i])
+				}
+			}
+			if len(report.Groups) != len(tt.groups) {
+				t.Fatalf("got %d groups, want %d", len(report.Groups), len(tt.groups))
+			}
+			var exact []bool
+			for i, group := range report.Groups {
+				if len(group.Duplicates) != len(tt.groups[i]) {
+					t.Fatalf("group %d: got %d duplicates, want %d", i+1, len(group.Duplicates), len(tt.groups[i]))
+				}
+				for j, dup := range group.Duplicates {
+					if dup.Quote.Text != tt.groups[i][j] {
+						t.Errorf("group %d: got duplicate %q, want %q", i+1, dup.Quote.Text, tt.groups[i][j])
+					}
+					if dup.Similarity < tt.threshold {
+						t.Errorf("group %d: %q merged at similarity %.2f", i+1, dup.Quote.Text, dup.Similarity)
+					}
+					exact = append(exact, dup.Exact)
+				}
+			}
+			if fmt.Sprint(exact) != fmt.Sprint(tt.exact) {
+				t.Errorf("got exact %v, want %v", exact, tt.exact)
+			}
+			if report.Conflicts() != tt.conflicts {
+				t.Errorf("got %d conflicts, want %d", report.Conflicts(), tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestDedupQuotesCommonShingles(t *testing.T) {
+	// Every quote shares " the " shingles, more than a posting keeps
+	var quotes []Quote
+	for i := 0; i < 4*maxShinglePostings; i++ {
+		quotes = append(quotes, Quote{Text: fmt.Sprintf("the %s of the %s", word(i), word(i+7919))})
+	}
+	near := quotes[3*maxShinglePostings].Text + " yes"
+	quotes = append(quotes, Quote{Text: near})
+
+	kept, report := dedupQuotes(quotes, 0.7)
+	if len(kept) != 4*maxShinglePostings || report.Removed != 1 {
+		t.Fatalf("kept %d and removed %d, want %d and 1", len(kept), report.Removed, 4*maxShinglePostings)
+	}
+	if got := report.Groups[0].Duplicates[0].Quote.Text; got != near {
+		t.Errorf("merged %q, want %q", got, near)
+	}
+}
+
+// word spells n in letters, so each quote has shingles of its own
+func word(n int) string {
+	b := []byte("qqqqqq")
+	for i := range b {
+		b[i] = byte('a' + n%26)
+		n /= 26
+	}
+	return string(b)
+}
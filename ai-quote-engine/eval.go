@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EvalCase is a judged query: the quotes a good ranking should surface (by ID
+// or text), or whether the query must trigger the crisis response
+type EvalCase struct {
+	Query    string   `json:"query"`
+	Relevant []string `json:"relevant,omitempty"`
+	Crisis   bool     `json:"crisis,omitempty"`
+}
+
+type EvalSet struct {
+	Cases []EvalCase `json:"cases"`
+}
+
+// EvalOutcome records how the service ranked a single case
+type EvalOutcome struct {
+	Case          EvalCase
+	Rank          int // 1-based rank of the first relevant quote, 0 if missing
+	RelevantFound int
+	CrisisFlagged bool
+	Err           error
+}
+
+type EvalReport struct {
+	TopN     int
+	Outcomes []EvalOutcome
+
+	RankedCases   int
+	HitRate       float64 // share of ranked cases with a relevant quote in the top N
+	MRR           float64 // mean reciprocal rank of the first relevant quote
+	Precision     float64 // mean share of the top N that is relevant
+	CrisisCases   int
+	CrisisCorrect int // crisis expectations met, over all cases
+	Errors        int
+}
+
+func LoadEvalCases(filename string) ([]EvalCase, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open eval cases file: %w", err)
+	}
+	defer file.Close()
+
+	var set EvalSet
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse eval cases file: %w", err)
+	}
+
+	if len(set.Cases) == 0 {
+		return nil, fmt.Errorf("no eval cases found in file")
+	}
+
+	for i, c := range set.Cases {
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("eval case %d: query cannot be empty", i+1)
+		}
+		if !c.Crisis && len(c.Relevant) == 0 {
+			return nil, fmt.Errorf("eval case %d: needs relevant quotes or crisis: true", i+1)
+		}
+	}
+
+	return set.Cases, nil
+}
+
+// Evaluate runs every case through the service and aggregates ranking metrics
+func Evaluate(service QuoteService, cases []EvalCase, topN int) *EvalReport {
+	report := &EvalReport{TopN: topN}
+
+	for _, c := range cases {
+		outcome := EvalOutcome{Case: c}
+
+		results, err := service.SearchQuotes(c.Query, topN)
+		switch {
+		case errors.Is(err, ErrCrisisDetected):
+			outcome.CrisisFlagged = true
+		case errors.Is(err, ErrNoMatches):
+			// Nothing ranked; counts as a miss
+		case err != nil:
+			outcome.Err = err
+			report.Errors++
+		}
+
+		relevant := make(map[string]bool)
+		for _, ref := range c.Relevant {
+			relevant[ref] = true
+			relevant[normalizeEvalText(ref)] = true
+		}
+		for i, result := range results {
+			if relevant[result.Quote.ID] || relevant[normalizeEvalText(result.Quote.Text)] {
+				outcome.RelevantFound++
+				if outcome.Rank == 0 {
+					outcome.Rank = i + 1
+				}
+			}
+		}
+
+		if c.Crisis {
+			report.CrisisCases++
+		}
+		if c.Crisis == outcome.CrisisFlagged {
+			report.CrisisCorrect++
+		}
+
+		if !c.Crisis && outcome.Err == nil {
+			report.RankedCases++
+			if outcome.Rank > 0 {
+				report.HitRate++
+				report.MRR += 1.0 / float64(outcome.Rank)
+			}
+			report.Precision += float64(outcome.RelevantFound) / float64(topN)
+		}
+
+		report.Outcomes = append(report.Outcomes, outcome)
+	}
+
+	if report.RankedCases > 0 {
+		n := float64(report.RankedCases)
+		report.HitRate /= n
+		report.MRR /= n
+		report.Precision /= n
+	}
+
+	return report
+}
+
+func normalizeEvalText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+func (r *EvalReport) Print(w io.Writer, verbose bool) {
+	if verbose {
+		for i, outcome := range r.Outcomes {
+			status := "miss"
+			switch {
+			case outcome.Err != nil:
+				status = "error: " + outcome.Err.Error()
+			case outcome.Case.Crisis && outcome.CrisisFlagged:
+				status = "crisis detected"
+			case outcome.Case.Crisis:
+				status = "crisis MISSED"
+			case outcome.CrisisFlagged:
+				status = "unexpected crisis"
+			case outcome.Rank > 0:
+				status = fmt.Sprintf("hit at rank %d", outcome.Rank)
+			}
+			fmt.Fprintf(w, "%3d. %-18s %s\n", i+1, status, outcome.Case.Query)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Cases:            %d (%d ranked, %d crisis)\n", len(r.Outcomes), r.RankedCases, r.CrisisCases)
+	fmt.Fprintf(w, "Hit rate@%d:       %.3f\n", r.TopN, r.HitRate)
+	fmt.Fprintf(w, "MRR@%d:            %.3f\n", r.TopN, r.MRR)
+	fmt.Fprintf(w, "Precision@%d:      %.3f\n", r.TopN, r.Precision)
+	fmt.Fprintf(w, "Crisis accuracy:  %d/%d\n", r.CrisisCorrect, len(r.Outcomes))
+	if r.Errors > 0 {
+		fmt.Fprintf(w, "Errors:           %d\n", r.Errors)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FeatureContribution is one shared feature's share of the cosine similarity
+type FeatureContribution struct {
+	Feature      string  `json:"feature"`
+	QueryWeight  float64 `json:"query_weight"`
+	QuoteWeight  float64 `json:"quote_weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+// MatchExplanation breaks a score down into the factors that produced it
+type MatchExplanation struct {
+	Quote            Quote                 `json:"-"` // already part of every result that carries an explanation
+	Score            float64               `json:"score"`
+	Cosine           float64               `json:"cosine"`
+	Compatible       bool                  `json:"compatible"`
+	QuerySentiment   string                `json:"query_sentiment"`
+	QuoteSentiment   string                `json:"quote_sentiment"`
+	SentimentPenalty float64               `json:"sentiment_penalty"`
+	TonePenalty      float64               `json:"tone_penalty"`
+	Exposure         float64               `json:"exposure,omitempty"`        // how much the user has seen the quote lately
+	NoveltyPenalty   float64               `json:"novelty_penalty,omitempty"` // multiplier for that; set only when exposure is tracked
+	Profile          *ProfileBoost         `json:"profile,omitempty"`         // set only for personalized searches
+	Shared           []FeatureContribution `json:"shared"`
+}
+
+// QuoteExplainer is implemented by services that can explain their scores
+type QuoteExplainer interface {
+	ExplainMatch(query string, quote Quote) (*MatchExplanation, error)
+}
+
+// ExplainMatch explains how a quote scores against a query
+func (s *SemanticQuoteService) ExplainMatch(query string, quote Quote) (*MatchExplanation, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	lexicon := s.activeLexicon()
+	queryFeatures := s.analyzeText(lexicon, query)
+	quoteFeatures := s.analyzeText(lexicon, quote.Text)
+
+	explanation := s.similarityBreakdown(queryFeatures, quoteFeatures)
+	explanation.Quote = quote
+	explanation.Compatible = s.areTonesCompatible(queryFeatures, quoteFeatures, quote.Text)
+	return explanation, nil
+}
+
+func sortContributions(contributions []FeatureContribution) {
+	sort.Slice(contributions, func(i, j int) bool {
+		if contributions[i].Contribution != contributions[j].Contribution {
+			return contributions[i].Contribution > contributions[j].Contribution
+		}
+		return contributions[i].Feature < contributions[j].Feature
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for the novelty penalty
+const (
+	DefaultNoveltyPenalty  = 0.5
+	DefaultNoveltyHalfLife = 7 * 24 * time.Hour
+
+	// maxShowingsPerQuote bounds how many showings of one quote are kept per
+	// user; older ones barely count by then
+	maxShowingsPerQuote = 10
+)
+
+// NoveltyOptions tune how quotes a user has seen give way to fresh ones
+type NoveltyOptions struct {
+	// Penalty is the most a score can lose to repeated, recent showings,
+	// from 0 (none) to 1; a single showing just now costs half of it
+	Penalty float64
+	// HalfLife is how long it takes a showing to count half as much
+	HalfLife time.Duration
+}
+
+// ExposureStore remembers which quotes were shown to each user, and when
+type ExposureStore interface {
+	// RecordShown notes that quotes were shown to a user at a time
+	RecordShown(user string, quoteIDs []string, at time.Time) error
+	// Shown lists when each quote was shown to a user, oldest first
+	Shown(user string) (map[string][]time.Time, error)
+}
+
+// Novelty is a user's exposure to quotes at one moment. Searches given a
+// Novelty scale each quote's similarity by Factor, so quotes seen often and
+// recently rank lower while a clearly better fit still wins. The nil Novelty
+// leaves scores unchanged.
+type Novelty struct {
+	penalty  float64
+	exposure map[string]float64
+	shown    map[string]int
+}
+
+// NewNovelty weighs every showing by its age: 1 for a showing now, halving
+// with every half-life since
+func NewNovelty(shown map[string][]time.Time, now time.Time, options NoveltyOptions) *Novelty {
+	n := &Novelty{
+		penalty:  options.Penalty,
+		exposure: make(map[string]float64, len(shown)),
+		shown:    make(map[string]int, len(shown)),
+	}
+	halfLife := options.HalfLife.Hours()
+	for id, times := range shown {
+		for _, at := range times {
+			age := math.Max(now.Sub(at).Hours(), 0)
+			n.exposure[id] += math.Pow(0.5, age/halfLife)
+		}
+		n.shown[id] = len(times)
+	}
+	return n
+}
+
+// Exposure is how much the user has seen a quote lately
+func (n *Novelty) Exposure(quote Quote) float64 {
+	if n == nil {
+		return 0
+	}
+	return n.exposure[quoteRef(quote)]
+}
+
+// Factor is the multiplier for a quote's score: 1 for a quote never shown,
+// approaching 1-penalty as its exposure grows
+func (n *Novelty) Factor(quote Quote) float64 {
+	exposure := n.Exposure(quote)
+	if exposure == 0 {
+		return 1
+	}
+	return 1 - n.penalty*exposure/(1+exposure)
+}
+
+// Shown is how many showings of a quote are remembered
+func (n *Novelty) Shown(quote Quote) int {
+	if n == nil {
+		return 0
+	}
+	return n.shown[quoteRef(quote)]
+}
+
+// ExposureTracker records what one user is shown and reads it back as the
+// novelty for their next search
+type ExposureTracker struct {
+	store   ExposureStore
+	user    string
+	options NoveltyOptions
+}
+
+func NewExposureTracker(store ExposureStore, user string, options NoveltyOptions) *ExposureTracker {
+	return &ExposureTracker{store: store, user: user, options: options}
+}
+
+// Novelty reads the user's exposure as of now
+func (t *ExposureTracker) Novelty() (*Novelty, error) {
+	shown, err := t.store.Shown(t.user)
+	if err != nil {
+		return nil, err
+	}
+	return NewNovelty(shown, time.Now(), t.options), nil
+}
+
+// Record notes the results as shown to the user now
+func (t *ExposureTracker) Record(results []SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = quoteRef(result.Quote)
+	}
+	return t.store.RecordShown(t.user, ids, time.Now())
+}
+
+// exposureFile maps each user to the times each quote was shown to them
+type exposureFile struct {
+	Users map[string]map[string][]time.Time `json:"users"`
+}
+
+// File Exposure Implementation. Safe for concurrent use within one process.
+type FileExposureStore struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewFileExposureStore(filename string) *FileExposureStore {
+	return &FileExposureStore{filename: filename}
+}
+
+func (f *FileExposureStore) RecordShown(user string, quoteIDs []string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+	if data.Users == nil {
+		data.Users = make(map[string]map[string][]time.Time)
+	}
+	shown := data.Users[user]
+	if shown == nil {
+		shown = make(map[string][]time.Time)
+		data.Users[user] = shown
+	}
+	for _, id := range quoteIDs {
+		times := append(shown[id], at)
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		if len(times) > maxShowingsPerQuote {
+			times = times[len(times)-maxShowingsPerQuote:]
+		}
+		shown[id] = times
+	}
+	return saveJSONFile(f.filename, 0o600, data)
+}
+
+func (f *FileExposureStore) Shown(user string) (map[string][]time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return data.Users[user], nil
+}
+
+// Purge drops the showings before a time, and the users left with none
+func (f *FileExposureStore) Purge(before time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for user, shown := range data.Users {
+		for id, times := range shown {
+			kept := showingsSince(times, before)
+			dropped += len(times) - len(kept)
+			if len(kept) == 0 {
+				delete(shown, id)
+			} else {
+				shown[id] = kept
+			}
+		}
+		if len(shown) == 0 {
+			delete(data.Users, user)
+		}
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+	return dropped, saveJSONFile(f.filename, 0o600, data)
+}
+
+func (f *FileExposureStore) load() (*exposureFile, error) {
+	var data exposureFile
+	if err := loadJSONFile(f.filename, &data); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// validate checks that the options are usable
+func (o NoveltyOptions) validate() error {
+	if o.Penalty < 0 || o.Penalty > 1 {
+		return fmt.Errorf("novelty penalty must be between 0 and 1")
+	}
+	if o.HalfLife <= 0 {
+		return fmt.Errorf("novelty half-life must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNoveltyFactor(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	options := NoveltyOptions{Penalty: 0.5, HalfLife: 24 * time.Hour}
+	quote := Quote{ID: "q-1"}
+
+	tests := []struct {
+		name  string
+		shown []time.Time
+		want  float64
+	}{
+		{"never shown", nil, 1},
+		// One showing now has exposure 1 and costs half the penalty
+		{"shown just now", []time.Time{now}, 1 - 0.5*1/2.0},
+		{"shown a half-life ago", []time.Time{now.Add(-24 * time.Hour)}, 1 - 0.5*0.5/1.5},
+		{"shown two half-lives ago", []time.Time{now.Add(-48 * time.Hour)}, 1 - 0.5*0.25/1.25},
+		{"shown twice just now", []time.Time{now, now}, 1 - 0.5*2/3.0},
+		{"shown in the future counts as now", []time.Time{now.Add(time.Hour)}, 1 - 0.5*1/2.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			novelty := NewNovelty(map[string][]time.Time{quote.ID: tt.shown}, now, options)
+			if got := novelty.Factor(quote); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got factor %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+
+	// The factor climbs back towards 1 as a showing ages
+	previous := 0.0
+	for days := 0; days <= 30; days++ {
+		novelty := NewNovelty(map[string][]time.Time{quote.ID: {now.AddDate(0, 0, -days)}}, now, options)
+		factor := novelty.Factor(quote)
+		if factor <= previous || factor >= 1 {
+			t.Fatalf("day %d: got factor %.6f after %.6f, want it rising towards 1", days, factor, previous)
+		}
+		previous = factor
+	}
+	if novelty := (*Novelty)(nil); novelty.Factor(quote) != 1 {
+		t.Errorf("the nil novelty changed a score")
+	}
+}
+
+func TestNoveltyOutsideRetention(t *testing.T) {
+	store := NewFileExposureStore(filepath.Join(t.TempDir(), "exposure.json"))
+	now := time.Now()
+	if err := store.RecordShown("sam", []string{"q-old"}, now.AddDate(0, 0, -40)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordShown("sam", []string{"q-new"}, now.AddDate(0, 0, -1)); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PrivacyPolicy{Retention: 30 * 24 * time.Hour}
+	novelty, err := NewExposureTracker(policy.Exposure(store), "sam", NoveltyOptions{Penalty: 0.5, HalfLife: DefaultNoveltyHalfLife}).Novelty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factor := novelty.Factor(Quote{ID: "q-old"}); factor != 1 {
+		t.Errorf("got factor %.4f for a quote shown before the retention window, want 1", factor)
+	}
+	if factor := novelty.Factor(Quote{ID: "q-new"}); factor >= 1 {
+		t.Errorf("got factor %.4f for a quote shown yesterday, want less than 1", factor)
+	}
+}
+
+func TestSearchRecordsExposureOnlyWhenAsked(t *testing.T) {
+	quotes := testCorpus(t, 20)
+	dir := t.TempDir()
+	writeTestQuotes(t, filepath.Join(dir, "quotes.json"), quotes)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	stdout := os.Stdout
+	if os.Stdout, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Stdout.Close(); os.Stdout = stdout })
+
+	if code := runCommand([]string{"search", "--quotes", "quotes.json", "-q", testQueries[1]}); code != exitOK {
+		t.Fatalf("got exit code %d", code)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		t.Fatalf("a search without --exposure left %q, want only quotes.json", names)
+	}
+
+	if code := runCommand([]string{"search", "--quotes", "quotes.json", "--exposure", "seen.json", "-q", testQueries[1]}); code != exitOK {
+		t.Fatalf("got exit code %d", code)
+	}
+	shown, err := NewFileExposureStore("seen.json").Shown("")
+	if err != nil || len(shown) == 0 {
+		t.Errorf("got showings %v and error %v from --exposure seen.json, want the results shown", shown, err)
+	}
+}
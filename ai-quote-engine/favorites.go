@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FavoritesCollection is the collection :save and the TUI's f key add to
+const FavoritesCollection = "favorites"
+
+// Collection errors
+var (
+	ErrCollectionNotFound    = errors.New("collection not found")
+	ErrNotInCollection       = errors.New("quote is not in the collection")
+	ErrInvalidCollectionName = errors.New("invalid collection name")
+)
+
+// FavoritesStore persists the quotes a user chose to keep
+type FavoritesStore interface {
+	Add(quote Quote) (bool, error)
+	List() ([]Quote, error)
+}
+
+// CollectionStore keeps named collections of quotes, keyed by quote ID. The
+// favorites are the collection named "favorites", which always exists.
+type CollectionStore interface {
+	FavoritesStore
+	AddToCollection(name string, quote Quote) (bool, error)
+	RemoveFromCollection(name, quoteID string) error
+	Collection(name string) ([]Quote, error)
+	Collections() ([]CollectionSummary, error)
+}
+
+// CollectionSummary names a collection and counts its quotes
+type CollectionSummary struct {
+	Name   string `json:"name"`
+	Quotes int    `json:"quotes"`
+}
+
+// NormalizeCollectionName lowercases a collection name and checks that it
+// can be used in a command line and a URL path
+func NormalizeCollectionName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: the name cannot be empty", ErrInvalidCollectionName)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("%w %q: use only letters, digits, - and _", ErrInvalidCollectionName, name)
+		}
+	}
+	return name, nil
+}
+
+// favoritesFile keeps the favorites at the top level, where files written
+// before collections existed have them
+type favoritesFile struct {
+	Favorites   []Quote            `json:"favorites"`
+	Collections map[string][]Quote `json:"collections,omitempty"`
+}
+
+func (f *favoritesFile) collection(name string) []Quote {
+	if name == FavoritesCollection {
+		return f.Favorites
+	}
+	return f.Collections[name]
+}
+
+func (f *favoritesFile) setCollection(name string, quotes []Quote) {
+	if name == FavoritesCollection {
+		f.Favorites = quotes
+		return
+	}
+	if len(quotes) == 0 {
+		delete(f.Collections, name)
+		return
+	}
+	if f.Collections == nil {
+		f.Collections = make(map[string][]Quote)
+	}
+	f.Collections[name] = quotes
+}
+
+// File Favorites Implementation. Safe for concurrent use within one process.
+type FileFavoritesStore struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewFileFavoritesStore(filename string) *FileFavoritesStore {
+	return &FileFavoritesStore{filename: filename}
+}
+
+// Add saves a quote, reporting false if it was already a favorite
+func (f *FileFavoritesStore) Add(quote Quote) (bool, error) {
+	return f.AddToCollection(FavoritesCollection, quote)
+}
+
+func (f *FileFavoritesStore) List() ([]Quote, error) {
+	return f.Collection(FavoritesCollection)
+}
+
+// AddToCollection saves a quote to a collection, creating the collection if
+// needed, and reports false if the quote was already in it
+func (f *FileFavoritesStore) AddToCollection(name string, quote Quote) (bool, error) {
+	name, err := NormalizeCollectionName(name)
+	if err != nil {
+		return false, err
+	}
+	if quote.ID == "" {
+		return false, fmt.Errorf("only quotes with an id can be saved")
+	}
+	// Where the server loaded the quote from is not part of the quote
+	quote.Source = ""
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return false, err
+	}
+	quotes := data.collection(name)
+	for _, existing := range quotes {
+		if sameQuote(existing, quote) {
+			return false, nil
+		}
+	}
+
+	data.setCollection(name, append(quotes, quote))
+	if err := saveJSONFile(f.filename, 0o600, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveFromCollection takes a quote out of a collection. A collection other
+// than the favorites is deleted with its last quote.
+func (f *FileFavoritesStore) RemoveFromCollection(name, quoteID string) error {
+	name, err := NormalizeCollectionName(name)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+	quotes := data.collection(name)
+	if quotes == nil && name != FavoritesCollection {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	for i, quote := range quotes {
+		if quoteRef(quote) == quoteID {
+			data.setCollection(name, append(quotes[:i:i], quotes[i+1:]...))
+			return saveJSONFile(f.filename, 0o600, data)
+		}
+	}
+	return fmt.Errorf("%w: %s is not in %s", ErrNotInCollection, quoteID, name)
+}
+
+// Collection lists the quotes of a collection in the order they were saved
+func (f *FileFavoritesStore) Collection(name string) ([]Quote, error) {
+	name, err := NormalizeCollectionName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	quotes := data.collection(name)
+	if quotes == nil && name != FavoritesCollection {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return quotes, nil
+}
+
+// Collections lists the favorites and then the other collections by name
+func (f *FileFavoritesStore) Collections() ([]CollectionSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	summaries := []CollectionSummary{{Name: FavoritesCollection, Quotes: len(data.Favorites)}}
+	for name, quotes := range data.Collections {
+		summaries = append(summaries, CollectionSummary{Name: name, Quotes: len(quotes)})
+	}
+	sort.Slice(summaries[1:], func(i, j int) bool { return summaries[i+1].Name < summaries[j+1].Name })
+	return summaries, nil
+}
+
+func (f *FileFavoritesStore) load() (*favoritesFile, error) {
+	var data favoritesFile
+	if err := loadJSONFile(f.filename, &data); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// ExportCollection writes quotes as a quotes file, "json" or "csv", that can
+// be searched with --quotes or merged with the import command
+func ExportCollection(w io.Writer, quotes []Quote, format string) error {
+	switch format {
+	case "json":
+		if quotes == nil {
+			quotes = []Quote{}
+		}
+		return writeJSON(w, QuoteData{Quotes: quotes})
+	case "csv":
+		out := csv.NewWriter(w)
+		out.Write([]string{"id", "text", "movie", "character", "year", "genres", "rating", "language", "context"})
+		for _, quote := range quotes {
+			year := ""
+			if quote.Year != 0 {
+				year = strconv.Itoa(quote.Year)
+			}
+			out.Write([]string{
+				quote.ID, quote.Text, quote.Movie, quote.Character, year,
+				strings.Join(quote.Genres, ","), string(quote.Rating), quote.Language, quote.Context,
+			})
+		}
+		out.Flush()
+		return out.Error()
+	}
+	return fmt.Errorf("unknown export format %q (expected json or csv)", format)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeCollectionName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"favorites", "favorites", true},
+		{" Road-Trip_2 ", "road-trip_2", true},
+		{"", "", false},
+		{"   ", "", false},
+		{"road trip", "", false},
+		{"road/trip", "", false},
+		{"../secrets", "", false},
+		{"café", "", false},
+		{"tag?x=1", "", false},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeCollectionName(tt.name)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("NormalizeCollectionName(%q) = %q, %v; want %q, ok %v", tt.name, got, err, tt.want, tt.ok)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidCollectionName) {
+			t.Errorf("NormalizeCollectionName(%q): got error %v, want ErrInvalidCollectionName", tt.name, err)
+		}
+	}
+}
+
+func TestCollections(t *testing.T) {
+	store := NewFileFavoritesStore(filepath.Join(t.TempDir(), "favorites.json"))
+	rick := Quote{ID: "q-1", Text: "Here's looking at you, kid.", Movie: "Casablanca", Character: "Rick", Source: "quotes.json"}
+	dory := Quote{ID: "q-2", Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"}
+
+	if added, err := store.AddToCollection("Road-Trip", rick); err != nil || !added {
+		t.Fatalf("got %v, %v adding to a new collection", added, err)
+	}
+	if added, err := store.AddToCollection("road-trip", rick); err != nil || added {
+		t.Errorf("got %v, %v adding a quote twice, want false", added, err)
+	}
+	if _, err := store.AddToCollection("road-trip", dory); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Add(dory); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddToCollection("road-trip", Quote{Text: "No id"}); err == nil {
+		t.Errorf("saved a quote without an id")
+	}
+
+	quotes, err := store.Collection("road-trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 2 || quotes[0].ID != "q-1" || quotes[0].Source != "" {
+		t.Errorf("got %+v, want rick then dory, without where they were loaded from", quotes)
+	}
+	summaries, err := store.Collections()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CollectionSummary{{Name: FavoritesCollection, Quotes: 1}, {Name: "road-trip", Quotes: 2}}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("got %+v, want %+v", summaries, want)
+	}
+
+	// Removing the last quote deletes the collection, but never the favorites
+	if err := store.RemoveFromCollection("road-trip", "q-3"); !errors.Is(err, ErrNotInCollection) {
+		t.Errorf("removing a quote not in the collection: got %v, want ErrNotInCollection", err)
+	}
+	for _, id := range []string{"q-1", "q-2"} {
+		if err := store.RemoveFromCollection("road-trip", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Collection("road-trip"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("got %v for the emptied collection, want ErrCollectionNotFound", err)
+	}
+	if err := store.RemoveFromCollection("road-trip", "q-1"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("got %v removing from a deleted collection, want ErrCollectionNotFound", err)
+	}
+	if err := store.RemoveFromCollection(FavoritesCollection, "q-2"); err != nil {
+		t.Fatal(err)
+	}
+	if favorites, err := store.List(); err != nil || len(favorites) != 0 {
+		t.Errorf("got %d favorites and error %v, want an empty collection", len(favorites), err)
+	}
+	summaries, _ = store.Collections()
+	if len(summaries) != 1 || summaries[0].Name != FavoritesCollection {
+		t.Errorf("got %+v, want only the empty favorites", summaries)
+	}
+}
+
+func TestExportCollection(t *testing.T) {
+	quotes := []Quote{
+		{ID: "q-1", Text: "Here's looking at you, kid.", Movie: "Casablanca", Character: "Rick", Year: 1942, Genres: []string{"drama", "romance"}, Rating: "PG", Language: "en"},
+		{ID: "q-2", Text: `He said "keep swimming", so I did`, Movie: "Finding Nemo", Character: "Marlin"},
+	}
+
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := ExportCollection(&out, quotes, format); err != nil {
+				t.Fatal(err)
+			}
+			// The export reads back as a quotes file
+			data, err := parseQuotes("collection."+format, out.Bytes(), LoadOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data.Quotes, quotes) {
+				t.Errorf("read back %+v, want %+v", data.Quotes, quotes)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if err := ExportCollection(&out, nil, "json"); err != nil || !bytes.Contains(out.Bytes(), []byte(`"quotes": []`)) {
+		t.Errorf("got %q, %v for an empty collection, want an empty quotes list", out.String(), err)
+	}
+	if err := ExportCollection(&out, quotes, "xml"); err == nil {
+		t.Errorf("exported to an unknown format")
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feedback ratings a user can give a result
+const (
+	RatingHelpful       = "helpful"
+	RatingNotHelpful    = "not_helpful"
+	RatingInappropriate = "inappropriate"
+)
+
+// ParseFeedbackRating accepts a rating name or a short form such as "+" or "flag"
+func ParseFeedbackRating(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case RatingHelpful, "+", "yes", "y", "up":
+		return RatingHelpful, nil
+	case RatingNotHelpful, "not-helpful", "-", "no", "n", "down":
+		return RatingNotHelpful, nil
+	case RatingInappropriate, "flag", "!":
+		return RatingInappropriate, nil
+	}
+	return "", fmt.Errorf("unknown rating %q (expected helpful, not-helpful or inappropriate)", value)
+}
+
+// FeedbackEntry records how a user rated a quote shown for a query
+type FeedbackEntry struct {
+	Time     time.Time          `json:"time"`
+	Query    string             `json:"query"`
+	Features map[string]float64 `json:"features,omitempty"` // what the engine read in the query
+	Quote    Quote              `json:"quote"`
+	Rating   string             `json:"rating"`
+	Comment  string             `json:"comment,omitempty"`
+}
+
+// FeedbackStore persists feedback on results
+type FeedbackStore interface {
+	Record(entry FeedbackEntry) error
+	List() ([]FeedbackEntry, error)
+}
+
+// QueryAnalyzer is implemented by services that can show the features they
+// read in a query
+type QueryAnalyzer interface {
+	AnalyzeQuery(query string) map[string]float64
+}
+
+// AnalyzeQuery extracts the emotion, theme, sentiment and tone features of a
+// query with the current lexicon
+func (s *SemanticQuoteService) AnalyzeQuery(query string) map[string]float64 {
+	return s.analyzeText(s.activeLexicon(), query)
+}
+
+// newFeedbackEntry fills in the time and, when the service can tell, the
+// query's features
+func newFeedbackEntry(service QuoteService, query string, quote Quote, rating, comment string) FeedbackEntry {
+	entry := FeedbackEntry{
+		Time:    time.Now(),
+		Query:   query,
+		Quote:   quote,
+		Rating:  rating,
+		Comment: strings.TrimSpace(comment),
+	}
+	if analyzer, ok := service.(QueryAnalyzer); ok {
+		entry.Features = analyzer.AnalyzeQuery(query)
+	}
+	return entry
+}
+
+// File Feedback Implementation - one JSON object per line, append only.
+// Safe for concurrent use within one process. A torn last line, left by a
+// crash mid-write, is ignored and cut off by the next Record.
+type FileFeedbackStore struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewFileFeedbackStore(filename string) *FileFeedbackStore {
+	return &FileFeedbackStore{filename: filename}
+}
+
+func (f *FileFeedbackStore) Record(entry FeedbackEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode feedback: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := openAppendFile(f.filename, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write feedback: %w", err)
+	}
+	return nil
+}
+
+// List reads every recorded entry; a missing file means no feedback yet
+func (f *FileFeedbackStore) List() ([]FeedbackEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.list()
+}
+
+// Purge drops the feedback recorded before a time
+func (f *FileFeedbackStore) Purge(before time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.list()
+	if err != nil {
+		return 0, err
+	}
+	var kept []FeedbackEntry
+	for _, entry := range entries {
+		if !entry.Time.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(entries) {
+		return 0, nil
+	}
+	err = rewriteFile(f.filename, 0o600, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for _, entry := range kept {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(entries) - len(kept), err
+}
+
+func (f *FileFeedbackStore) list() ([]FeedbackEntry, error) {
+	content, err := os.ReadFile(f.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback file: %w", err)
+	}
+
+	var entries []FeedbackEntry
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line, torn := 0, tornLine(content)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry FeedbackEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			if line == torn {
+				break
+			}
+			return nil, &ParseError{File: f.filename, Line: line, Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feedback file: %w", err)
+	}
+	return entries, nil
+}
+
+// noEmotion groups feedback on queries in which no emotion was detected
+const noEmotion = "(none)"
+
+// QuoteFeedback totals the ratings one quote received
+type QuoteFeedback struct {
+	Quote         Quote    `json:"quote"`
+	Helpful       int      `json:"helpful"`
+	NotHelpful    int      `json:"not_helpful"`
+	Inappropriate int      `json:"inappropriate"`
+	Comments      []string `json:"comments,omitempty"`
+}
+
+// Flags counts the ratings that say the quote should not have been shown
+func (q QuoteFeedback) Flags() int {
+	return q.NotHelpful + q.Inappropriate
+}
+
+// EmotionFeedback lists the most-flagged quotes for queries showing one emotion
+type EmotionFeedback struct {
+	Emotion  string          `json:"emotion"`
+	Feedback int             `json:"feedback"`
+	Flagged  []QuoteFeedback `json:"flagged"`
+}
+
+// FeedbackReport summarizes collected feedback per detected emotion
+type FeedbackReport struct {
+	Entries  int               `json:"entries"`
+	Ratings  map[string]int    `json:"ratings"`
+	Emotions []EmotionFeedback `json:"emotions"`
+}
+
+// BuildFeedbackReport groups entries by the emotions detected in their
+// queries and keeps the top most-flagged quotes of each. A query showing
+// several emotions counts towards each of them.
+func BuildFeedbackReport(entries []FeedbackEntry, top int) *FeedbackReport {
+	report := &FeedbackReport{Entries: len(entries), Ratings: make(map[string]int), Emotions: []EmotionFeedback{}}
+	totals := make(map[string]int)
+	byEmotion := make(map[string]map[string]*QuoteFeedback)
+
+	for _, entry := range entries {
+		report.Ratings[entry.Rating]++
+		for _, emotion := range queryEmotions(entry.Features) {
+			totals[emotion]++
+			quotes := byEmotion[emotion]
+			if quotes == nil {
+				quotes = make(map[string]*QuoteFeedback)
+				byEmotion[emotion] = quotes
+			}
+			ref := quoteRef(entry.Quote)
+			tally := quotes[ref]
+			if tally == nil {
+				tally = &QuoteFeedback{Quote: entry.Quote}
+				quotes[ref] = tally
+			}
+			switch entry.Rating {
+			case RatingHelpful:
+				tally.Helpful++
+			case RatingNotHelpful:
+				tally.NotHelpful++
+			case RatingInappropriate:
+				tally.Inappropriate++
+			}
+			if entry.Comment != "" {
+				tally.Comments = append(tally.Comments, entry.Comment)
+			}
+		}
+	}
+
+	for emotion, quotes := range byEmotion {
+		group := EmotionFeedback{Emotion: emotion, Feedback: totals[emotion], Flagged: []QuoteFeedback{}}
+		for _, tally := range quotes {
+			if tally.Flags() > 0 {
+				group.Flagged = append(group.Flagged, *tally)
+			}
+		}
+		sort.Slice(group.Flagged, func(i, j int) bool {
+			a, b := group.Flagged[i], group.Flagged[j]
+			if a.Inappropriate != b.Inappropriate {
+				return a.Inappropriate > b.Inappropriate
+			}
+			if a.Flags() != b.Flags() {
+				return a.Flags() > b.Flags()
+			}
+			return quoteRef(a.Quote) < quoteRef(b.Quote)
+		})
+		if top > 0 && len(group.Flagged) > top {
+			group.Flagged = group.Flagged[:top]
+		}
+		report.Emotions = append(report.Emotions, group)
+	}
+	sort.Slice(report.Emotions, func(i, j int) bool {
+		if report.Emotions[i].Feedback != report.Emotions[j].Feedback {
+			return report.Emotions[i].Feedback > report.Emotions[j].Feedback
+		}
+		return report.Emotions[i].Emotion < report.Emotions[j].Emotion
+	})
+	return report
+}
+
+// queryEmotions names the emotions a query's words matched directly. The
+// related emotions the lexicon adds at a lower weight are left out.
+func queryEmotions(features map[string]float64) []string {
+	var emotions []string
+	for feature, weight := range features {
+		if emotion, ok := strings.CutPrefix(feature, "emotion:"); ok && weight >= 1 {
+			emotions = append(emotions, emotion)
+		}
+	}
+	if len(emotions) == 0 {
+		return []string{noEmotion}
+	}
+	sort.Strings(emotions)
+	return emotions
+}
+
+// Print lists the flagged quotes of each emotion
+func (r *FeedbackReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "%d feedback entries: %d helpful, %d not helpful, %d inappropriate\n",
+		r.Entries, r.Ratings[RatingHelpful], r.Ratings[RatingNotHelpful], r.Ratings[RatingInappropriate])
+
+	for _, group := range r.Emotions {
+		if len(group.Flagged) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d entries)\n", group.Emotion, group.Feedback)
+		for _, tally := range group.Flagged {
+			fmt.Fprintf(w, "   %d inappropriate, %d not helpful, %d helpful  %s \"%s\"\n",
+				tally.Inappropriate, tally.NotHelpful, tally.Helpful, quoteRef(tally.Quote), tally.Quote.Text)
+			for _, comment := range tally.Comments {
+				fmt.Fprintf(w, "      “%s”\n", comment)
+			}
+		}
+	}
+}
+
+// WriteCSV writes one row per emotion and flagged quote
+func (r *FeedbackReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"emotion", "quote_id", "text", "movie", "inappropriate", "not_helpful", "helpful", "comments"})
+	for _, group := range r.Emotions {
+		for _, tally := range group.Flagged {
+			out.Write([]string{
+				group.Emotion,
+				quoteRef(tally.Quote),
+				tally.Quote.Text,
+				tally.Quote.Movie,
+				strconv.Itoa(tally.Inappropriate),
+				strconv.Itoa(tally.NotHelpful),
+				strconv.Itoa(tally.Helpful),
+				strings.Join(tally.Comments, " | "),
+			})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFeedbackTornLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "feedback.jsonl")
+	store := NewFileFeedbackStore(filename)
+	quote := Quote{ID: "q-1", Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"}
+	if err := store.Record(FeedbackEntry{Time: time.Now(), Query: "lost", Quote: quote, Rating: RatingHelpful}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time":"2026-10-18T09:00:00Z","query":"to`)
+	file.Close()
+
+	// Reading skips the torn line
+	if entries, err := store.List(); err != nil || len(entries) != 1 {
+		t.Fatalf("got %d entries and error %v, want 1 entry", len(entries), err)
+	}
+
+	// Recording cuts it off before appending
+	if err := store.Record(FeedbackEntry{Time: time.Now(), Query: "alone", Quote: quote, Rating: RatingNotHelpful}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("got %d entries and error %v, want 2 entries", len(entries), err)
+	}
+	if entries[1].Query != "alone" {
+		t.Errorf("got query %q for the new entry, want alone", entries[1].Query)
+	}
+	if n := countLines(t, filename); n != 2 {
+		t.Errorf("got %d lines after the write, want 2", n)
+	}
+}
@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QuoteFormat is a supported quotes file format
+type QuoteFormat string
+
+const (
+	FormatAuto  QuoteFormat = ""
+	FormatJSON  QuoteFormat = "json"
+	FormatJSONL QuoteFormat = "jsonl"
+	FormatCSV   QuoteFormat = "csv"
+	FormatTSV   QuoteFormat = "tsv"
+	FormatStore QuoteFormat = "store" // the log written by QuoteStore
+)
+
+// ParseQuoteFormat validates a --format value
+func ParseQuoteFormat(value string) (QuoteFormat, error) {
+	switch format := QuoteFormat(strings.ToLower(value)); format {
+	case FormatAuto, FormatJSON, FormatJSONL, FormatCSV, FormatTSV, FormatStore:
+		return format, nil
+	case "auto":
+		return FormatAuto, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected json, jsonl, csv, tsv or store)", value)
+}
+
+// ColumnMapping names the CSV/TSV columns holding each quote field. A value
+// is either a header name (case-insensitive) or a 1-based column number.
+type ColumnMapping struct {
+	ID        string
+	Text      string
+	Movie     string
+	Character string
+	Year      string
+	Genres    string
+	Rating    string
+	Language  string
+	Context   string
+}
+
+// Header names recognized when no mapping is given
+var defaultColumnNames = map[string][]string{
+	"id":        {"id"},
+	"text":      {"text", "quote", "line"},
+	"movie":     {"movie", "film", "title", "source"},
+	"character": {"character", "speaker", "who", "by"},
+	"year":      {"year", "released"},
+	"genres":    {"genres", "genre"},
+	"rating":    {"rating", "mpa", "mpaa"},
+	"language":  {"language", "lang"},
+	"context":   {"context", "scene"},
+}
+
+// ParseColumnMapping reads a mapping like "text=Quote,movie=Film,character=3"
+func ParseColumnMapping(value string) (ColumnMapping, error) {
+	var mapping ColumnMapping
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return mapping, fmt.Errorf("invalid column mapping %q (expected field=column)", pair)
+		}
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "id":
+			mapping.ID = column
+		case "text":
+			mapping.Text = column
+		case "movie":
+			mapping.Movie = column
+		case "character":
+			mapping.Character = column
+		case "year":
+			mapping.Year = column
+		case "genres", "genre":
+			mapping.Genres = column
+		case "rating":
+			mapping.Rating = column
+		case "language":
+			mapping.Language = column
+		case "context":
+			mapping.Context = column
+		default:
+			return mapping, fmt.Errorf("unknown quote field %q in column mapping (expected id, text, movie, character, year, genres, rating, language or context)", field)
+		}
+	}
+	return mapping, nil
+}
+
+// LoadOptions control how FileQuoteRepository reads quote files
+type LoadOptions struct {
+	Format  QuoteFormat
+	Columns ColumnMapping
+
+	// NearDuplicateThreshold is the similarity at which two quotes are merged;
+	// zero uses DefaultNearDuplicateThreshold and 1 merges exact duplicates only
+	NearDuplicateThreshold float64
+	// KeepDuplicates loads every quote as written
+	KeepDuplicates bool
+	// Strict refuses a corpus with lint errors instead of skipping the quotes
+	Strict bool
+}
+
+// ParseError reports a malformed record with its line number
+type ParseError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// maxReportedParseErrors bounds how many malformed rows are listed
+const maxReportedParseErrors = 20
+
+// ParseErrors collects every malformed record in a file
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d malformed record(s):", len(e))
+	for i, err := range e {
+		if i == maxReportedParseErrors {
+			fmt.Fprintf(&b, "\n  ... and %d more", len(e)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %v", err)
+	}
+	return b.String()
+}
+
+// DetectFormat picks a format from the file extension, falling back to
+// sniffing the content for files with an unknown or missing extension
+func DetectFormat(filename string, content []byte) QuoteFormat {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return FormatJSON
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".csv":
+		return FormatCSV
+	case ".tsv", ".tab":
+		return FormatTSV
+	case storeExtension:
+		return FormatStore
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return FormatJSON
+	}
+	switch trimmed[0] {
+	case '[':
+		return FormatJSON
+	case '{':
+		// A JSONL file has a complete object on its first line
+		firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+		var quote Quote
+		if json.Unmarshal(firstLine, &quote) == nil && quote.Text != "" {
+			return FormatJSONL
+		}
+		return FormatJSON
+	}
+
+	firstLine, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if bytes.Count(firstLine, []byte("\t")) > bytes.Count(firstLine, []byte(",")) {
+		return FormatTSV
+	}
+	return FormatCSV
+}
+
+// parseQuotes decodes quote file content in the given format
+func parseQuotes(filename string, content []byte, options LoadOptions) (*QuoteData, error) {
+	format := options.Format
+	if format == FormatAuto {
+		format = DetectFormat(filename, content)
+	}
+
+	switch format {
+	case FormatJSON:
+		return parseJSONQuotes(filename, content)
+	case FormatJSONL:
+		return parseJSONLQuotes(filename, content)
+	case FormatCSV:
+		return parseDelimitedQuotes(filename, content, ',', options.Columns)
+	case FormatTSV:
+		return parseDelimitedQuotes(filename, content, '\t', options.Columns)
+	case FormatStore:
+		return parseStoreQuotes(filename, content)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// JSON accepts the {"quotes": [...]} document or a bare array of quotes
+func parseJSONQuotes(filename string, content []byte) (*QuoteData, error) {
+	var data QuoteData
+	var records []map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &data.Quotes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(content, &data); err != nil {
+			return nil, err
+		}
+		var document map[string]json.RawMessage
+		if err := json.Unmarshal(content, &document); err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(document) {
+			if key != "query" && key != "quotes" {
+				data.Issues = append(data.Issues, LintIssue{
+					Severity: SeverityWarning,
+					Source:   filename,
+					Message:  fmt.Sprintf("unknown top-level field %q", key),
+				})
+			}
+		}
+		if quotes, ok := document["quotes"]; ok {
+			if err := json.Unmarshal(quotes, &records); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, record := range records {
+		data.Issues = append(data.Issues, unknownFieldIssues(filename, i+1, 0, record)...)
+	}
+	return &data, nil
+}
+
+// unknownFieldIssues warns about quote fields that would be silently ignored,
+// which are usually typos such as "charater"
+func unknownFieldIssues(filename string, record, line int, fields map[string]json.RawMessage) []LintIssue {
+	var issues []LintIssue
+	for _, key := range sortedKeys(fields) {
+		if !quoteFields[key] {
+			issues = append(issues, LintIssue{
+				Severity: SeverityWarning,
+				Source:   filename,
+				Record:   record,
+				Line:     line,
+				Message:  fmt.Sprintf("unknown field %q", key),
+			})
+		}
+	}
+	return issues
+}
+
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JSONL holds one quote object per line; blank lines are ignored
+func parseJSONLQuotes(filename string, content []byte) (*QuoteData, error) {
+	var data QuoteData
+	var errs ParseErrors
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line, record := 0, 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var quote Quote
+		if err := json.Unmarshal(text, &quote); err != nil {
+			errs = append(errs, &ParseError{File: filename, Line: line, Err: err})
+			continue
+		}
+		record++
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(text, &fields) == nil {
+			data.Issues = append(data.Issues, unknownFieldIssues(filename, record, line, fields)...)
+		}
+		data.Quotes = append(data.Quotes, quote)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &data, nil
+}
+
+// CSV and TSV files need a header row naming their columns
+func parseDelimitedQuotes(filename string, content []byte, delimiter rune, mapping ColumnMapping) (*QuoteData, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if delimiter == '\t' {
+		// TSV exports rarely quote fields; treat quotes as ordinary text
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return &QuoteData{}, nil
+	}
+	if err != nil {
+		return nil, delimitedError(filename, err)
+	}
+
+	columns, err := resolveColumns(header, mapping)
+	if err != nil {
+		return nil, &ParseError{File: filename, Line: 1, Err: err}
+	}
+
+	var data QuoteData
+	for i, name := range header {
+		if !columns.uses(i) {
+			data.Issues = append(data.Issues, LintIssue{
+				Severity: SeverityWarning,
+				Source:   filename,
+				Line:     1,
+				Message:  fmt.Sprintf("unknown column %q", strings.TrimSpace(name)),
+			})
+		}
+	}
+
+	var errs ParseErrors
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errs = append(errs, delimitedError(filename, err))
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		quote, err := columns.quote(record)
+		if err != nil {
+			errs = append(errs, &ParseError{File: filename, Line: line, Err: err})
+			continue
+		}
+		data.Quotes = append(data.Quotes, quote)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &data, nil
+}
+
+func delimitedError(filename string, err error) *ParseError {
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		return &ParseError{File: filename, Line: csvErr.StartLine, Err: csvErr.Err}
+	}
+	return &ParseError{File: filename, Err: err}
+}
+
+// columnIndexes are the resolved 0-based positions of each quote field;
+// -1 marks an optional column that is absent
+type columnIndexes struct {
+	id, text, movie, character              int
+	year, genres, rating, language, context int
+}
+
+func (c columnIndexes) uses(column int) bool {
+	for _, i := range []int{c.id, c.text, c.movie, c.character, c.year, c.genres, c.rating, c.language, c.context} {
+		if i == column {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveColumns(header []string, mapping ColumnMapping) (columnIndexes, error) {
+	find := func(field, column string) (int, error) {
+		if column != "" {
+			if n, err := strconv.Atoi(column); err == nil {
+				if n < 1 || n > len(header) {
+					return -1, fmt.Errorf("column %d for %s is out of range (the header has %d columns)", n, field, len(header))
+				}
+				return n - 1, nil
+			}
+			for i, name := range header {
+				if strings.EqualFold(strings.TrimSpace(name), column) {
+					return i, nil
+				}
+			}
+			return -1, fmt.Errorf("no %q column for %s in header %q", column, field, strings.Join(header, ","))
+		}
+
+		for _, candidate := range defaultColumnNames[field] {
+			for i, name := range header {
+				if strings.EqualFold(strings.TrimSpace(name), candidate) {
+					return i, nil
+				}
+			}
+		}
+		return -1, nil
+	}
+
+	var columns columnIndexes
+	var err error
+	if columns.text, err = find("text", mapping.Text); err != nil {
+		return columns, err
+	}
+	if columns.text < 0 {
+		return columns, fmt.Errorf("no quote text column in header %q (map one with --columns text=NAME)", strings.Join(header, ","))
+	}
+	if columns.movie, err = find("movie", mapping.Movie); err != nil {
+		return columns, err
+	}
+	if columns.character, err = find("character", mapping.Character); err != nil {
+		return columns, err
+	}
+	if columns.id, err = find("id", mapping.ID); err != nil {
+		return columns, err
+	}
+	if columns.year, err = find("year", mapping.Year); err != nil {
+		return columns, err
+	}
+	if columns.genres, err = find("genres", mapping.Genres); err != nil {
+		return columns, err
+	}
+	if columns.rating, err = find("rating", mapping.Rating); err != nil {
+		return columns, err
+	}
+	if columns.language, err = find("language", mapping.Language); err != nil {
+		return columns, err
+	}
+	if columns.context, err = find("context", mapping.Context); err != nil {
+		return columns, err
+	}
+	return columns, nil
+}
+
+func (c columnIndexes) quote(record []string) (Quote, error) {
+	field := func(i int) (string, error) {
+		if i < 0 {
+			return "", nil
+		}
+		if i >= len(record) {
+			return "", fmt.Errorf("expected at least %d columns, found %d", i+1, len(record))
+		}
+		return strings.TrimSpace(record[i]), nil
+	}
+
+	var quote Quote
+	var err error
+	if quote.Text, err = field(c.text); err != nil {
+		return quote, err
+	}
+	if quote.Movie, err = field(c.movie); err != nil {
+		return quote, err
+	}
+	if quote.Character, err = field(c.character); err != nil {
+		return quote, err
+	}
+	if quote.ID, err = field(c.id); err != nil {
+		return quote, err
+	}
+
+	year, err := field(c.year)
+	if err != nil {
+		return quote, err
+	}
+	if year != "" {
+		if quote.Year, err = strconv.Atoi(year); err != nil {
+			return quote, fmt.Errorf("invalid year %q", year)
+		}
+	}
+	genres, err := field(c.genres)
+	if err != nil {
+		return quote, err
+	}
+	quote.Genres = ParseGenres(genres)
+	rating, err := field(c.rating)
+	if err != nil {
+		return quote, err
+	}
+	quote.Rating = ContentRating(rating)
+	if quote.Language, err = field(c.language); err != nil {
+		return quote, err
+	}
+	if quote.Context, err = field(c.context); err != nil {
+		return quote, err
+	}
+	return quote, nil
+}
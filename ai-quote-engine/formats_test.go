@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		columns ColumnMapping
+		texts   []string // quote texts parsed, in order
+		lint    []string // lint errors on the parsed quotes
+		errLine int      // line of the parse error, 0 for none
+	}{
+		{
+			name:    "csv",
+			file:    "quotes.csv",
+			content: "text,movie,character,year\n\"Hello, there\",Star Wars,Obi-Wan,1977\nI'll be back,The Terminator,T-800,1984\n",
+			texts:   []string{"Hello, there", "I'll be back"},
+		},
+		{
+			name:    "tsv",
+			file:    "quotes.tsv",
+			content: "quote\tfilm\tspeaker\nHere's \"Johnny\"\tThe Shining\tJack\n",
+			texts:   []string{`Here's "Johnny"`},
+		},
+		{
+			name:    "jsonl",
+			file:    "quotes.jsonl",
+			content: "{\"text\":\"May the Force be with you\",\"movie\":\"Star Wars\",\"character\":\"Han\"}\n\n{\"text\":\"Bond. James Bond.\",\"movie\":\"Dr. No\",\"character\":\"Bond\"}\n",
+			texts:   []string{"May the Force be with you", "Bond. James Bond."},
+		},
+		{
+			name:    "csv mapped columns",
+			file:    "quotes.csv",
+			content: "a,b,c\nStar Wars,Yoda,Do or do not\n",
+			columns: ColumnMapping{Text: "3", Movie: "a", Character: "2"},
+			texts:   []string{"Do or do not"},
+		},
+		{
+			name:    "csv empty text goes to lint",
+			file:    "quotes.csv",
+			content: "text,movie,character\n,Star Wars,Yoda\nDo or do not,Star Wars,Yoda\n",
+			texts:   []string{"", "Do or do not"},
+			lint:    []string{"empty text"},
+		},
+		{
+			name:    "jsonl empty text goes to lint",
+			file:    "quotes.jsonl",
+			content: "{\"text\":\"Do or do not\",\"movie\":\"Star Wars\",\"character\":\"Yoda\"}\n{\"text\":\" \",\"movie\":\"Star Wars\",\"character\":\"Yoda\"}\n",
+			texts:   []string{"Do or do not", " "},
+			lint:    []string{"empty text"},
+		},
+		{
+			name:    "csv bad year",
+			file:    "quotes.csv",
+			content: "text,movie,character,year\nDo or do not,Star Wars,Yoda,1977\nI'll be back,The Terminator,T-800,soon\n",
+			errLine: 3,
+		},
+		{
+			name:    "csv short row",
+			file:    "quotes.csv",
+			content: "movie,character,text\nStar Wars,Yoda\n",
+			errLine: 2,
+		},
+		{
+			name:    "jsonl malformed line",
+			file:    "quotes.jsonl",
+			content: "{\"text\":\"Do or do not\",\"movie\":\"Star Wars\",\"character\":\"Yoda\"}\n{\"text\": \n",
+			errLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parseQuotes(tt.file, []byte(tt.content), LoadOptions{Columns: tt.columns})
+			if tt.errLine != 0 {
+				var errs ParseErrors
+				if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Line != tt.errLine {
+					t.Fatalf("got error %v, want one parse error on line %d", err, tt.errLine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(data.Quotes) != len(tt.texts) {
+				t.Fatalf("got %d quotes, want %d", len(data.Quotes), len(tt.texts))
+			}
+			var lint []string
+			for i, quote := range data.Quotes {
+				if quote.Text != tt.texts[i] {
+					t.Errorf("quote %d: got text %q, want %q", i+1, quote.Text, tt.texts[i])
+				}
+				for _, issue := range lintQuote(tt.file, i+1, quote) {
+					if issue.Severity == SeverityError {
+						lint = append(lint, issue.Message)
+					}
+				}
+			}
+			if len(lint) != len(tt.lint) {
+				t.Fatalf("got lint errors %q, want %q", lint, tt.lint)
+			}
+			for i := range lint {
+				if lint[i] != tt.lint[i] {
+					t.Errorf("got lint errors %q, want %q", lint, tt.lint)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrDuplicateQuoteID reports an ID used by more than one quote
+var ErrDuplicateQuoteID = errors.New("duplicate quote id")
+
+// QuoteLookup is implemented by services that can resolve quote IDs
+type QuoteLookup interface {
+	QuoteByID(id string) (Quote, bool)
+}
+
+// derivedIDPrefix marks IDs computed from the quote text rather than supplied
+const derivedIDPrefix = "q-"
+
+// DeriveQuoteID hashes the normalized quote text, so the same line keeps the
+// same ID across reloads, file moves and punctuation fixes
+func DeriveQuoteID(quote Quote) string {
+	sum := sha256.Sum256([]byte(normalizeQuoteText(quote.Text)))
+	return derivedIDPrefix + hex.EncodeToString(sum[:6])
+}
+
+// assignQuoteIDs checks the IDs supplied in the files and derives the rest.
+// A supplied ID that is malformed or already taken is reported as an error
+// issue; a derived ID that is already taken (only possible when duplicates
+// are kept) gets a numeric suffix in corpus order.
+func assignQuoteIDs(quotes []Quote) []LintIssue {
+	var issues []LintIssue
+	invalid := func(i int, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity: SeverityError,
+			Source:   quotes[i].Source,
+			QuoteID:  quotes[i].ID,
+			Message:  fmt.Sprintf(format, args...),
+			quote:    i + 1,
+		})
+	}
+
+	owner := make(map[string]int)
+	for i := range quotes {
+		id := strings.TrimSpace(quotes[i].ID)
+		quotes[i].ID = id
+		if id == "" {
+			continue
+		}
+		if strings.IndexFunc(id, unicode.IsSpace) >= 0 {
+			invalid(i, "quote id %q must not contain spaces", id)
+			continue
+		}
+		if j, ok := owner[id]; ok {
+			invalid(i, "%v %q: %q is also used by %q%s", ErrDuplicateQuoteID, id,
+				quotes[i].Text, quotes[j].Text, sourceSuffix(quotes[j]))
+			continue
+		}
+		owner[id] = i
+	}
+
+	for i := range quotes {
+		if quotes[i].ID != "" {
+			continue
+		}
+		id := DeriveQuoteID(quotes[i])
+		for n := 2; ; n++ {
+			if _, taken := owner[id]; !taken {
+				break
+			}
+			id = fmt.Sprintf("%s-%d", DeriveQuoteID(quotes[i]), n)
+		}
+		quotes[i].ID = id
+		owner[id] = i
+	}
+	return issues
+}
+
+// quoteRef identifies a quote by ID, falling back to its normalized text for
+// quotes saved before IDs existed
+func quoteRef(quote Quote) string {
+	if quote.ID != "" {
+		return quote.ID
+	}
+	return quoteKey(quote)
+}
+
+// sameQuote compares by ID when both quotes have one, otherwise by text
+func sameQuote(a, b Quote) bool {
+	if a.ID != "" && b.ID != "" {
+		return a.ID == b.ID
+	}
+	return quoteKey(a) == quoteKey(b)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDeriveQuoteID(t *testing.T) {
+	id := DeriveQuoteID(Quote{Text: "I'll be back.", Movie: "The Terminator"})
+	if !regexp.MustCompile(`^q-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("got id %q, want q- and 12 hex digits", id)
+	}
+
+	// Only the wording counts, not punctuation, case or where the quote is from
+	for _, text := range []string{"I’ll be back", "  i'll   BE back!!", "Ill be back."} {
+		if other := DeriveQuoteID(Quote{Text: text, Movie: "Terminator 2", Source: "other.csv"}); other != id {
+			t.Errorf("%q: got id %s, want %s", text, other, id)
+		}
+	}
+	if other := DeriveQuoteID(Quote{Text: "I'll be back soon."}); other == id {
+		t.Errorf("different wording got the same id %s", id)
+	}
+}
+
+func TestAssignQuoteIDs(t *testing.T) {
+	back := DeriveQuoteID(Quote{Text: "I'll be back."})
+	tests := []struct {
+		name   string
+		quotes []Quote
+		want   []string
+		errors []string // parts of the issues reported, in order
+	}{
+		{
+			name:   "supplied ids are kept and trimmed",
+			quotes: []Quote{{ID: " t-800 ", Text: "I'll be back."}, {Text: "Hasta la vista, baby."}},
+			want:   []string{"t-800", DeriveQuoteID(Quote{Text: "Hasta la vista, baby."})},
+		},
+		{
+			name:   "kept duplicates get a numeric suffix",
+			quotes: []Quote{{Text: "I'll be back."}, {Text: "I’ll be back!"}, {Text: "i'll be back"}},
+			want:   []string{back, back + "-2", back + "-3"},
+		},
+		{
+			name:   "a derived id never takes a supplied one",
+			quotes: []Quote{{Text: "I'll be back."}, {ID: back, Text: "Something else."}},
+			want:   []string{back + "-2", back},
+		},
+		{
+			name:   "ids with spaces",
+			quotes: []Quote{{ID: "t 800", Text: "I'll be back."}},
+			want:   []string{"t 800"},
+			errors: []string{"must not contain spaces"},
+		},
+		{
+			name:   "ids used twice",
+			quotes: []Quote{{ID: "t-800", Text: "I'll be back.", Source: "a.json"}, {ID: "t-800", Text: "Hasta la vista, baby."}},
+			want:   []string{"t-800", "t-800"},
+			errors: []string{`duplicate quote id "t-800"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := assignQuoteIDs(tt.quotes)
+			var got []string
+			for _, quote := range tt.quotes {
+				got = append(got, quote.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got ids %q, want %q", got, tt.want)
+			}
+			if len(issues) != len(tt.errors) {
+				t.Fatalf("got issues %v, want %d", issues, len(tt.errors))
+			}
+			for i, issue := range issues {
+				if issue.Severity != SeverityError || !strings.Contains(issue.Message, tt.errors[i]) {
+					t.Errorf("got issue %v, want an error about %q", issue, tt.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQuoteByID(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+	results, err := service.SearchQuotes(testQueries[1], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := results[0].Quote.ID
+	if quote, ok := service.QuoteByID(id); !ok || quote.Text != results[0].Quote.Text {
+		t.Errorf("got %+v, %v for %s, want the quote found by the search", quote, ok, id)
+	}
+	if _, ok := service.QuoteByID("q-000000000000"); ok {
+		t.Errorf("found a quote for an unknown id")
+	}
+
+	// IDs survive a reload
+	if err := service.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := service.QuoteByID(id); !ok {
+		t.Errorf("lost %s in a reload", id)
+	}
+}
+
+func TestSameQuote(t *testing.T) {
+	tests := []struct {
+		a, b Quote
+		want bool
+	}{
+		{Quote{ID: "q-1", Text: "Hello"}, Quote{ID: "q-1", Text: "Goodbye"}, true},
+		{Quote{ID: "q-1", Text: "Hello"}, Quote{ID: "q-2", Text: "Hello"}, false},
+		{Quote{Text: "Hello!"}, Quote{ID: "q-2", Text: "hello"}, true}, // saved before IDs existed
+		{Quote{Text: "Hello"}, Quote{Text: "Goodbye"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameQuote(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameQuote(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
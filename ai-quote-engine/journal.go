@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrJournalEntryNotFound reports a journal entry ID that was never logged
+var ErrJournalEntryNotFound = errors.New("journal entry not found")
+
+// NewEmotionalContext reads how a query feels from its features. The primary
+// emotion is the strongest one; related emotions are the others its words
+// matched directly, strongest first, leaving out those the lexicon only
+// links to them. Intensity grows with the emotion and sentiment words found,
+// from 0 for none towards 1.
+func NewEmotionalContext(features map[string]float64) EmotionalContext {
+	type weighted struct {
+		emotion string
+		weight  float64
+	}
+	var emotions []weighted
+	total := 0.0
+	for feature, weight := range features {
+		if emotion, ok := strings.CutPrefix(feature, "emotion:"); ok && weight >= 1 {
+			emotions = append(emotions, weighted{emotion, weight})
+			total += weight
+		}
+	}
+	sort.Slice(emotions, func(i, j int) bool {
+		if emotions[i].weight != emotions[j].weight {
+			return emotions[i].weight > emotions[j].weight
+		}
+		return emotions[i].emotion < emotions[j].emotion
+	})
+
+	context := EmotionalContext{Valence: sentimentOf(features)}
+	for i, e := range emotions {
+		if i == 0 {
+			context.PrimaryEmotion = e.emotion
+		} else {
+			context.RelatedEmotions = append(context.RelatedEmotions, e.emotion)
+		}
+	}
+	total += features["sentiment:positive"] + features["sentiment:negative"]
+	context.IntensityScore = 1 - math.Exp(-total/2)
+	return context
+}
+
+// JournalQuote is a quote shown for a journal entry
+type JournalQuote struct {
+	ID    string  `json:"id"`
+	Text  string  `json:"text"`
+	Movie string  `json:"movie"`
+	Score float64 `json:"score"`
+}
+
+// JournalRating is feedback given on a quote shown for an entry
+type JournalRating struct {
+	Time    time.Time `json:"time"`
+	QuoteID string    `json:"quote_id"`
+	Rating  string    `json:"rating"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// JournalEntry is one query logged to the mood journal, with what the engine
+// read in it, the quotes it showed and how the user rated them
+type JournalEntry struct {
+	ID       string             `json:"id"`
+	Time     time.Time          `json:"time"`
+	Query    string             `json:"query"`
+	Features map[string]float64 `json:"features,omitempty"`
+	Context  EmotionalContext   `json:"context"`
+	Crisis   bool               `json:"crisis,omitempty"` // crisis resources were shown instead of quotes
+	Quotes   []JournalQuote     `json:"quotes"`
+	Ratings  []JournalRating    `json:"ratings,omitempty"`
+}
+
+// Journal keeps a user's mood journal
+type Journal interface {
+	// Log stores a new entry, assigning its ID and time if unset
+	Log(entry JournalEntry) (JournalEntry, error)
+	// AddQuotes records more quotes shown for an entry, e.g. a second page
+	AddQuotes(entryID string, quotes []JournalQuote) error
+	// AddRating records feedback on a quote shown for an entry
+	AddRating(entryID string, rating JournalRating) error
+	// Entries lists the entries logged at or after since, oldest first
+	Entries(since time.Time) ([]JournalEntry, error)
+}
+
+// newJournalEntry describes a query and the outcome of searching for it
+func newJournalEntry(service QuoteService, query string, results []SearchResult, err error) JournalEntry {
+	entry := JournalEntry{
+		Time:   time.Now(),
+		Query:  query,
+		Crisis: errors.Is(err, ErrCrisisDetected),
+		Quotes: journalQuotes(results),
+	}
+	if analyzer, ok := service.(QueryAnalyzer); ok {
+		entry.Features = analyzer.AnalyzeQuery(query)
+		entry.Context = NewEmotionalContext(entry.Features)
+	}
+	return entry
+}
+
+func journalQuotes(results []SearchResult) []JournalQuote {
+	quotes := make([]JournalQuote, 0, len(results))
+	for _, result := range results {
+		quotes = append(quotes, JournalQuote{
+			ID:    result.Quote.ID,
+			Text:  result.Quote.Text,
+			Movie: result.Quote.Movie,
+			Score: result.Score,
+		})
+	}
+	return quotes
+}
+
+// journalRecord is one line of a journal file: a new entry, or quotes or a
+// rating added to an earlier one
+type journalRecord struct {
+	Entry   *JournalEntry  `json:"entry,omitempty"`
+	EntryID string         `json:"entry_id,omitempty"`
+	Quotes  []JournalQuote `json:"quotes,omitempty"`
+	Rating  *JournalRating `json:"rating,omitempty"`
+}
+
+// File Journal Implementation - one JSON record per line, append only.
+// Safe for concurrent use within one process. A torn last line, left by a
+// crash mid-write, is ignored and cut off by the next append.
+type FileJournal struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewFileJournal(filename string) *FileJournal {
+	return &FileJournal{filename: filename}
+}
+
+func (j *FileJournal) Log(entry JournalEntry) (JournalEntry, error) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = "j-" + strconv.FormatInt(entry.Time.UnixNano(), 36)
+	}
+	if entry.Quotes == nil {
+		entry.Quotes = []JournalQuote{}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.append(journalRecord{Entry: &entry}); err != nil {
+		return JournalEntry{}, err
+	}
+	return entry, nil
+}
+
+func (j *FileJournal) AddQuotes(entryID string, quotes []JournalQuote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.checkEntry(entryID); err != nil {
+		return err
+	}
+	return j.append(journalRecord{EntryID: entryID, Quotes: quotes})
+}
+
+func (j *FileJournal) AddRating(entryID string, rating JournalRating) error {
+	if rating.Time.IsZero() {
+		rating.Time = time.Now()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.checkEntry(entryID); err != nil {
+		return err
+	}
+	return j.append(journalRecord{EntryID: entryID, Rating: &rating})
+}
+
+func (j *FileJournal) Entries(since time.Time) ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	first := sort.Search(len(entries), func(i int) bool { return !entries[i].Time.Before(since) })
+	return entries[first:], nil
+}
+
+// Purge drops the entries logged before a time, with the quotes and
+// ratings added to them
+func (j *FileJournal) Purge(before time.Time) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.read()
+	if err != nil {
+		return 0, err
+	}
+	first := sort.Search(len(entries), func(i int) bool { return !entries[i].Time.Before(before) })
+	if first == 0 {
+		return 0, nil
+	}
+	// The entries left are written whole, each on one line
+	err = rewriteFile(j.filename, 0o600, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for i := range entries[first:] {
+			if err := encoder.Encode(journalRecord{Entry: &entries[first+i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return first, err
+}
+
+func (j *FileJournal) checkEntry(entryID string) error {
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.ID == entryID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrJournalEntryNotFound, entryID)
+}
+
+func (j *FileJournal) append(record journalRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	file, err := openAppendFile(j.filename, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// read replays the journal into entries, oldest first; a missing file is an
+// empty journal
+func (j *FileJournal) read() ([]JournalEntry, error) {
+	content, err := os.ReadFile(j.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var entries []*JournalEntry
+	byID := make(map[string]*JournalEntry)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line, torn := 0, tornLine(content)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			if line == torn {
+				break
+			}
+			return nil, &ParseError{File: j.filename, Line: line, Err: err}
+		}
+		if record.Entry != nil {
+			entries = append(entries, record.Entry)
+			byID[record.Entry.ID] = record.Entry
+			continue
+		}
+		// Additions to an entry that is gone are ignored
+		if entry := byID[record.EntryID]; entry != nil {
+			entry.Quotes = append(entry.Quotes, record.Quotes...)
+			if record.Rating != nil {
+				entry.Ratings = append(entry.Ratings, *record.Rating)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	sorted := make([]JournalEntry, len(entries))
+	for i, entry := range entries {
+		sorted[i] = *entry
+	}
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Time.Before(sorted[b].Time) })
+	return sorted, nil
+}
+
+// ParseSince reads the start of a period: a date (2026-10-01), a number of
+// days or weeks back (30d, 4w), or a duration (12h). Empty means the start
+// of time.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return date, nil
+	}
+	if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+		switch value[len(value)-1] {
+		case 'd':
+			return now.AddDate(0, 0, -n), nil
+		case 'w':
+			return now.AddDate(0, 0, -7*n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid period %q (expected a date like 2026-10-01, 30d, 4w or 12h)", value)
+}
+
+// weekStart is midnight on the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.AddDate(0, 0, -offset).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// valenceMarks show an entry's valence at a glance in the timeline
+var valenceMarks = map[string]string{"positive": "☀", "negative": "☁", "neutral": "·"}
+
+// PrintJournalTimeline lists entries week by week, each with the mood read
+// in it, the quotes shown and the ratings given
+func PrintJournalTimeline(w io.Writer, entries []JournalEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "The journal is empty.")
+		return
+	}
+
+	var week time.Time
+	for _, entry := range entries {
+		local := entry.Time.Local()
+		if start := weekStart(local); !start.Equal(week) {
+			week = start
+			fmt.Fprintf(w, "\nWeek of %s\n", week.Format("Mon 2 Jan 2006"))
+		}
+
+		mood := entry.Context.PrimaryEmotion
+		if mood == "" {
+			mood = "no emotion detected"
+		}
+		if len(entry.Context.RelatedEmotions) > 0 {
+			mood += ", " + strings.Join(entry.Context.RelatedEmotions, ", ")
+		}
+		fmt.Fprintf(w, "  %s  %s %-8s %s (intensity %.2f)\n", local.Format("Mon 02 15:04"),
+			valenceMarks[entry.Context.Valence], entry.Context.Valence, mood, entry.Context.IntensityScore)
+		if entry.Query != "" {
+			fmt.Fprintf(w, "                \"%s\"\n", entry.Query)
+		}
+		if entry.Crisis {
+			fmt.Fprintln(w, "                ⚠️  crisis resources shown")
+		}
+
+		ratings := make(map[string]string)
+		for _, rating := range entry.Ratings {
+			ratings[rating.QuoteID] = rating.Rating
+		}
+		for _, quote := range entry.Quotes {
+			fmt.Fprintf(w, "                → \"%s\" (%s)", quote.Text, quote.Movie)
+			if rating, ok := ratings[quote.ID]; ok {
+				fmt.Fprintf(w, " [%s]", strings.ReplaceAll(rating, "_", " "))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
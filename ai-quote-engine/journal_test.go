@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalTornLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal := NewFileJournal(filename)
+	day := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+	entry, err := journal.Log(JournalEntry{Time: day, Query: "feeling lost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"entry_id":"` + entry.ID + `","quo`)
+	file.Close()
+
+	// Reading skips the torn line
+	entries, err := journal.Entries(time.Time{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got %d entries and error %v, want 1 entry", len(entries), err)
+	}
+
+	// Each kind of append cuts it off first
+	if err := journal.AddQuotes(entry.ID, []JournalQuote{{ID: "q-1", Text: "Just keep swimming."}}); err != nil {
+		t.Fatal(err)
+	}
+	file, _ = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"entry_id":"` + entry.ID + `","rat`)
+	file.Close()
+	if err := journal.AddRating(entry.ID, JournalRating{QuoteID: "q-1", Rating: RatingHelpful}); err != nil {
+		t.Fatal(err)
+	}
+	file, _ = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"entry":{"id":`)
+	file.Close()
+	if _, err := journal.Log(JournalEntry{Time: day.Add(time.Hour), Query: "better now"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err = journal.Entries(time.Time{})
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("got %d entries and error %v, want 2 entries", len(entries), err)
+	}
+	if first := entries[0]; len(first.Quotes) != 1 || len(first.Ratings) != 1 {
+		t.Errorf("got %d quotes and %d ratings on the first entry, want 1 of each", len(first.Quotes), len(first.Ratings))
+	}
+	if entries[1].Query != "better now" {
+		t.Errorf("got query %q for the second entry, want better now", entries[1].Query)
+	}
+	if n := countLines(t, filename); n != 4 {
+		t.Errorf("got %d lines, want 4", n)
+	}
+}
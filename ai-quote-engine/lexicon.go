@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// LoadLexicon reads a lexicon JSON file. Sections omitted from the file fall
+// back to the built-in lexicon, so a file can override only what it needs.
+func LoadLexicon(filename string) (*EmotionalLexicon, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lexicon file: %w", err)
+	}
+	defer file.Close()
+
+	var lexicon EmotionalLexicon
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&lexicon); err != nil {
+		return nil, fmt.Errorf("failed to parse lexicon file: %w", err)
+	}
+
+	defaults := NewEmotionalLexicon()
+	if lexicon.EmotionKeywords == nil {
+		lexicon.EmotionKeywords = defaults.EmotionKeywords
+	}
+	if lexicon.EmotionRelations == nil {
+		lexicon.EmotionRelations = defaults.EmotionRelations
+	}
+	if lexicon.ThemeKeywords == nil {
+		lexicon.ThemeKeywords = defaults.ThemeKeywords
+	}
+	if lexicon.PositiveWords == nil {
+		lexicon.PositiveWords = defaults.PositiveWords
+	}
+	if lexicon.NegativeWords == nil {
+		lexicon.NegativeWords = defaults.NegativeWords
+	}
+	if lexicon.ActionWords == nil {
+		lexicon.ActionWords = defaults.ActionWords
+	}
+	if lexicon.ReflectiveWords == nil {
+		lexicon.ReflectiveWords = defaults.ReflectiveWords
+	}
+
+	if len(lexicon.EmotionKeywords) == 0 {
+		return nil, fmt.Errorf("lexicon has no emotion keywords")
+	}
+
+	return &lexicon, nil
+}
+
+// WriteJSON writes the lexicon in the format accepted by LoadLexicon
+func (l *EmotionalLexicon) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Severity says whether a lint issue makes a quote unusable
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // the quote is skipped, or refused in strict mode
+	SeverityWarning Severity = "warning" // the quote loads but should be fixed
+)
+
+// MaxQuoteLength is the longest quote text, in characters, that lint accepts
+// without a warning
+const MaxQuoteLength = 280
+
+// firstFilmYear is the earliest plausible release year
+const firstFilmYear = 1888
+
+// Fields a quote object may have; anything else is reported as unknown
+var quoteFields = map[string]bool{
+	"id": true, "text": true, "movie": true, "character": true,
+	"year": true, "genres": true, "rating": true, "language": true, "context": true,
+	"source": true,
+}
+
+// LintIssue is a problem found in a quote file
+type LintIssue struct {
+	Severity Severity `json:"severity"`
+	Source   string   `json:"source"`
+	Line     int      `json:"line,omitempty"`
+	Record   int      `json:"record,omitempty"` // 1-based position of the quote in its file
+	QuoteID  string   `json:"quote_id,omitempty"`
+	Message  string   `json:"message"`
+
+	quote int // 1-based position in the corpus being loaded, 0 for file-level issues
+}
+
+func (i LintIssue) String() string {
+	location := i.Source
+	switch {
+	case i.Line > 0:
+		location += fmt.Sprintf(":%d", i.Line)
+	case i.Record > 0:
+		location += fmt.Sprintf(" #%d", i.Record)
+	}
+	if i.QuoteID != "" {
+		location += " (" + i.QuoteID + ")"
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Severity, i.Message)
+}
+
+// LintReport collects every issue found in a corpus
+type LintReport struct {
+	Issues []LintIssue `json:"issues"`
+	Quotes int         `json:"quotes"` // quotes that would be loaded
+}
+
+func (r *LintReport) Errors() int {
+	return countSeverity(r.Issues, SeverityError)
+}
+
+func (r *LintReport) Warnings() int {
+	return countSeverity(r.Issues, SeverityWarning)
+}
+
+func (r *LintReport) Print(w io.Writer) {
+	for _, issue := range r.Issues {
+		fmt.Fprintln(w, issue)
+	}
+	if len(r.Issues) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s); %d quote(s) would be loaded\n", r.Errors(), r.Warnings(), r.Quotes)
+}
+
+func countSeverity(issues []LintIssue, severity Severity) int {
+	n := 0
+	for _, issue := range issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidationError is returned in strict mode when a corpus has errors
+type ValidationError struct {
+	Path   string
+	Issues []LintIssue
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	listed := 0
+	for _, issue := range e.Issues {
+		if issue.Severity != SeverityError {
+			continue
+		}
+		if listed == maxReportedParseErrors {
+			fmt.Fprintf(&b, "\n  ... and %d more", countSeverity(e.Issues, SeverityError)-listed)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %v", issue)
+		listed++
+	}
+	return fmt.Sprintf("%s failed validation with %d error(s):%s", e.Path, countSeverity(e.Issues, SeverityError), b.String())
+}
+
+// lintQuote checks one quote as read from its file
+func lintQuote(source string, record int, quote Quote) []LintIssue {
+	var issues []LintIssue
+	add := func(severity Severity, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity: severity,
+			Source:   source,
+			Record:   record,
+			QuoteID:  strings.TrimSpace(quote.ID),
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if strings.TrimSpace(quote.Text) == "" {
+		add(SeverityError, "empty text")
+	} else if n := utf8.RuneCountInString(quote.Text); n > MaxQuoteLength {
+		add(SeverityWarning, "text is %d characters long (limit %d)", n, MaxQuoteLength)
+	}
+	if strings.TrimSpace(quote.Movie) == "" {
+		add(SeverityWarning, "missing movie")
+	}
+	if strings.TrimSpace(quote.Character) == "" {
+		add(SeverityWarning, "missing character")
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"text", quote.Text},
+		{"movie", quote.Movie},
+		{"character", quote.Character},
+		{"context", quote.Context},
+	} {
+		if problem := encodingProblem(field.value); problem != "" {
+			add(SeverityWarning, "%s %s", field.name, problem)
+		}
+	}
+
+	if quote.Rating != "" {
+		if _, err := ParseContentRating(string(quote.Rating)); err != nil {
+			add(SeverityError, "%v", err)
+		}
+	}
+	if quote.Year != 0 && quote.Year < firstFilmYear {
+		add(SeverityError, "invalid year %d", quote.Year)
+	}
+	return issues
+}
+
+// encodingProblem spots text that was decoded with the wrong character set
+func encodingProblem(value string) string {
+	if !utf8.ValidString(value) {
+		return "is not valid UTF-8"
+	}
+	if strings.ContainsRune(value, utf8.RuneError) {
+		return "contains a replacement character (�); the file may not be UTF-8"
+	}
+
+	runes := []rune(value)
+	for i, r := range runes {
+		// UTF-8 read as Latin-1 or Windows-1252: "Ã©" for "é", "â€™" for "’"
+		if (r == 'Ã' || r == 'Â') && i+1 < len(runes) && runes[i+1] >= 0x80 && runes[i+1] <= 0xBF {
+			return fmt.Sprintf("looks double-encoded (%q)", string(runes[i:i+2]))
+		}
+		if r == 'â' && i+1 < len(runes) && runes[i+1] == '€' {
+			return "looks double-encoded (\"â€\")"
+		}
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return fmt.Sprintf("contains control character %U", r)
+		}
+	}
+	return ""
+}
+
+// withoutErrors drops the corpus quotes an error-level issue points at
+func withoutErrors(quotes []Quote, issues []LintIssue) []Quote {
+	invalid := make(map[int]bool)
+	for _, issue := range issues {
+		if issue.Severity == SeverityError && issue.quote > 0 {
+			invalid[issue.quote-1] = true
+		}
+	}
+	if len(invalid) == 0 {
+		return quotes
+	}
+
+	kept := make([]Quote, 0, len(quotes)-len(invalid))
+	for i, quote := range quotes {
+		if !invalid[i] {
+			kept = append(kept, quote)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintQuote(t *testing.T) {
+	good := Quote{Text: "Here's looking at you, kid.", Movie: "Casablanca", Character: "Rick", Year: 1942, Rating: "PG"}
+	with := func(change func(*Quote)) Quote {
+		quote := good
+		change(&quote)
+		return quote
+	}
+
+	tests := []struct {
+		name     string
+		quote    Quote
+		severity Severity // of the one issue expected, "" for none
+		message  string
+	}{
+		{"clean", good, "", ""},
+		{"empty text", with(func(q *Quote) { q.Text = "  " }), SeverityError, "empty text"},
+		{"long text", with(func(q *Quote) { q.Text = strings.Repeat("a", MaxQuoteLength+1) }), SeverityWarning, "characters long"},
+		{"missing movie", with(func(q *Quote) { q.Movie = "" }), SeverityWarning, "missing movie"},
+		{"missing character", with(func(q *Quote) { q.Character = "" }), SeverityWarning, "missing character"},
+		{"double-encoded", with(func(q *Quote) { q.Text = "Hereâ€™s looking at you" }), SeverityWarning, "double-encoded"},
+		{"latin-1 read as utf-8", with(func(q *Quote) { q.Movie = "AmÃ©lie" }), SeverityWarning, "movie looks double-encoded"},
+		{"replacement character", with(func(q *Quote) { q.Character = "Ri�k" }), SeverityWarning, "replacement character"},
+		{"invalid utf-8", with(func(q *Quote) { q.Context = "bar\xff" }), SeverityWarning, "not valid UTF-8"},
+		{"control character", with(func(q *Quote) { q.Text = "Here's\x07 looking" }), SeverityWarning, "control character"},
+		{"unknown rating", with(func(q *Quote) { q.Rating = "X" }), SeverityError, "unknown content rating"},
+		{"year before film", with(func(q *Quote) { q.Year = 1500 }), SeverityError, "invalid year 1500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := lintQuote("quotes.json", 3, tt.quote)
+			if tt.severity == "" {
+				if len(issues) > 0 {
+					t.Errorf("got issues %v, want none", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("got issues %v, want one", issues)
+			}
+			issue := issues[0]
+			if issue.Severity != tt.severity || !strings.Contains(issue.Message, tt.message) {
+				t.Errorf("got %v, want a %s about %q", issue, tt.severity, tt.message)
+			}
+			if issue.Source != "quotes.json" || issue.Record != 3 {
+				t.Errorf("got the issue at %s #%d, want quotes.json #3", issue.Source, issue.Record)
+			}
+		})
+	}
+}
+
+func TestLintAndStrictLoading(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "quotes.json")
+	writeFiles(t, filepath.Dir(filename), map[string]string{"quotes.json": `{"quotes": [
+		{"text": "Here's looking at you, kid.", "movie": "Casablanca", "character": "Rick"},
+		{"text": "", "movie": "Casablanca", "character": "Ilsa"},
+		{"text": "Play it, Sam.", "movie": "Casablanca", "charater": "Ilsa", "year": 12}
+	]}`})
+
+	report, err := NewFileQuoteRepository().Lint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Errors: the empty text and the year. Warnings: the typo, and the
+	// character it left missing.
+	if report.Errors() != 2 || report.Warnings() != 2 || report.Quotes != 1 {
+		t.Errorf("got %d errors, %d warnings and %d quotes, want 2, 2 and 1:\n%v", report.Errors(), report.Warnings(), report.Quotes, report.Issues)
+	}
+
+	// Quotes with errors are skipped...
+	data, err := NewFileQuoteRepository().LoadQuotes(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Quotes) != 1 || data.Quotes[0].Character != "Rick" {
+		t.Errorf("loaded %+v, want only Rick's quote", data.Quotes)
+	}
+
+	// ...or refuse the whole file in strict mode
+	_, err = NewFileQuoteRepositoryWithOptions(LoadOptions{Strict: true}).LoadQuotes(filename)
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("got error %v, want a ValidationError", err)
+	}
+	if message := validation.Error(); !strings.Contains(message, "2 error(s)") || !strings.Contains(message, "empty text") {
+		t.Errorf("got %q, want the errors listed", message)
+	}
+}
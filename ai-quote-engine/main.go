@@ -2,36 +2,86 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Domain Models
 type Quote struct {
+	ID        string `json:"id,omitempty"` // stable identifier, derived from the text if not supplied
 	Text      string `json:"text"`
 	Movie     string `json:"movie"`
 	Character string `json:"character"`
+
+	// Optional metadata used for filtering
+	Year     int           `json:"year,omitempty"`
+	Genres   []string      `json:"genres,omitempty"`
+	Rating   ContentRating `json:"rating,omitempty"`
+	Language string        `json:"language,omitempty"`
+	Context  string        `json:"context,omitempty"` // the scene the line is from
+
+	Source string `json:"source,omitempty"` // file the quote was loaded from
 }
 
 type QuoteData struct {
-	Query  string  `json:"query"`
-	Quotes []Quote `json:"quotes"`
+	Query      string        `json:"query"`
+	Quotes     []Quote       `json:"quotes"`
+	Sources    []SourceStats `json:"-"`
+	Duplicates *DedupReport  `json:"-"`
+	Issues     []LintIssue   `json:"-"`
 }
 
 type SearchResult struct {
-	Quote Quote
-	Score float64
+	Quote       Quote
+	Score       float64
+	Explanation *MatchExplanation // set when the search asked for explanations
+}
+
+// IndexedQuote pairs a quote with the features extracted from its text
+type IndexedQuote struct {
+	Quote    Quote              `json:"quote"`
+	Features map[string]float64 `json:"features"`
+}
+
+// Sentinel errors returned by the service
+var (
+	ErrCrisisDetected   = errors.New("CRISIS_DETECTED")
+	ErrNoMatches        = errors.New("no matching quotes found for your situation")
+	ErrNoConfidentMatch = errors.New("no confident match for your situation")
+)
+
+// DefaultMinScore is the confidence below which results are not shown
+const DefaultMinScore = 0.2
+
+// LowConfidenceError reports that even the best match scored below the threshold
+type LowConfidenceError struct {
+	BestScore float64
+	MinScore  float64
+}
+
+func (e *LowConfidenceError) Error() string {
+	return fmt.Sprintf("%v (best score %.2f is below %.2f)", ErrNoConfidentMatch, e.BestScore, e.MinScore)
+}
+
+func (e *LowConfidenceError) Is(target error) bool {
+	return target == ErrNoConfidentMatch
 }
 
-// Emotional and thematic lexicons
+// EmotionalContext summarizes how a query feels; see NewEmotionalContext
 type EmotionalContext struct {
-	PrimaryEmotion  string
-	RelatedEmotions []string
-	IntensityScore  float64
-	Valence         string // positive, negative, neutral
+	PrimaryEmotion  string   `json:"primary_emotion,omitempty"`
+	RelatedEmotions []string `json:"related_emotions,omitempty"`
+	IntensityScore  float64  `json:"intensity"`
+	Valence         string   `json:"valence"` // positive, negative, neutral
 }
 
 // Repository Interface
@@ -45,37 +95,127 @@ type QuoteService interface {
 }
 
 // File Repository Implementation
-type FileQuoteRepository struct{}
+type FileQuoteRepository struct {
+	options LoadOptions
+}
 
 func NewFileQuoteRepository() *FileQuoteRepository {
 	return &FileQuoteRepository{}
 }
 
-func (r *FileQuoteRepository) LoadQuotes(filename string) (*QuoteData, error) {
-	file, err := os.Open(filename)
+// NewFileQuoteRepositoryWithOptions reads files with an explicit format or
+// CSV/TSV column mapping instead of detecting them
+func NewFileQuoteRepositoryWithOptions(options LoadOptions) *FileQuoteRepository {
+	return &FileQuoteRepository{options: options}
+}
+
+// LoadQuotes reads a JSON, JSONL, CSV or TSV quotes file, or merges every
+// quote file in a directory or matching a glob pattern. The format comes from
+// the repository options or is detected per file. Exact and near-duplicate
+// quotes are merged unless the options keep them. Quotes with lint errors are
+// skipped and reported in Issues, or refused outright in strict mode.
+func (r *FileQuoteRepository) LoadQuotes(path string) (*QuoteData, error) {
+	data, err := r.load(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open quotes file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	var data QuoteData
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to parse quotes file: %w", err)
+	if r.options.Strict && countSeverity(data.Issues, SeverityError) > 0 {
+		return nil, &ValidationError{Path: path, Issues: data.Issues}
 	}
 
 	if len(data.Quotes) == 0 {
-		return nil, fmt.Errorf("no quotes found in file")
+		return nil, fmt.Errorf("no quotes found in %s", path)
 	}
 
-	return &data, nil
+	return data, nil
 }
 
-// Dynamic Quote Search Service Implementation
+// Lint reports every problem LoadQuotes would find, without refusing to load
+func (r *FileQuoteRepository) Lint(path string) (*LintReport, error) {
+	data, err := r.load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &LintReport{Issues: data.Issues, Quotes: len(data.Quotes)}, nil
+}
+
+func (r *FileQuoteRepository) load(path string) (*QuoteData, error) {
+	data, err := r.loadCorpus(path)
+	if err != nil {
+		return nil, err
+	}
+	data.Quotes = withoutErrors(data.Quotes, data.Issues)
+
+	if !r.options.KeepDuplicates {
+		threshold := r.options.NearDuplicateThreshold
+		if threshold == 0 {
+			threshold = DefaultNearDuplicateThreshold
+		}
+		data.Quotes, data.Duplicates = dedupQuotes(data.Quotes, threshold)
+		for _, group := range data.Duplicates.Groups {
+			for _, conflict := range group.Conflicts {
+				data.Issues = append(data.Issues, LintIssue{
+					Severity: SeverityWarning,
+					Source:   group.Kept.Source,
+					Message:  fmt.Sprintf("duplicates of %q disagree on %s", group.Kept.Text, conflict),
+				})
+			}
+		}
+	}
+
+	idIssues := assignQuoteIDs(data.Quotes)
+	data.Quotes = withoutErrors(data.Quotes, idIssues)
+	data.Issues = append(data.Issues, idIssues...)
+	normalizeMetadata(data.Quotes)
+
+	return data, nil
+}
+
+// SaveQuotes writes the quotes file atomically so a failed write never
+// leaves a truncated corpus behind. Provenance pointing at the file itself is
+// implied by the file and not written out.
+func (r *FileQuoteRepository) SaveQuotes(filename string, data *QuoteData) error {
+	out := *data
+	out.Quotes = make([]Quote, len(data.Quotes))
+	for i, quote := range data.Quotes {
+		if quote.Source == filename {
+			quote.Source = ""
+		}
+		out.Quotes[i] = quote
+	}
+	return saveJSONFile(filename, 0o644, &out)
+}
+
+// Dynamic Quote Search Service Implementation.
+//
+// SemanticQuoteService is safe for concurrent use: any number of goroutines
+// may search, explain and look up quotes while another reloads or swaps the
+// lexicon. Searches never wait for a reload. UseWorkers must be called before
+// the service is shared.
 type SemanticQuoteService struct {
-	data       *QuoteData
 	repository QuoteRepository
-	lexicon    *EmotionalLexicon
+	snapshot   atomic.Pointer[corpusSnapshot]
+	workers    int             // goroutines used to score large corpora; 0 means one per CPU
+	weights    *RankingWeights // nil uses DefaultRankingWeights
+	daily      dailyCache      // quotes of the day already picked
+
+	// Guarded by mu; only loads and reloads take it, never searches
+	mu          sync.Mutex
+	filename    string
+	lexicon     *EmotionalLexicon
+	lexiconFile string
+}
+
+// corpusSnapshot is everything a search reads. It is never modified once
+// published: a reload builds a new snapshot and swaps it in, so in-flight
+// searches finish against the data they started with.
+type corpusSnapshot struct {
+	data     *QuoteData
+	index    []IndexedQuote
+	byID     map[string]int
+	lexicon  *EmotionalLexicon
+	loadedAt time.Time
 }
 
 func NewSemanticQuoteService(repo QuoteRepository) *SemanticQuoteService {
@@ -85,34 +225,248 @@ func NewSemanticQuoteService(repo QuoteRepository) *SemanticQuoteService {
 	}
 }
 
+// UseWorkers caps the goroutines used to index and score large corpora;
+// 0 uses one per CPU and 1 disables parallel scoring
+func (s *SemanticQuoteService) UseWorkers(n int) {
+	s.workers = n
+}
+
+func (s *SemanticQuoteService) parallelism() int {
+	if s.workers > 0 {
+		return s.workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// current returns the published snapshot, or nil before Initialize
+func (s *SemanticQuoteService) current() *corpusSnapshot {
+	return s.snapshot.Load()
+}
+
 func (s *SemanticQuoteService) Initialize(filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	data, err := s.repository.LoadQuotes(filename)
 	if err != nil {
 		return err
 	}
-	s.data = data
+	s.filename = filename
+	s.snapshot.Store(s.buildSnapshot(data, s.lexicon))
+	return nil
+}
+
+// Reload re-reads the quotes file the service was initialized with, and the
+// lexicon file if one is in use. Nothing changes unless both load cleanly.
+func (s *SemanticQuoteService) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filename == "" {
+		return fmt.Errorf("service not initialized")
+	}
+
+	lexicon := s.lexicon
+	if s.lexiconFile != "" {
+		var err error
+		if lexicon, err = LoadLexicon(s.lexiconFile); err != nil {
+			return err
+		}
+	}
+	data, err := s.repository.LoadQuotes(s.filename)
+	if err != nil {
+		return err
+	}
+
+	s.lexicon = lexicon
+	s.snapshot.Store(s.buildSnapshot(data, lexicon))
+	return nil
+}
+
+// UseLexicon replaces the emotional lexicon and re-analyzes any loaded quotes
+func (s *SemanticQuoteService) UseLexicon(lexicon *EmotionalLexicon) {
+	s.useLexicon(lexicon, "")
+}
+
+// UseLexiconFile loads a lexicon file and re-reads it on every Reload
+func (s *SemanticQuoteService) UseLexiconFile(filename string) error {
+	lexicon, err := LoadLexicon(filename)
+	if err != nil {
+		return err
+	}
+	s.useLexicon(lexicon, filename)
+	return nil
+}
+
+func (s *SemanticQuoteService) useLexicon(lexicon *EmotionalLexicon, filename string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lexicon = lexicon
+	s.lexiconFile = filename
+	if snap := s.current(); snap != nil {
+		s.snapshot.Store(s.buildSnapshot(snap.data, lexicon))
+	}
+}
+
+// watchedFiles lists the files a reload reads, for change detection
+func (s *SemanticQuoteService) watchedFiles() (quotes, lexicon string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filename, s.lexiconFile
+}
+
+// activeLexicon is the lexicon searches use right now
+func (s *SemanticQuoteService) activeLexicon() *EmotionalLexicon {
+	if snap := s.current(); snap != nil {
+		return snap.lexicon
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lexicon
+}
+
+// LoadedAt reports when the current quotes were loaded
+func (s *SemanticQuoteService) LoadedAt() time.Time {
+	if snap := s.current(); snap != nil {
+		return snap.loadedAt
+	}
+	return time.Time{}
+}
+
+// Sources reports the files the corpus was loaded from
+func (s *SemanticQuoteService) Sources() []SourceStats {
+	if snap := s.current(); snap != nil {
+		return snap.data.Sources
+	}
+	return nil
+}
+
+// Issues reports the lint problems found while loading the corpus
+func (s *SemanticQuoteService) Issues() []LintIssue {
+	if snap := s.current(); snap != nil {
+		return snap.data.Issues
+	}
+	return nil
+}
+
+// Duplicates reports the quotes merged while loading the corpus
+func (s *SemanticQuoteService) Duplicates() *DedupReport {
+	if snap := s.current(); snap != nil && snap.data.Duplicates != nil {
+		return snap.data.Duplicates
+	}
+	return &DedupReport{}
+}
+
+// Index returns the analyzed quotes in corpus order
+func (s *SemanticQuoteService) Index() []IndexedQuote {
+	if snap := s.current(); snap != nil {
+		return snap.index
+	}
 	return nil
 }
 
+// Pre-compute quote features so searches don't re-analyze the corpus
+func (s *SemanticQuoteService) buildSnapshot(data *QuoteData, lexicon *EmotionalLexicon) *corpusSnapshot {
+	snap := &corpusSnapshot{
+		data:     data,
+		index:    make([]IndexedQuote, len(data.Quotes)),
+		byID:     make(map[string]int, len(data.Quotes)),
+		lexicon:  lexicon,
+		loadedAt: time.Now(),
+	}
+	forEachRange(len(data.Quotes), s.workers, func(_, start, end int) {
+		for i := start; i < end; i++ {
+			snap.index[i] = IndexedQuote{
+				Quote:    data.Quotes[i],
+				Features: s.analyzeText(lexicon, data.Quotes[i].Text),
+			}
+		}
+	})
+	for i, quote := range data.Quotes {
+		snap.byID[quote.ID] = i
+	}
+	return snap
+}
+
+// QuoteByID looks up a quote by its stable ID
+func (s *SemanticQuoteService) QuoteByID(id string) (Quote, bool) {
+	snap := s.current()
+	if snap == nil {
+		return Quote{}, false
+	}
+	i, ok := snap.byID[id]
+	if !ok {
+		return Quote{}, false
+	}
+	return snap.index[i].Quote, true
+}
+
+// SearchQuotes searches without a deadline; use Search to bound or cancel it
 func (s *SemanticQuoteService) SearchQuotes(query string, topN int) ([]SearchResult, error) {
-	if s.data == nil {
-		return nil, fmt.Errorf("service not initialized")
+	return s.Search(context.Background(), SearchRequest{Query: query, TopN: topN})
+}
+
+// FilteredSearcher is implemented by services that can restrict the corpus
+// by quote metadata
+type FilteredSearcher interface {
+	SearchFiltered(query string, topN int, filter QuoteFilter) ([]SearchResult, error)
+}
+
+// SearchFiltered searches only the quotes that pass the filter
+func (s *SemanticQuoteService) SearchFiltered(query string, topN int, filter QuoteFilter) ([]SearchResult, error) {
+	return s.Search(context.Background(), SearchRequest{Query: query, TopN: topN, Filter: filter})
+}
+
+// rank scores every indexed quote that passes the filter against the query
+// features and returns the best topN. Quotes for which skip returns true are
+// left out, and the stages adjust the rest for the user. Scoring stops early
+// once ctx is done.
+func (s *SemanticQuoteService) rank(ctx context.Context, snap *corpusSnapshot, queryContext map[string]float64, topN int, filter QuoteFilter, skip func(Quote) bool, stages rankStages) ([]SearchResult, error) {
+	// Large corpora are scored in parallel, one slice of the index per core
+	parts := make([][]SearchResult, s.parallelism())
+	forEachRange(len(snap.index), s.workers, func(part, start, end int) {
+		parts[part] = s.score(ctx, snap.index[start:end], queryContext, filter, skip, stages)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, part := range parts {
+		results = append(results, part...)
 	}
 
-	if strings.TrimSpace(query) == "" {
-		return nil, fmt.Errorf("query cannot be empty")
+	if len(results) == 0 {
+		return nil, ErrNoMatches
 	}
 
-	// Check for crisis indicators
-	if s.detectCrisis(query) {
-		return nil, fmt.Errorf("CRISIS_DETECTED")
+	// Sort by score (descending); ties keep corpus order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	// Return top N results
+	if topN > len(results) {
+		topN = len(results)
 	}
 
-	queryContext := s.analyzeText(query)
+	return results[:topN], nil
+}
 
+// score rates a slice of the index. It only reads shared state, so slices can
+// be scored concurrently. It gives up, returning nil, once ctx is done.
+func (s *SemanticQuoteService) score(ctx context.Context, entries []IndexedQuote, queryContext map[string]float64, filter QuoteFilter, skip func(Quote) bool, stages rankStages) []SearchResult {
 	var results []SearchResult
-	for _, quote := range s.data.Quotes {
-		quoteContext := s.analyzeText(quote.Text)
+	for i, entry := range entries {
+		if i%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil
+		}
+		quote := entry.Quote
+		quoteContext := entry.Features
+
+		if !filter.Matches(quote) || (skip != nil && skip(quote)) {
+			continue
+		}
 
 		// Check tone compatibility before calculating similarity
 		if !s.areTonesCompatible(queryContext, quoteContext, quote.Text) {
@@ -120,6 +474,7 @@ func (s *SemanticQuoteService) SearchQuotes(query string, topN int) ([]SearchRes
 		}
 
 		score := s.calculateSimilarity(queryContext, quoteContext)
+		score = stages.apply(quote, quoteContext, score)
 
 		if score > 0 {
 			results = append(results, SearchResult{
@@ -128,26 +483,26 @@ func (s *SemanticQuoteService) SearchQuotes(query string, topN int) ([]SearchRes
 			})
 		}
 	}
+	return results
+}
 
-	if len(results) == 0 {
-		return nil, fmt.Errorf("no matching quotes found for your situation")
+// FilterConfident drops results scoring below minScore. Results must be sorted
+// by descending score; if none qualify a *LowConfidenceError is returned.
+func FilterConfident(results []SearchResult, minScore float64) ([]SearchResult, error) {
+	if len(results) == 0 || minScore <= 0 {
+		return results, nil
 	}
 
-	// Sort by score (descending)
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].Score > results[i].Score {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	if results[0].Score < minScore {
+		return nil, &LowConfidenceError{BestScore: results[0].Score, MinScore: minScore}
 	}
 
-	// Return top N results
-	if topN > len(results) {
-		topN = len(results)
+	for i, result := range results {
+		if result.Score < minScore {
+			return results[:i], nil
+		}
 	}
-
-	return results[:topN], nil
+	return results, nil
 }
 
 // Check if query tone is compatible with quote tone
@@ -250,6 +605,31 @@ func (s *SemanticQuoteService) areTonesCompatible(queryFeatures, quoteFeatures m
 	return true
 }
 
+// CrisisResource describes a support line shown instead of quotes in a crisis
+type CrisisResource struct {
+	Name    string   `json:"name"`
+	Details []string `json:"details"`
+}
+
+var CrisisResources = []CrisisResource{
+	{
+		Name:    "National Suicide Prevention Lifeline (US)",
+		Details: []string{"Call or Text: 988", "Available 24/7, free and confidential"},
+	},
+	{
+		Name:    "Crisis Text Line (US)",
+		Details: []string{"Text: HOME to 741741"},
+	},
+	{
+		Name:    "International Association for Suicide Prevention",
+		Details: []string{"https://www.iasp.info/resources/Crisis_Centres/"},
+	},
+	{
+		Name:    "Emergency Services",
+		Details: []string{"Call: 911 (US) or your local emergency number"},
+	},
+}
+
 // Detect crisis situations that require professional help
 func (s *SemanticQuoteService) detectCrisis(query string) bool {
 	query = strings.ToLower(query)
@@ -281,21 +661,21 @@ func (s *SemanticQuoteService) detectCrisis(query string) bool {
 }
 
 // Analyze text to extract emotional and thematic content
-func (s *SemanticQuoteService) analyzeText(text string) map[string]float64 {
+func (s *SemanticQuoteService) analyzeText(lexicon *EmotionalLexicon, text string) map[string]float64 {
 	text = strings.ToLower(text)
 	words := s.tokenize(text)
 
 	features := make(map[string]float64)
 
 	// Emotion detection
-	for emotion, keywords := range s.lexicon.EmotionKeywords {
+	for emotion, keywords := range lexicon.EmotionKeywords {
 		for _, word := range words {
 			for _, keyword := range keywords {
 				if strings.Contains(word, keyword) || strings.Contains(keyword, word) {
 					features["emotion:"+emotion] += 1.0
 
 					// Add related emotions with lower weight
-					if related, exists := s.lexicon.EmotionRelations[emotion]; exists {
+					if related, exists := lexicon.EmotionRelations[emotion]; exists {
 						for _, relEmotion := range related {
 							features["emotion:"+relEmotion] += 0.3
 						}
@@ -306,7 +686,7 @@ func (s *SemanticQuoteService) analyzeText(text string) map[string]float64 {
 	}
 
 	// Theme detection
-	for theme, keywords := range s.lexicon.ThemeKeywords {
+	for theme, keywords := range lexicon.ThemeKeywords {
 		for _, word := range words {
 			for _, keyword := range keywords {
 				if strings.Contains(word, keyword) || strings.Contains(keyword, word) {
@@ -321,12 +701,12 @@ func (s *SemanticQuoteService) analyzeText(text string) map[string]float64 {
 	negativeCount := 0.0
 
 	for _, word := range words {
-		for _, posWord := range s.lexicon.PositiveWords {
+		for _, posWord := range lexicon.PositiveWords {
 			if word == posWord {
 				positiveCount += 1.0
 			}
 		}
-		for _, negWord := range s.lexicon.NegativeWords {
+		for _, negWord := range lexicon.NegativeWords {
 			if word == negWord {
 				negativeCount += 1.0
 			}
@@ -342,12 +722,12 @@ func (s *SemanticQuoteService) analyzeText(text string) map[string]float64 {
 
 	// Action vs reflection
 	for _, word := range words {
-		for _, actionWord := range s.lexicon.ActionWords {
+		for _, actionWord := range lexicon.ActionWords {
 			if word == actionWord {
 				features["tone:action"] += 1.0
 			}
 		}
-		for _, reflectWord := range s.lexicon.ReflectiveWords {
+		for _, reflectWord := range lexicon.ReflectiveWords {
 			if word == reflectWord {
 				features["tone:reflective"] += 1.0
 			}
@@ -359,33 +739,56 @@ func (s *SemanticQuoteService) analyzeText(text string) map[string]float64 {
 
 // Calculate cosine similarity between query and quote feature vectors
 func (s *SemanticQuoteService) calculateSimilarity(queryFeatures, quoteFeatures map[string]float64) float64 {
-	// Get all unique features
-	allFeatures := make(map[string]bool)
+	return s.similarityBreakdown(queryFeatures, quoteFeatures).Score
+}
+
+// similarityBreakdown computes the similarity score along with every factor
+// that went into it, so results can be explained
+func (s *SemanticQuoteService) similarityBreakdown(queryFeatures, quoteFeatures map[string]float64) *MatchExplanation {
+	return s.weightedBreakdown(s.rankingWeights(), queryFeatures, quoteFeatures)
+}
+
+// weightedBreakdown is similarityBreakdown with explicit weights, so training
+// can score candidates without changing the service
+func (s *SemanticQuoteService) weightedBreakdown(weights *RankingWeights, queryFeatures, quoteFeatures map[string]float64) *MatchExplanation {
+	explanation := &MatchExplanation{}
+
+	// Get all unique features, in a fixed order so that equal quotes always
+	// sum to exactly the same score and ties keep corpus order
+	allFeatures := make([]string, 0, len(queryFeatures)+len(quoteFeatures))
 	for feature := range queryFeatures {
-		allFeatures[feature] = true
+		allFeatures = append(allFeatures, feature)
 	}
 	for feature := range quoteFeatures {
-		allFeatures[feature] = true
+		if _, ok := queryFeatures[feature]; !ok {
+			allFeatures = append(allFeatures, feature)
+		}
 	}
+	sort.Strings(allFeatures)
 
 	// Calculate dot product and magnitudes
 	dotProduct := 0.0
 	queryMagnitude := 0.0
 	quoteMagnitude := 0.0
 
-	for feature := range allFeatures {
+	for _, feature := range allFeatures {
 		queryVal := queryFeatures[feature]
 		quoteVal := quoteFeatures[feature]
 
 		// Weight emotions higher than other features
-		weight := 1.0
-		if strings.HasPrefix(feature, "emotion:") {
-			weight = 3.0
-		} else if strings.HasPrefix(feature, "theme:") {
-			weight = 2.5
+		weight := weights.featureWeight(feature)
+
+		product := (queryVal * weight) * (quoteVal * weight)
+		if product != 0 {
+			explanation.Shared = append(explanation.Shared, FeatureContribution{
+				Feature:      feature,
+				QueryWeight:  queryVal,
+				QuoteWeight:  quoteVal,
+				Contribution: product,
+			})
 		}
 
-		dotProduct += (queryVal * weight) * (quoteVal * weight)
+		dotProduct += product
 		queryMagnitude += (queryVal * weight) * (queryVal * weight)
 		quoteMagnitude += (quoteVal * weight) * (quoteVal * weight)
 	}
@@ -397,11 +800,11 @@ func (s *SemanticQuoteService) calculateSimilarity(queryFeatures, quoteFeatures
 	// Strong penalty for opposite sentiments
 	sentimentPenalty := 1.0
 	if querySentiment == "negative" && quoteSentiment == "positive" {
-		sentimentPenalty = 0.4
+		sentimentPenalty = weights.NegativeToPositive
 	} else if querySentiment == "positive" && quoteSentiment == "negative" {
-		sentimentPenalty = 0.3
+		sentimentPenalty = weights.PositiveToNegative
 	} else if querySentiment == "neutral" && quoteSentiment != "neutral" {
-		sentimentPenalty = 0.8
+		sentimentPenalty = weights.NeutralToEmotional
 	}
 
 	// Apply tone filtering - penalize mismatched emotional contexts
@@ -412,14 +815,27 @@ func (s *SemanticQuoteService) calculateSimilarity(queryFeatures, quoteFeatures
 
 	tonePenalty := 1.0
 	if queryHasJoy && quoteHasConflict {
-		tonePenalty = 0.3
+		tonePenalty = weights.JoyConflict
 	}
 
+	explanation.QuerySentiment = querySentiment
+	explanation.QuoteSentiment = quoteSentiment
+	explanation.SentimentPenalty = sentimentPenalty
+	explanation.TonePenalty = tonePenalty
+
 	if queryMagnitude == 0 || quoteMagnitude == 0 {
-		return 0.0
+		explanation.Shared = nil
+		return explanation
+	}
+
+	norm := math.Sqrt(queryMagnitude) * math.Sqrt(quoteMagnitude)
+	for i := range explanation.Shared {
+		explanation.Shared[i].Contribution /= norm
 	}
+	sortContributions(explanation.Shared)
 
-	similarity := (dotProduct / (math.Sqrt(queryMagnitude) * math.Sqrt(quoteMagnitude))) * sentimentPenalty * tonePenalty
+	explanation.Cosine = dotProduct / norm
+	similarity := explanation.Cosine * sentimentPenalty * tonePenalty
 
 	// Normalize to 0-1 range
 	if similarity < 0 {
@@ -429,7 +845,8 @@ func (s *SemanticQuoteService) calculateSimilarity(queryFeatures, quoteFeatures
 		similarity = 1
 	}
 
-	return similarity
+	explanation.Score = similarity
+	return explanation
 }
 
 func (s *SemanticQuoteService) getQuoteTextFromFeatures(features map[string]float64) string {
@@ -439,6 +856,11 @@ func (s *SemanticQuoteService) getQuoteTextFromFeatures(features map[string]floa
 }
 
 func (s *SemanticQuoteService) getSentiment(features map[string]float64) string {
+	return sentimentOf(features)
+}
+
+// sentimentOf is positive, negative or neutral by the sentiment words counted
+func sentimentOf(features map[string]float64) string {
 	positive := features["sentiment:positive"]
 	negative := features["sentiment:negative"]
 
@@ -482,13 +904,13 @@ func (s *SemanticQuoteService) tokenize(text string) []string {
 
 // Emotional Lexicon - Dynamic knowledge base
 type EmotionalLexicon struct {
-	EmotionKeywords  map[string][]string
-	EmotionRelations map[string][]string
-	ThemeKeywords    map[string][]string
-	PositiveWords    []string
-	NegativeWords    []string
-	ActionWords      []string
-	ReflectiveWords  []string
+	EmotionKeywords  map[string][]string `json:"emotion_keywords"`
+	EmotionRelations map[string][]string `json:"emotion_relations"`
+	ThemeKeywords    map[string][]string `json:"theme_keywords"`
+	PositiveWords    []string            `json:"positive_words"`
+	NegativeWords    []string            `json:"negative_words"`
+	ActionWords      []string            `json:"action_words"`
+	ReflectiveWords  []string            `json:"reflective_words"`
 }
 
 func NewEmotionalLexicon() *EmotionalLexicon {
@@ -584,11 +1006,117 @@ func NewEmotionalLexicon() *EmotionalLexicon {
 
 // CLI Interface
 type CLI struct {
-	service QuoteService
+	service   QuoteService
+	favorites FavoritesStore
+	feedback  FeedbackStore
+	journal   Journal
+	exposure  *ExposureTracker
+	profiles  *ProfileBuilder
+	topN      int
+	minScore  float64
+
+	// Interactive session state
+	filter       QuoteFilter
+	lastQuery    string
+	shown        []SearchResult
+	history      []string
+	conversation *Conversation
+	contextDecay float64
+	journalEntry string             // ID of the last query's journal entry
+	novelty      *Novelty           // exposure when the last query was first searched
+	profile      *PreferenceProfile // preferences when the last query was first searched
+}
+
+func NewCLI(service QuoteService, topN int, minScore float64) *CLI {
+	return &CLI{service: service, topN: topN, minScore: minScore, contextDecay: DefaultContextDecay}
 }
 
-func NewCLI(service QuoteService) *CLI {
-	return &CLI{service: service}
+// UseFavorites enables the :save command
+func (c *CLI) UseFavorites(store FavoritesStore) {
+	c.favorites = store
+}
+
+// UseFeedback enables the :rate command
+func (c *CLI) UseFeedback(store FeedbackStore) {
+	c.feedback = store
+}
+
+// UseJournal logs every query, the quotes shown and ratings to the journal
+func (c *CLI) UseJournal(journal Journal) {
+	c.journal = journal
+}
+
+// UseExposure remembers the quotes shown, so later queries and sessions
+// favor quotes not seen lately. The service must implement ContextSearcher.
+func (c *CLI) UseExposure(tracker *ExposureTracker) error {
+	if _, ok := c.service.(ContextSearcher); !ok && tracker != nil {
+		return fmt.Errorf("novelty ranking is not available for this search engine")
+	}
+	c.exposure = tracker
+	return nil
+}
+
+// UseProfile personalizes results with preferences learned from the
+// user's favorites and feedback. The service must implement ContextSearcher.
+func (c *CLI) UseProfile(builder *ProfileBuilder) error {
+	if _, ok := c.service.(ContextSearcher); !ok && builder != nil {
+		return fmt.Errorf("personalized ranking is not available for this search engine")
+	}
+	c.profiles = builder
+	return nil
+}
+
+// stages are the novelty and personalization steps of the last query
+func (c *CLI) stages() rankStages {
+	return rankStages{novelty: c.novelty, profile: c.profile}
+}
+
+// UseConversation carries context between interactive queries; nil turns
+// it off. The service must implement ConversationSearcher.
+func (c *CLI) UseConversation(conv *Conversation) error {
+	if conv != nil {
+		if _, ok := c.service.(ConversationSearcher); !ok {
+			return fmt.Errorf("conversation context is not available for this search engine")
+		}
+		c.contextDecay = conv.Decay()
+	}
+	c.conversation = conv
+	return nil
+}
+
+// UseFilter restricts every search to quotes matching the filter. The
+// service must implement FilteredSearcher unless the filter is empty.
+func (c *CLI) UseFilter(filter QuoteFilter) error {
+	if _, ok := c.service.(FilteredSearcher); !ok && !filter.IsZero() {
+		return fmt.Errorf("filters are not available for this search engine")
+	}
+	c.filter = filter
+	return nil
+}
+
+// search runs the query through the filter when one is set, and through
+// the conversation, the novelty penalty and the profile when those are on
+func (c *CLI) search(query string, topN int, conv *Conversation) ([]SearchResult, error) {
+	switch {
+	case c.exposure != nil || c.profiles != nil:
+		return c.service.(ContextSearcher).Search(context.Background(), SearchRequest{
+			Query:        query,
+			TopN:         topN,
+			Filter:       c.filter,
+			Conversation: conv,
+			Novelty:      c.novelty,
+			Profile:      c.profile,
+		})
+	case conv != nil:
+		return c.conversationSearcher().SearchConversation(conv, query, topN, c.filter)
+	case c.filter.IsZero():
+		return c.service.SearchQuotes(query, topN)
+	}
+	return c.service.(FilteredSearcher).SearchFiltered(query, topN, c.filter)
+}
+
+func (c *CLI) conversationSearcher() ConversationSearcher {
+	return c.service.(ConversationSearcher)
 }
 
 func (c *CLI) Run() {
@@ -620,6 +1148,15 @@ func (c *CLI) Run() {
 			break
 		}
 
+		if strings.HasPrefix(query, ":") {
+			if quit := c.handleCommand(query); quit {
+				fmt.Println("\nTake care! Remember: just keep swimming. 🐠")
+				break
+			}
+			continue
+		}
+
+		c.history = append(c.history, query)
 		c.displayResults(query)
 	}
 }
@@ -635,31 +1172,140 @@ func (c *CLI) RunSingleQuery(query string) {
 }
 
 func (c *CLI) displayResults(query string) {
-	results, err := c.service.SearchQuotes(query, 3)
+	c.lastQuery = query
+	c.shown = nil
+	c.novelty, c.profile = nil, nil
+	// Read once per query, so pages stay in the same order while the quotes
+	// on them are recorded as shown
+	if c.exposure != nil {
+		var err error
+		if c.novelty, err = c.exposure.Novelty(); err != nil {
+			fmt.Printf("\n⚠ Could not read the quotes shown before: %v\n", err)
+		}
+	}
+	if c.profiles != nil {
+		var err error
+		if c.profile, err = c.profiles.Profile(); err != nil {
+			fmt.Printf("\n⚠ Could not learn your preferences: %v\n", err)
+		}
+	}
+	c.displayNextPage()
+}
+
+// displayNextPage shows the next topN results for the last query, numbering
+// them after the results already shown
+func (c *CLI) displayNextPage() {
+	offset := len(c.shown)
+
+	page, err := c.fetchNextPage()
+	c.journalPage(offset, page, err)
 	if err != nil {
 		// Check if it's a crisis situation
-		if err.Error() == "CRISIS_DETECTED" {
+		if errors.Is(err, ErrCrisisDetected) {
 			c.displayCrisisResources()
 			return
 		}
 
+		var lowConfidence *LowConfidenceError
+		if errors.As(err, &lowConfidence) {
+			fmt.Println("\n🤔 I couldn't find a quote that really fits what you described.")
+			fmt.Printf("   (best match scored %.2f, below the %.2f confidence threshold)\n", lowConfidence.BestScore, lowConfidence.MinScore)
+			fmt.Println("Try telling me a bit more about how you feel.")
+			return
+		}
+
+		if errors.Is(err, ErrNoMatches) && c.conversation != nil && c.conversation.ShownCount() > 0 {
+			fmt.Println("\n🤔 You've already seen every quote that fits this conversation.")
+			fmt.Println("Type :reset to start a fresh conversation.")
+			return
+		}
+
 		fmt.Printf("\n❌ %s\n", err.Error())
 		fmt.Println("Try describing your feelings differently.")
 		return
 	}
 
-	fmt.Println("\n✨ Here are some quotes that might resonate with you:\n")
-	for i, result := range results {
-		fmt.Printf("%d. [%.2f] \"%s\"\n", i+1, result.Score, result.Quote.Text)
-		fmt.Printf("   — %s (%s)\n", result.Quote.Character, result.Quote.Movie)
-		if i < len(results)-1 {
+	if len(page) == 0 {
+		fmt.Println("\nThat's every quote that fits this search. Try describing it differently.")
+		return
+	}
+
+	if offset == 0 {
+		fmt.Print("\n✨ Here are some quotes that might resonate with you:\n\n")
+	} else {
+		fmt.Print("\n✨ A few more:\n\n")
+	}
+	for i, result := range page {
+		fmt.Printf("%d. [%.2f] \"%s\"\n", offset+i+1, result.Score, result.Quote.Text)
+		fmt.Printf("   — %s (%s) · %s\n", result.Quote.Character, result.Quote.Movie, result.Quote.ID)
+		if i < len(page)-1 {
 			fmt.Println()
 		}
 	}
+	c.shown = append(c.shown, page...)
+	if c.exposure != nil {
+		if err := c.exposure.Record(page); err != nil {
+			fmt.Printf("\n⚠ Could not remember the quotes shown: %v\n", err)
+		}
+	}
 
 	fmt.Println("\n" + strings.Repeat("─", 60))
 }
 
+// journalPage logs a query's first page as a new journal entry and later
+// pages as more quotes shown for it
+func (c *CLI) journalPage(offset int, page []SearchResult, err error) {
+	if c.journal == nil {
+		return
+	}
+	var logErr error
+	if offset == 0 {
+		var entry JournalEntry
+		entry, logErr = c.journal.Log(newJournalEntry(c.service, c.lastQuery, page, err))
+		c.journalEntry = entry.ID
+	} else if c.journalEntry != "" {
+		logErr = c.journal.AddQuotes(c.journalEntry, journalQuotes(page))
+	}
+	if logErr != nil {
+		fmt.Printf("\n⚠ Could not write to your journal: %v\n", logErr)
+	}
+}
+
+// fetchNextPage returns the results after those already shown. In a
+// conversation the service skips shown quotes itself; otherwise the ranking
+// is re-run with a larger cutoff and sliced.
+func (c *CLI) fetchNextPage() ([]SearchResult, error) {
+	offset := len(c.shown)
+
+	if c.conversation != nil {
+		results, err := c.search(c.lastQuery, c.topN, c.conversation)
+		if err == nil {
+			results, err = FilterConfident(results, c.minScore)
+		}
+		if offset > 0 && (errors.Is(err, ErrNoConfidentMatch) || errors.Is(err, ErrNoMatches)) {
+			// Later pages simply run out
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.conversation.MarkShown(results)
+		return results, nil
+	}
+
+	results, err := c.search(c.lastQuery, offset+c.topN, nil)
+	if err == nil {
+		results, err = FilterConfident(results, c.minScore)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(results) {
+		return nil, nil
+	}
+	return results[offset:], nil
+}
+
 func (c *CLI) displayCrisisResources() {
 	fmt.Println("\n" + strings.Repeat("═", 60))
 	fmt.Println()
@@ -670,19 +1316,13 @@ func (c *CLI) displayCrisisResources() {
 	fmt.Println()
 	fmt.Println("🆘 CRISIS RESOURCES:")
 	fmt.Println()
-	fmt.Println("   • National Suicide Prevention Lifeline (US)")
-	fmt.Println("     Call or Text: 988")
-	fmt.Println("     Available 24/7, free and confidential")
-	fmt.Println()
-	fmt.Println("   • Crisis Text Line (US)")
-	fmt.Println("     Text: HOME to 741741")
-	fmt.Println()
-	fmt.Println("   • International Association for Suicide Prevention")
-	fmt.Println("     https://www.iasp.info/resources/Crisis_Centres/")
-	fmt.Println()
-	fmt.Println("   • Emergency Services")
-	fmt.Println("     Call: 911 (US) or your local emergency number")
-	fmt.Println()
+	for _, resource := range CrisisResources {
+		fmt.Printf("   • %s\n", resource.Name)
+		for _, detail := range resource.Details {
+			fmt.Printf("     %s\n", detail)
+		}
+		fmt.Println()
+	}
 	fmt.Println("You don't have to go through this alone. These trained")
 	fmt.Println("professionals are available to listen and help, any time.")
 	fmt.Println()
@@ -690,82 +1330,5 @@ func (c *CLI) displayCrisisResources() {
 }
 
 func main() {
-	// Parse command line arguments
-	args := os.Args[1:]
-
-	var quotesFile string
-	var customQuery string
-
-	// Default quotes file
-	quotesFile = "quotes.json"
-
-	// Parse arguments
-	i := 0
-	for i < len(args) {
-		arg := args[i]
-
-		if arg == "--query" || arg == "-q" {
-			if i+1 >= len(args) {
-				fmt.Fprintf(os.Stderr, "Error: --query flag requires an argument\n")
-				printUsage()
-				os.Exit(1)
-			}
-			customQuery = args[i+1]
-			i += 2
-		} else if arg == "--help" || arg == "-h" {
-			printUsage()
-			os.Exit(0)
-		} else {
-			// Assume it's the quotes file path
-			quotesFile = arg
-			i++
-		}
-	}
-
-	// Dependency injection
-	repo := NewFileQuoteRepository()
-	service := NewSemanticQuoteService(repo)
-
-	// Initialize service with quotes file
-	if err := service.Initialize(quotesFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Run CLI
-	cli := NewCLI(service)
-
-	// If custom query provided, run single query mode
-	if customQuery != "" {
-		cli.RunSingleQuery(customQuery)
-	} else {
-		cli.Run()
-	}
-}
-
-func printUsage() {
-	fmt.Println("Movie Quote Search Engine - Find inspiration in cinema")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  go run main.go [quotes_file] [options]")
-	fmt.Println()
-	fmt.Println("Arguments:")
-	fmt.Println("  quotes_file    Path to quotes JSON file (default: quotes.json)")
-	fmt.Println()
-	fmt.Println("Options:")
-	fmt.Println("  --query, -q    Custom query to search (skips interactive mode)")
-	fmt.Println("  --help, -h     Show this help message")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  # Interactive mode with default file")
-	fmt.Println("  go run main.go")
-	fmt.Println()
-	fmt.Println("  # Interactive mode with custom file")
-	fmt.Println("  go run main.go my_quotes.json")
-	fmt.Println()
-	fmt.Println("  # Single query mode")
-	fmt.Println("  go run main.go --query \"I just got rejected and feel like giving up\"")
-	fmt.Println()
-	fmt.Println("  # Single query with custom file")
-	fmt.Println("  go run main.go my_quotes.json --query \"I need motivation\"")
+	os.Exit(runCommand(os.Args[1:]))
 }
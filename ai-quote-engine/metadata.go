@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ContentRating is an MPA film rating such as PG or R
+type ContentRating string
+
+// Ratings from least to most restricted
+var contentRatings = []ContentRating{"G", "PG", "PG-13", "R", "NC-17"}
+
+// ParseContentRating accepts a rating in any case, with or without the dash
+func ParseContentRating(value string) (ContentRating, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+	for _, rating := range contentRatings {
+		if normalized == string(rating) || normalized == strings.ReplaceAll(string(rating), "-", "") {
+			return rating, nil
+		}
+	}
+	return "", fmt.Errorf("unknown content rating %q (expected G, PG, PG-13, R or NC-17)", value)
+}
+
+// level orders ratings; unrated quotes are 0
+func (r ContentRating) level() int {
+	for i, rating := range contentRatings {
+		if r == rating {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// QuoteFilter narrows the corpus before scoring. Zero fields do not filter;
+// a quote missing the metadata a filter asks about is left out.
+type QuoteFilter struct {
+	Genres    []string      // any of these genres
+	MaxRating ContentRating // rated at most this
+	YearFrom  int           // released in or after this year
+	YearTo    int           // released in or before this year
+	Language  string
+}
+
+func (f QuoteFilter) IsZero() bool {
+	return len(f.Genres) == 0 && f.MaxRating == "" && f.YearFrom == 0 && f.YearTo == 0 && f.Language == ""
+}
+
+// Matches reports whether a quote passes every filter
+func (f QuoteFilter) Matches(quote Quote) bool {
+	if len(f.Genres) > 0 && !hasAnyGenre(quote, f.Genres) {
+		return false
+	}
+	if f.MaxRating != "" {
+		level := quote.Rating.level()
+		if level == 0 || level > f.MaxRating.level() {
+			return false
+		}
+	}
+	if f.YearFrom != 0 || f.YearTo != 0 {
+		if quote.Year == 0 || (f.YearFrom != 0 && quote.Year < f.YearFrom) || (f.YearTo != 0 && quote.Year > f.YearTo) {
+			return false
+		}
+	}
+	if f.Language != "" && !strings.EqualFold(quote.Language, f.Language) {
+		return false
+	}
+	return true
+}
+
+func hasAnyGenre(quote Quote, genres []string) bool {
+	for _, want := range genres {
+		for _, genre := range quote.Genres {
+			if strings.EqualFold(genre, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseGenres splits a list like "drama, sports" or "drama;sports"
+func ParseGenres(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	})
+	var genres []string
+	for _, field := range fields {
+		if genre := strings.ToLower(strings.TrimSpace(field)); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
+
+// ParseYearRange reads "1995", "1990-2010", "1990-" or "-2010"
+func ParseYearRange(value string) (from, to int, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, nil
+	}
+	first, last, isRange := strings.Cut(value, "-")
+	if !isRange {
+		last = first
+	}
+
+	parse := func(s string) (int, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return 0, nil
+		}
+		year, err := strconv.Atoi(s)
+		if err != nil || year < 1 {
+			return 0, fmt.Errorf("invalid year range %q (expected e.g. 1995 or 1990-2010)", value)
+		}
+		return year, nil
+	}
+	if from, err = parse(first); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parse(last); err != nil {
+		return 0, 0, err
+	}
+	if from == 0 && to == 0 {
+		return 0, 0, fmt.Errorf("invalid year range %q (expected e.g. 1995 or 1990-2010)", value)
+	}
+	if from != 0 && to != 0 && from > to {
+		return 0, 0, fmt.Errorf("invalid year range %q: %d is after %d", value, from, to)
+	}
+	return from, to, nil
+}
+
+// normalizeMetadata canonicalizes ratings, genres and languages so filters
+// compare like with like. Invalid ratings are caught by lint before this runs.
+func normalizeMetadata(quotes []Quote) {
+	for i := range quotes {
+		quote := &quotes[i]
+		if rating, err := ParseContentRating(string(quote.Rating)); err == nil {
+			quote.Rating = rating
+		}
+		if len(quote.Genres) > 0 {
+			quote.Genres = ParseGenres(strings.Join(quote.Genres, ","))
+		}
+		quote.Language = strings.ToLower(strings.TrimSpace(quote.Language))
+		quote.Context = strings.TrimSpace(quote.Context)
+	}
+}
+
+// metadataSummary is a one-line description such as "1995 · PG · drama, sports · en"
+func metadataSummary(quote Quote) string {
+	var parts []string
+	if quote.Year != 0 {
+		parts = append(parts, strconv.Itoa(quote.Year))
+	}
+	if quote.Rating != "" {
+		parts = append(parts, string(quote.Rating))
+	}
+	if len(quote.Genres) > 0 {
+		parts = append(parts, strings.Join(quote.Genres, ", "))
+	}
+	if quote.Language != "" {
+		parts = append(parts, quote.Language)
+	}
+	return strings.Join(parts, " · ")
+}
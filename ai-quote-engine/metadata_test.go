@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseContentRating(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ContentRating
+		ok    bool
+	}{
+		{"PG", "PG", true},
+		{" pg-13 ", "PG-13", true},
+		{"pg13", "PG-13", true},
+		{"nc17", "NC-17", true},
+		{"X", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseContentRating(tt.value)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("ParseContentRating(%q) = %q, %v; want %q, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseYearRange(t *testing.T) {
+	tests := []struct {
+		value    string
+		from, to int
+		ok       bool
+	}{
+		{"", 0, 0, true},
+		{"1995", 1995, 1995, true},
+		{"1990-2010", 1990, 2010, true},
+		{" 1990 - ", 1990, 0, true},
+		{"-2010", 0, 2010, true},
+		{"-", 0, 0, false},
+		{"2010-1990", 0, 0, false},
+		{"nineties", 0, 0, false},
+		{"0", 0, 0, false},
+	}
+	for _, tt := range tests {
+		from, to, err := ParseYearRange(tt.value)
+		if from != tt.from || to != tt.to || (err == nil) != tt.ok {
+			t.Errorf("ParseYearRange(%q) = %d, %d, %v; want %d, %d, ok %v", tt.value, from, to, err, tt.from, tt.to, tt.ok)
+		}
+	}
+}
+
+func TestParseGenres(t *testing.T) {
+	if got, want := ParseGenres(" Drama, sports;;Sci-Fi | "), []string{"drama", "sports", "sci-fi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := ParseGenres(" , "); got != nil {
+		t.Errorf("got %q from no genres, want nil", got)
+	}
+}
+
+func TestQuoteFilterMatches(t *testing.T) {
+	rocky := Quote{Text: "Keep punching.", Year: 1976, Rating: "PG", Genres: []string{"drama", "sports"}, Language: "en"}
+	unknown := Quote{Text: "No metadata at all."}
+
+	tests := []struct {
+		name    string
+		filter  QuoteFilter
+		rocky   bool
+		unknown bool
+	}{
+		{"no filter", QuoteFilter{}, true, true},
+		{"any genre", QuoteFilter{Genres: []string{"comedy", "Sports"}}, true, false},
+		{"other genre", QuoteFilter{Genres: []string{"comedy"}}, false, false},
+		{"rating within", QuoteFilter{MaxRating: "PG-13"}, true, false},
+		{"rating at the limit", QuoteFilter{MaxRating: "PG"}, true, false},
+		{"rating above", QuoteFilter{MaxRating: "G"}, false, false},
+		{"year in range", QuoteFilter{YearFrom: 1970, YearTo: 1979}, true, false},
+		{"year from", QuoteFilter{YearFrom: 1977}, false, false},
+		{"year to", QuoteFilter{YearTo: 1976}, true, false},
+		{"language in any case", QuoteFilter{Language: "EN"}, true, false},
+		{"other language", QuoteFilter{Language: "fr"}, false, false},
+		{"every filter must pass", QuoteFilter{Genres: []string{"drama"}, Language: "fr"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(rocky); got != tt.rocky {
+				t.Errorf("got %v for a 1976 PG sports drama, want %v", got, tt.rocky)
+			}
+			if got := tt.filter.Matches(unknown); got != tt.unknown {
+				t.Errorf("got %v for a quote without metadata, want %v", got, tt.unknown)
+			}
+		})
+	}
+}
+
+func TestNormalizeMetadata(t *testing.T) {
+	quotes := []Quote{{Rating: "pg13", Genres: []string{"Drama", " sports;family"}, Language: " EN ", Context: " the final fight "}}
+	normalizeMetadata(quotes)
+	want := Quote{Rating: "PG-13", Genres: []string{"drama", "sports", "family"}, Language: "en", Context: "the final fight"}
+	if !reflect.DeepEqual(quotes[0], want) {
+		t.Errorf("got %+v, want %+v", quotes[0], want)
+	}
+}
+
+func TestSearchFiltered(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+	filter := QuoteFilter{MaxRating: "PG"}
+	results, err := service.SearchFiltered(testQueries[1], 20, filter)
+	if err != nil || len(results) == 0 {
+		t.Fatalf("got %d results and error %v, want some", len(results), err)
+	}
+	for _, result := range results {
+		if !filter.Matches(result.Quote) {
+			t.Errorf("got %s rated %q past a PG filter", result.Quote.ID, result.Quote.Rating)
+		}
+	}
+
+	// A request can narrow the server's filter, never widen it
+	server := NewServer(service, 20, 0)
+	if err := server.UseFilter(filter); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target string
+		worst  ContentRating
+	}{
+		{"/search?q=keep+going&max_rating=R", "PG"},
+		{"/search?q=keep+going&max_rating=g", "G"},
+	} {
+		var response SearchResponse
+		if status := request(t, server, "GET", tt.target, "", &response); status != http.StatusOK || len(response.Results) == 0 {
+			t.Fatalf("%s: got status %d and %d results, want some", tt.target, status, len(response.Results))
+		}
+		for _, result := range response.Results {
+			if result.Rating.level() > tt.worst.level() {
+				t.Errorf("%s: got %s rated %s, want at most %s", tt.target, result.ID, result.Rating, tt.worst)
+			}
+		}
+	}
+	if status := request(t, server, "GET", "/search?q=keep+going&year=2010-1990", "", nil); status != http.StatusBadRequest {
+		t.Errorf("got status %d for a backwards year range, want 400", status)
+	}
+}
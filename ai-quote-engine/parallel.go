@@ -0,0 +1,36 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelMinQuotes is the corpus size from which scoring and indexing are
+// spread across CPU cores; below it the goroutines cost more than they save
+const parallelMinQuotes = 2048
+
+// forEachRange splits [0, n) into at most workers contiguous ranges and runs
+// fn on each concurrently, returning once all are done. part numbers the
+// ranges in order, so callers can merge per-part results deterministically.
+// Small inputs run as a single range on the calling goroutine.
+func forEachRange(n, workers int, fn func(part, start, end int)) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if n < parallelMinQuotes || workers == 1 {
+		fn(0, 0, n)
+		return
+	}
+
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for part, start := 0, 0; start < n; part, start = part+1, start+size {
+		end := min(start+size, n)
+		wg.Add(1)
+		go func(part, start, end int) {
+			defer wg.Done()
+			fn(part, start, end)
+		}(part, start, end)
+	}
+	wg.Wait()
+}
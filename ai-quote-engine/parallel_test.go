@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestForEachRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		workers int
+		parts   int
+	}{
+		{"empty", 0, 4, 1},
+		{"small runs serially", parallelMinQuotes - 1, 4, 1},
+		{"one worker", 3 * parallelMinQuotes, 1, 1},
+		{"split evenly", 4 * parallelMinQuotes, 4, 4},
+		{"uneven split", parallelMinQuotes + 1, 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			visits := make([]int, tt.n)
+			starts := map[int]int{}
+			forEachRange(tt.n, tt.workers, func(part, start, end int) {
+				mu.Lock()
+				defer mu.Unlock()
+				starts[part] = start
+				for i := start; i < end; i++ {
+					visits[i]++
+				}
+			})
+
+			if len(starts) != tt.parts {
+				t.Errorf("got %d parts, want %d", len(starts), tt.parts)
+			}
+			for part := 1; part < len(starts); part++ {
+				if starts[part] <= starts[part-1] {
+					t.Errorf("part %d starts at %d, before part %d at %d", part, starts[part], part-1, starts[part-1])
+				}
+			}
+			for i, n := range visits {
+				if n != 1 {
+					t.Fatalf("index %d visited %d times", i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelScoringMatchesSerial(t *testing.T) {
+	quotes := testCorpus(t, parallelMinQuotes+500)
+	serial, filename := newTestService(t, quotes)
+	serial.UseWorkers(1)
+	parallel := NewSemanticQuoteService(NewFileQuoteRepositoryWithOptions(LoadOptions{KeepDuplicates: true}))
+	parallel.UseWorkers(4)
+	if err := parallel.Initialize(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(serial.Index(), parallel.Index()) {
+		t.Fatal("parallel indexing differs from serial")
+	}
+	for _, query := range testQueries {
+		req := SearchRequest{Query: query, TopN: len(quotes)}
+		want, wantErr := serial.Search(context.Background(), req)
+		got, err := parallel.Search(context.Background(), req)
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("%q: got error %v, want %v", query, err, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: parallel results differ from serial (%d vs %d results)", query, len(got), len(want))
+		}
+	}
+}
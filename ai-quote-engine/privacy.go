@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of personal information RedactPII replaces
+const (
+	PIIName    = "name"
+	PIIEmail   = "email"
+	PIIPhone   = "phone"
+	PIIAddress = "address"
+)
+
+// purgeInterval is how often a store with a retention period is purged
+const purgeInterval = time.Hour
+
+// PIIMatch is personal information found in a text
+type PIIMatch struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Start int    `json:"start"` // byte offsets into the text
+	End   int    `json:"end"`
+}
+
+// piiDetector finds one kind of personal information. When the pattern has
+// a group, only the group is personal, e.g. the name after "my name is".
+type piiDetector struct {
+	kind    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// notPronoun keeps "I" and its contractions from reading as names
+func notPronoun(match string) bool {
+	word, _, _ := strings.Cut(match, " ")
+	return word != "I" && !strings.HasPrefix(word, "I'")
+}
+
+// notPhonePattern matches dates and year ranges, which look like phone numbers
+var notPhonePattern = regexp.MustCompile(`^(\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}|\d{4}\s*-\s*\d{4})$`)
+
+// looksLikePhone tells a phone number from a date or a year range: 7 to 15
+// digits that are neither
+func looksLikePhone(match string) bool {
+	if notPhonePattern.MatchString(match) {
+		return false
+	}
+	digits := 0
+	for _, r := range match {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+// piiDetectors are heuristics: names are only found where the words around
+// them say so, e.g. "my name is", "Dr." or "my sister"
+var piiDetectors = []piiDetector{
+	{PIIEmail, regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`), nil},
+	{PIIAddress, regexp.MustCompile(`\b\d{1,5}[A-Za-z]?\s+(?:[A-Z][\w'-]*\s+){1,3}(?i:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|terrace|close|square|sq)\b\.?`), nil},
+	{PIIAddress, regexp.MustCompile(`(?i)\bp\.?\s?o\.?\s+box\s+\d+\b`), nil},
+	{PIIPhone, regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{1,4}\)[\s.-]?)?\d[\d\s.-]{5,}\d`), looksLikePhone},
+	{PIIName, regexp.MustCompile(`(?i:\b(?:my name is|my name's|i am called|i'm called|they call me))\s+([A-Za-z][a-z'-]*(?:\s+[A-Z][a-z'-]+)?)`), notPronoun},
+	{PIIName, regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Mx|Dr|Prof)\.?\s+[A-Z][a-z'-]+(?:\s+[A-Z][a-z'-]+)?`), nil},
+	{PIIName, regexp.MustCompile(`(?i:\b(?:my|our|his|her|their)\s+(?:best friend|friend|wife|husband|partner|boyfriend|girlfriend|fiancée?|son|daughter|kid|child|mom|mum|mother|dad|father|brother|sister|boss|colleague|coworker|neighbou?r|grandma|grandpa|grandmother|grandfather|aunt|uncle|cousin|ex|therapist|teacher|dog|cat)),?\s+([A-Z][a-z'-]+(?:\s+[A-Z][a-z'-]+)?)`), notPronoun},
+}
+
+// DetectPII finds names, emails, phone numbers and addresses in a text, in
+// the order they appear; where two overlap the earlier, longer one is kept
+func DetectPII(text string) []PIIMatch {
+	var matches []PIIMatch
+	for _, detector := range piiDetectors {
+		for _, loc := range detector.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			match := strings.TrimSpace(text[start:end])
+			if detector.valid != nil && !detector.valid(match) {
+				continue
+			}
+			end = start + len(match)
+			matches = append(matches, PIIMatch{Kind: detector.kind, Text: match, Start: start, End: end})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+
+	kept := matches[:0]
+	for _, match := range matches {
+		if len(kept) > 0 && match.Start < kept[len(kept)-1].End {
+			continue
+		}
+		kept = append(kept, match)
+	}
+	return kept
+}
+
+// RedactPII replaces the personal information DetectPII finds with its
+// kind in brackets, e.g. "[email]", and lists what it replaced
+func RedactPII(text string) (string, []PIIMatch) {
+	matches := DetectPII(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(text[last:match.Start])
+		b.WriteString("[" + match.Kind + "]")
+		last = match.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// PrivacyPolicy says what of a user's own words is stored, and for how long.
+// It applies to feedback, the journal and the record of quotes shown;
+// favorites are kept until the user removes them. The zero policy stores
+// everything as given, forever.
+type PrivacyPolicy struct {
+	// Redact replaces names, emails, phone numbers and addresses in queries
+	// and comments before they are stored
+	Redact bool
+	// FeaturesOnly stores what the engine read in a query, such as its
+	// emotions and themes, instead of the query and comments themselves
+	FeaturesOnly bool
+	// Retention is how long anything is kept; older data is purged
+	// automatically. 0 keeps it forever.
+	Retention time.Duration
+}
+
+// IsZero reports whether the policy leaves stores as they are
+func (p PrivacyPolicy) IsZero() bool {
+	return p == PrivacyPolicy{}
+}
+
+// text is what is stored of a user's words
+func (p PrivacyPolicy) text(s string) string {
+	if p.FeaturesOnly {
+		return ""
+	}
+	if p.Redact {
+		s, _ = RedactPII(s)
+	}
+	return s
+}
+
+// cutoff is the time before which data is expired, or zero without one
+func (p PrivacyPolicy) cutoff(now time.Time) time.Time {
+	if p.Retention <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-p.Retention)
+}
+
+// Purger is implemented by stores that can drop what they stored before a
+// time, returning how much was dropped
+type Purger interface {
+	Purge(before time.Time) (int, error)
+}
+
+// purge drops what a store kept before a time, if it can
+func purge(store any, before time.Time) (int, error) {
+	if purger, ok := store.(Purger); ok {
+		return purger.Purge(before)
+	}
+	return 0, nil
+}
+
+// autoPurge calls purge with the retention cutoff now and then every
+// purgeInterval until stop is called, so expired data goes even when nobody
+// comes back to read or add to it
+func autoPurge(purge func(before time.Time) (int, error), retention time.Duration, report func(int, error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			report(purge(time.Now().Add(-retention)))
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// retention purges one store at most once per purgeInterval
+type retention struct {
+	policy PrivacyPolicy
+	store  any
+	mu     sync.Mutex
+	last   time.Time
+}
+
+// purge drops expired data from the store when a purge is due, returning
+// the cutoff reads should apply
+func (r *retention) purge() (time.Time, error) {
+	now := time.Now()
+	cutoff := r.policy.cutoff(now)
+	purger, ok := r.store.(Purger)
+	if cutoff.IsZero() || !ok {
+		return cutoff, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.last) < purgeInterval {
+		return cutoff, nil
+	}
+	if _, err := purger.Purge(cutoff); err != nil {
+		return cutoff, fmt.Errorf("failed to purge expired data: %w", err)
+	}
+	r.last = now
+	return cutoff, nil
+}
+
+// Stores applies the policy to a user's stores; nil stores stay nil
+func (p PrivacyPolicy) Stores(stores UserStores) UserStores {
+	if stores.Feedback != nil {
+		stores.Feedback = p.Feedback(stores.Feedback)
+	}
+	if stores.Journal != nil {
+		stores.Journal = p.Journal(stores.Journal)
+	}
+	if stores.Exposure != nil {
+		stores.Exposure = p.Exposure(stores.Exposure)
+	}
+	return stores
+}
+
+// Feedback applies the policy to a feedback store
+func (p PrivacyPolicy) Feedback(store FeedbackStore) FeedbackStore {
+	if p.IsZero() {
+		return store
+	}
+	return &privateFeedback{store: store, retention: &retention{policy: p, store: store}}
+}
+
+type privateFeedback struct {
+	store     FeedbackStore
+	retention *retention
+}
+
+func (f *privateFeedback) Record(entry FeedbackEntry) error {
+	if _, err := f.retention.purge(); err != nil {
+		return err
+	}
+	entry.Query = f.retention.policy.text(entry.Query)
+	entry.Comment = f.retention.policy.text(entry.Comment)
+	return f.store.Record(entry)
+}
+
+func (f *privateFeedback) Purge(before time.Time) (int, error) {
+	return purge(f.store, before)
+}
+
+func (f *privateFeedback) List() ([]FeedbackEntry, error) {
+	cutoff, err := f.retention.purge()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.store.List()
+	if err != nil || cutoff.IsZero() {
+		return entries, err
+	}
+	kept := entries[:0]
+	for _, entry := range entries {
+		if !entry.Time.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+// Journal applies the policy to a journal
+func (p PrivacyPolicy) Journal(journal Journal) Journal {
+	if p.IsZero() {
+		return journal
+	}
+	return &privateJournal{journal: journal, retention: &retention{policy: p, store: journal}}
+}
+
+type privateJournal struct {
+	journal   Journal
+	retention *retention
+}
+
+func (j *privateJournal) Log(entry JournalEntry) (JournalEntry, error) {
+	if _, err := j.retention.purge(); err != nil {
+		return JournalEntry{}, err
+	}
+	entry.Query = j.retention.policy.text(entry.Query)
+	// The ratings belong to the caller; redact a copy
+	entry.Ratings = append([]JournalRating(nil), entry.Ratings...)
+	for i := range entry.Ratings {
+		entry.Ratings[i].Comment = j.retention.policy.text(entry.Ratings[i].Comment)
+	}
+	return j.journal.Log(entry)
+}
+
+func (j *privateJournal) Purge(before time.Time) (int, error) {
+	return purge(j.journal, before)
+}
+
+func (j *privateJournal) AddQuotes(entryID string, quotes []JournalQuote) error {
+	return j.journal.AddQuotes(entryID, quotes)
+}
+
+func (j *privateJournal) AddRating(entryID string, rating JournalRating) error {
+	rating.Comment = j.retention.policy.text(rating.Comment)
+	return j.journal.AddRating(entryID, rating)
+}
+
+func (j *privateJournal) Entries(since time.Time) ([]JournalEntry, error) {
+	cutoff, err := j.retention.purge()
+	if err != nil {
+		return nil, err
+	}
+	if since.Before(cutoff) {
+		since = cutoff
+	}
+	return j.journal.Entries(since)
+}
+
+// Exposure applies the policy's retention to the record of quotes shown,
+// which holds no text of the user's
+func (p PrivacyPolicy) Exposure(store ExposureStore) ExposureStore {
+	if p.Retention <= 0 {
+		return store
+	}
+	return &privateExposure{store: store, retention: &retention{policy: p, store: store}}
+}
+
+type privateExposure struct {
+	store     ExposureStore
+	retention *retention
+}
+
+func (e *privateExposure) RecordShown(user string, quoteIDs []string, at time.Time) error {
+	if _, err := e.retention.purge(); err != nil {
+		return err
+	}
+	return e.store.RecordShown(user, quoteIDs, at)
+}
+
+func (e *privateExposure) Purge(before time.Time) (int, error) {
+	return purge(e.store, before)
+}
+
+func (e *privateExposure) Shown(user string) (map[string][]time.Time, error) {
+	cutoff, err := e.retention.purge()
+	if err != nil {
+		return nil, err
+	}
+	shown, err := e.store.Shown(user)
+	if err != nil || cutoff.IsZero() {
+		return shown, err
+	}
+	kept := make(map[string][]time.Time, len(shown))
+	for id, times := range shown {
+		if times = showingsSince(times, cutoff); len(times) > 0 {
+			kept[id] = times
+		}
+	}
+	return kept, nil
+}
+
+// showingsSince keeps the times at or after cutoff of times sorted oldest first
+func showingsSince(times []time.Time, cutoff time.Time) []time.Time {
+	first := sort.Search(len(times), func(i int) bool { return !times[i].Before(cutoff) })
+	return times[first:]
+}
+
+// ParseRetention reads a retention period: a number of days or weeks (90d,
+// 12w) or a duration (720h). Empty or 0 keeps data forever.
+func ParseRetention(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n > 0 {
+		switch value[len(value)-1] {
+		case 'd':
+			return time.Duration(n) * 24 * time.Hour, nil
+		case 'w':
+			return time.Duration(n) * 7 * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid retention %q (expected e.g. 90d, 12w or 720h)", value)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedactPII(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"nothing personal", "I feel lost and need some hope", "I feel lost and need some hope"},
+		{"email", "write to sam.smith@example.co.uk please", "write to [email] please"},
+		{"phone", "call me on +1 (555) 123-4567 tonight", "call me on [phone] tonight"},
+		{"short phone", "my number is 555-1234", "my number is [phone]"},
+		{"dates are not phones", "since 2024-03-15, or 15/03/2024", "since 2024-03-15, or 15/03/2024"},
+		{"year ranges are not phones", "from 1999 - 2004 it was fine", "from 1999 - 2004 it was fine"},
+		{"street address", "I live at 221B Baker Street now", "I live at [address] now"},
+		{"po box", "send it to PO Box 1234", "send it to [address]"},
+		{"introduced name", "Hi, my name is Sam Smith and I'm sad", "Hi, my name is [name] and I'm sad"},
+		{"pronoun is not a name", "my name is I don't know", "my name is I don't know"},
+		{"title", "Dr. Jones said to rest", "[name] said to rest"},
+		{"relation", "my sister Anna won't talk to me", "my sister [name] won't talk to me"},
+		{"several kinds", "I'm called Max, mail max@example.com", "I'm called [name], mail [email]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := RedactPII(tt.text)
+			if got != tt.want {
+				t.Errorf("RedactPII(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if (got == tt.text) != (len(matches) == 0) {
+				t.Errorf("got %d matches for %q", len(matches), tt.text)
+			}
+		})
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, true},
+		{"0", 0, true},
+		{"90d", 90 * 24 * time.Hour, true},
+		{"12w", 12 * 7 * 24 * time.Hour, true},
+		{"720h", 720 * time.Hour, true},
+		{" 30d ", 30 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"-5d", 0, false},
+		{"-1h", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.value)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseRetention(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParseRetention(%q) = %v, want an error", tt.value, got)
+		}
+	}
+}
+
+func TestPrivateJournal(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  PrivacyPolicy
+		query   string
+		comment string
+	}{
+		{"as typed", PrivacyPolicy{Retention: time.Hour}, "my name is Sam", "ask sam@example.com"},
+		{"redacted", PrivacyPolicy{Redact: true}, "my name is [name]", "ask [email]"},
+		{"features only", PrivacyPolicy{Redact: true, FeaturesOnly: true}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journal := tt.policy.Journal(NewFileJournal(filepath.Join(t.TempDir(), "journal.jsonl")))
+			ratings := []JournalRating{{QuoteID: "q-1", Rating: RatingHelpful, Comment: "ask sam@example.com"}}
+			entry, err := journal.Log(JournalEntry{Query: "my name is Sam", Ratings: ratings})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ratings[0].Comment != "ask sam@example.com" {
+				t.Errorf("Log changed the caller's ratings to %q", ratings[0].Comment)
+			}
+			if err := journal.AddRating(entry.ID, JournalRating{QuoteID: "q-2", Rating: RatingHelpful, Comment: "ask sam@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := journal.Entries(time.Time{})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("got %d entries and error %v, want 1 entry", len(entries), err)
+			}
+			stored := entries[0]
+			if stored.Query != tt.query {
+				t.Errorf("stored query %q, want %q", stored.Query, tt.query)
+			}
+			if len(stored.Ratings) != 2 {
+				t.Fatalf("stored %d ratings, want 2", len(stored.Ratings))
+			}
+			for _, rating := range stored.Ratings {
+				if rating.Comment != tt.comment {
+					t.Errorf("stored comment %q, want %q", rating.Comment, tt.comment)
+				}
+			}
+		})
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old, recent := now.Add(-48*time.Hour), now.Add(-time.Hour)
+	quote := Quote{ID: "q-1", Text: "Just keep swimming."}
+
+	// Written without a policy, so nothing is purged yet
+	feedback := NewFileFeedbackStore(filepath.Join(dir, "feedback.jsonl"))
+	journal := NewFileJournal(filepath.Join(dir, "journal.jsonl"))
+	exposure := NewFileExposureStore(filepath.Join(dir, "exposure.json"))
+	for _, at := range []time.Time{old, recent} {
+		if err := feedback.Record(FeedbackEntry{Time: at, Quote: quote, Rating: RatingHelpful}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := journal.Log(JournalEntry{Time: at, Query: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := exposure.RecordShown("", []string{quote.ID}, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := PrivacyPolicy{Retention: 24 * time.Hour}
+	stores := policy.Stores(UserStores{Feedback: feedback, Journal: journal, Exposure: exposure})
+
+	// Reads leave expired data out
+	entries, err := stores.Feedback.List()
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %d feedback entries and error %v, want 1", len(entries), err)
+	}
+	logged, err := stores.Journal.Entries(time.Time{})
+	if err != nil || len(logged) != 1 {
+		t.Errorf("got %d journal entries and error %v, want 1", len(logged), err)
+	}
+	shown, err := stores.Exposure.Shown("")
+	if err != nil || len(shown[quote.ID]) != 1 {
+		t.Errorf("got showings %v and error %v, want 1", shown, err)
+	}
+
+	// and the files have been purged underneath
+	if entries, _ := feedback.List(); len(entries) != 1 {
+		t.Errorf("the feedback file holds %d entries, want 1", len(entries))
+	}
+	if logged, _ := journal.Entries(time.Time{}); len(logged) != 1 {
+		t.Errorf("the journal file holds %d entries, want 1", len(logged))
+	}
+	if shown, _ := exposure.Shown(""); len(shown[quote.ID]) != 1 {
+		t.Errorf("the exposure file holds %v, want 1 showing", shown)
+	}
+
+	// A sweep drops what is left once it expires
+	n, err := stores.Purge(now)
+	if err != nil || n != 3 {
+		t.Errorf("purged %d records and error %v, want 3", n, err)
+	}
+}
+
+func TestAutoPurge(t *testing.T) {
+	purged := make(chan time.Time, 1)
+	stop := autoPurge(func(before time.Time) (int, error) {
+		purged <- before
+		return 0, nil
+	}, 24*time.Hour, func(int, error) {})
+	defer stop()
+
+	select {
+	case before := <-purged:
+		if age := time.Since(before); age < 24*time.Hour || age > 25*time.Hour {
+			t.Errorf("purged data from before %v ago, want 24h", age)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing was purged when the sweep started")
+	}
+}
+
+func TestPrivacyPurgeAllUsers(t *testing.T) {
+	dir := t.TempDir()
+	old, recent := time.Now().Add(-48*time.Hour), time.Now()
+	stores, err := NewUserData(dir, DefaultUserFiles).Stores("sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []time.Time{old, recent} {
+		if err := stores.Exposure.RecordShown("sam", []string{"q-1"}, at); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stores.Journal.Log(JournalEntry{Time: at, Query: "keep going"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if code := runCommand([]string{"privacy", "purge", "--retention", "24h", "--all-users", "--users", dir}); code != exitOK {
+		t.Fatalf("got exit code %d", code)
+	}
+	shown, err := NewFileExposureStore(filepath.Join(dir, "sam", "exposure.json")).Shown("sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(shown["q-1"]); n != 1 {
+		t.Errorf("got %d showings left in users/sam/exposure.json, want 1", n)
+	}
+	entries, err := stores.Journal.Entries(time.Time{})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %d journal entries and error %v, want 1 entry", len(entries), err)
+	}
+}
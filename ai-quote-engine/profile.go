@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultProfileStrength is how far a profile can move a score, as a share
+const DefaultProfileStrength = 0.25
+
+// profilePrior is how many signals of no opinion every preference starts
+// with, so one favorite reads as a leaning rather than a certainty
+const profilePrior = 2.0
+
+// Preference is how much a user likes one genre, movie or tone
+type Preference struct {
+	Score   float64 `json:"score"`   // from -1 (avoid) to 1 (favor)
+	Signals int     `json:"signals"` // favorites and ratings it was learned from
+}
+
+// PreferenceProfile is what a user tends to like, learned from the quotes
+// they saved and how they rated results. Searches given a profile boost
+// quotes from the genres, movies and tones the user favors and hold back
+// those they rated down. The nil profile leaves scores unchanged.
+type PreferenceProfile struct {
+	Genres  map[string]Preference `json:"genres"`
+	Movies  map[string]Preference `json:"movies"`
+	Tones   map[string]Preference `json:"tones"` // "action" or "reflective"
+	Signals int                   `json:"signals"`
+
+	strength float64
+}
+
+// ProfileBoost shows how a profile moved one quote's score
+type ProfileBoost struct {
+	Genre  float64 `json:"genre"` // preference for the quote's genres, -1 to 1
+	Movie  float64 `json:"movie"`
+	Tone   float64 `json:"tone"`
+	Factor float64 `json:"factor"` // the score multiplier
+}
+
+// signalWeights are what each kind of signal says about the quote's traits
+var signalWeights = map[string]float64{
+	"favorite":          1,
+	RatingHelpful:       1,
+	RatingNotHelpful:    -1,
+	RatingInappropriate: -1,
+}
+
+// LearnPreferenceProfile builds a profile from saved quotes and feedback. A
+// quote saved to several collections counts once. The analyzer reads each
+// quote's tone; strength is the most a profile can move a score, from 0 to 1.
+func LearnPreferenceProfile(analyzer QueryAnalyzer, saved []Quote, feedback []FeedbackEntry, strength float64) *PreferenceProfile {
+	type tally struct {
+		sum float64
+		n   int
+	}
+	genres, movies, tones := map[string]*tally{}, map[string]*tally{}, map[string]*tally{}
+	add := func(tallies map[string]*tally, key string, weight float64) {
+		if key == "" {
+			return
+		}
+		t := tallies[key]
+		if t == nil {
+			t = &tally{}
+			tallies[key] = t
+		}
+		t.sum += weight
+		t.n++
+	}
+
+	profile := &PreferenceProfile{strength: strength}
+	learn := func(quote Quote, weight float64) {
+		profile.Signals++
+		for _, genre := range quote.Genres {
+			add(genres, strings.ToLower(genre), weight)
+		}
+		add(movies, strings.TrimSpace(quote.Movie), weight)
+		for _, tone := range quoteTones(analyzer.AnalyzeQuery(quote.Text)) {
+			add(tones, tone, weight)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, quote := range saved {
+		if ref := quoteRef(quote); !seen[ref] {
+			seen[ref] = true
+			learn(quote, signalWeights["favorite"])
+		}
+	}
+	for _, entry := range feedback {
+		if weight, ok := signalWeights[entry.Rating]; ok {
+			learn(entry.Quote, weight)
+		}
+	}
+
+	preferences := func(tallies map[string]*tally) map[string]Preference {
+		prefs := make(map[string]Preference, len(tallies))
+		for key, t := range tallies {
+			prefs[key] = Preference{Score: t.sum / (float64(t.n) + profilePrior), Signals: t.n}
+		}
+		return prefs
+	}
+	profile.Genres = preferences(genres)
+	profile.Movies = preferences(movies)
+	profile.Tones = preferences(tones)
+	return profile
+}
+
+// quoteTones lists the tones whose words appear in a quote
+func quoteTones(features map[string]float64) []string {
+	var tones []string
+	for _, tone := range []string{"action", "reflective"} {
+		if features["tone:"+tone] > 0 {
+			tones = append(tones, tone)
+		}
+	}
+	return tones
+}
+
+// meanPreference averages the preferences for keys; keys the profile has
+// no opinion on count as 0
+func meanPreference(prefs map[string]Preference, keys []string) float64 {
+	if len(keys) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, key := range keys {
+		total += prefs[key].Score
+	}
+	return total / float64(len(keys))
+}
+
+// Boost is how the profile rates a quote with the given features
+func (p *PreferenceProfile) Boost(quote Quote, features map[string]float64) ProfileBoost {
+	if p == nil {
+		return ProfileBoost{Factor: 1}
+	}
+	genres := make([]string, len(quote.Genres))
+	for i, genre := range quote.Genres {
+		genres[i] = strings.ToLower(genre)
+	}
+	boost := ProfileBoost{
+		Genre: meanPreference(p.Genres, genres),
+		Movie: p.Movies[strings.TrimSpace(quote.Movie)].Score,
+		Tone:  meanPreference(p.Tones, quoteTones(features)),
+	}
+	lean := math.Max(-1, math.Min(1, boost.Genre+boost.Movie+boost.Tone))
+	boost.Factor = 1 + p.strength*lean
+	return boost
+}
+
+// ProfileBuilder learns a user's profile from their favorites and feedback,
+// afresh for every search so new ratings count right away
+type ProfileBuilder struct {
+	analyzer  QueryAnalyzer
+	favorites FavoritesStore
+	feedback  FeedbackStore
+	strength  float64
+}
+
+// NewProfileBuilder learns from either store, or both; nil stores are skipped
+func NewProfileBuilder(analyzer QueryAnalyzer, favorites FavoritesStore, feedback FeedbackStore, strength float64) *ProfileBuilder {
+	return &ProfileBuilder{analyzer: analyzer, favorites: favorites, feedback: feedback, strength: strength}
+}
+
+// Profile reads the stores and learns the profile from them
+func (b *ProfileBuilder) Profile() (*PreferenceProfile, error) {
+	var saved []Quote
+	if collections, ok := b.favorites.(CollectionStore); ok {
+		summaries, err := collections.Collections()
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			quotes, err := collections.Collection(summary.Name)
+			if err != nil {
+				return nil, err
+			}
+			saved = append(saved, quotes...)
+		}
+	} else if b.favorites != nil {
+		var err error
+		if saved, err = b.favorites.List(); err != nil {
+			return nil, err
+		}
+	}
+
+	var feedback []FeedbackEntry
+	if b.feedback != nil {
+		var err error
+		if feedback, err = b.feedback.List(); err != nil {
+			return nil, err
+		}
+	}
+	return LearnPreferenceProfile(b.analyzer, saved, feedback, b.strength), nil
+}
+
+// rankStages are the optional steps after calculateSimilarity that fit a
+// score to the user searching. The zero value leaves scores unchanged.
+type rankStages struct {
+	novelty *Novelty
+	profile *PreferenceProfile
+}
+
+// apply adjusts a quote's similarity score
+func (r rankStages) apply(quote Quote, features map[string]float64, score float64) float64 {
+	// Quotes seen lately give way to fresh ones of similar fit
+	score *= r.novelty.Factor(quote)
+	score *= r.profile.Boost(quote, features).Factor
+	return math.Min(score, 1)
+}
+
+// explain adds each stage to an explanation of the similarity score, given
+// the features of the explained quote
+func (r rankStages) explain(e *MatchExplanation, features map[string]float64) {
+	if r.novelty != nil {
+		e.Exposure = r.novelty.Exposure(e.Quote)
+		e.NoveltyPenalty = r.novelty.Factor(e.Quote)
+	}
+	if r.profile != nil {
+		boost := r.profile.Boost(e.Quote, features)
+		e.Profile = &boost
+	}
+	e.Score = r.apply(e.Quote, features, e.Score)
+}
+
+// explainWith is explain for an explanation from ExplainMatch, reading the
+// quote's features with the service that explained it
+func (r rankStages) explainWith(service QuoteService, e *MatchExplanation) {
+	var features map[string]float64
+	if analyzer, ok := service.(QueryAnalyzer); ok {
+		features = analyzer.AnalyzeQuery(e.Quote.Text)
+	}
+	r.explain(e, features)
+}
+
+// Print lists what the profile favors and avoids, strongest first
+func (p *PreferenceProfile) Print(w io.Writer) {
+	if p.Signals == 0 {
+		fmt.Fprintln(w, "No favorites or ratings yet, so there are no preferences to learn from.")
+		return
+	}
+	fmt.Fprintf(w, "Learned from %d favorites and ratings\n", p.Signals)
+	for _, group := range []struct {
+		title string
+		prefs map[string]Preference
+	}{{"Genres", p.Genres}, {"Movies", p.Movies}, {"Tones", p.Tones}} {
+		if len(group.prefs) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(group.prefs))
+		for name := range group.prefs {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			a, b := group.prefs[names[i]].Score, group.prefs[names[j]].Score
+			if a != b {
+				return a > b
+			}
+			return names[i] < names[j]
+		})
+		fmt.Fprintf(w, "\n%s:\n", group.title)
+		for _, name := range names {
+			pref := group.prefs[name]
+			fmt.Fprintf(w, "   %+.2f  %s (%d signal(s))\n", pref.Score, name, pref.Signals)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// toneAnalyzer reads an action tone in quotes about fighting and a
+// reflective one in the rest
+type toneAnalyzer struct{}
+
+func (toneAnalyzer) AnalyzeQuery(text string) map[string]float64 {
+	if strings.Contains(text, "fight") {
+		return map[string]float64{"tone:action": 1}
+	}
+	return map[string]float64{"tone:reflective": 1}
+}
+
+func TestLearnPreferenceProfile(t *testing.T) {
+	rocky := Quote{ID: "q-1", Text: "Keep fighting.", Movie: "Rocky", Genres: []string{"Drama", "Sports"}}
+	nemo := Quote{ID: "q-2", Text: "Just keep swimming.", Movie: "Finding Nemo", Genres: []string{"family"}}
+	rated := func(quote Quote, rating string) FeedbackEntry {
+		return FeedbackEntry{Quote: quote, Rating: rating}
+	}
+
+	tests := []struct {
+		name     string
+		saved    []Quote
+		feedback []FeedbackEntry
+		signals  int
+		genres   map[string]float64
+		movies   map[string]float64
+		tones    map[string]float64
+	}{
+		{
+			name:   "nothing to learn from",
+			genres: map[string]float64{},
+			movies: map[string]float64{},
+			tones:  map[string]float64{},
+		},
+		{
+			// One signal against the prior of two: 1 / (1 + 2)
+			name:    "one favorite",
+			saved:   []Quote{rocky},
+			signals: 1,
+			genres:  map[string]float64{"drama": 1.0 / 3, "sports": 1.0 / 3},
+			movies:  map[string]float64{"Rocky": 1.0 / 3},
+			tones:   map[string]float64{"action": 1.0 / 3},
+		},
+		{
+			name:    "a favorite saved to two collections counts once",
+			saved:   []Quote{rocky, rocky},
+			signals: 1,
+			genres:  map[string]float64{"drama": 1.0 / 3, "sports": 1.0 / 3},
+			movies:  map[string]float64{"Rocky": 1.0 / 3},
+			tones:   map[string]float64{"action": 1.0 / 3},
+		},
+		{
+			name:     "helpful ratings add up",
+			feedback: []FeedbackEntry{rated(nemo, RatingHelpful), rated(nemo, RatingHelpful)},
+			signals:  2,
+			genres:   map[string]float64{"family": 2.0 / 4},
+			movies:   map[string]float64{"Finding Nemo": 2.0 / 4},
+			tones:    map[string]float64{"reflective": 2.0 / 4},
+		},
+		{
+			name:     "ratings down count against",
+			feedback: []FeedbackEntry{rated(rocky, RatingNotHelpful), rated(rocky, RatingInappropriate)},
+			signals:  2,
+			genres:   map[string]float64{"drama": -2.0 / 4, "sports": -2.0 / 4},
+			movies:   map[string]float64{"Rocky": -2.0 / 4},
+			tones:    map[string]float64{"action": -2.0 / 4},
+		},
+		{
+			name:     "a favorite rated down cancels out and unknown ratings are skipped",
+			saved:    []Quote{rocky},
+			feedback: []FeedbackEntry{rated(rocky, RatingNotHelpful), rated(nemo, "meh")},
+			signals:  2,
+			genres:   map[string]float64{"drama": 0, "sports": 0},
+			movies:   map[string]float64{"Rocky": 0},
+			tones:    map[string]float64{"action": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := LearnPreferenceProfile(toneAnalyzer{}, tt.saved, tt.feedback, DefaultProfileStrength)
+			if profile.Signals != tt.signals {
+				t.Errorf("got %d signals, want %d", profile.Signals, tt.signals)
+			}
+			for _, group := range []struct {
+				name string
+				got  map[string]Preference
+				want map[string]float64
+			}{{"genre", profile.Genres, tt.genres}, {"movie", profile.Movies, tt.movies}, {"tone", profile.Tones, tt.tones}} {
+				if len(group.got) != len(group.want) {
+					t.Errorf("got %s preferences %v, want %v", group.name, group.got, group.want)
+					continue
+				}
+				for key, want := range group.want {
+					if got := group.got[key].Score; math.Abs(got-want) > 1e-9 {
+						t.Errorf("%s %s: got score %.3f, want %.3f", group.name, key, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPreferenceProfileBoost(t *testing.T) {
+	quote := Quote{ID: "q-1", Text: "Keep fighting.", Movie: "Rocky", Genres: []string{"Drama", "Sports"}}
+	action := map[string]float64{"tone:action": 1}
+	profile := func(genre, movie, tone float64) *PreferenceProfile {
+		return &PreferenceProfile{
+			Genres:   map[string]Preference{"drama": {Score: genre}},
+			Movies:   map[string]Preference{"Rocky": {Score: movie}},
+			Tones:    map[string]Preference{"action": {Score: tone}},
+			strength: 0.25,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		profile  *PreferenceProfile
+		features map[string]float64
+		genre    float64 // sports has no preference, so drama's is halved
+		factor   float64
+	}{
+		{"nil profile", nil, action, 0, 1},
+		{"no opinion", profile(0, 0, 0), action, 0, 1},
+		{"leaning for", profile(0.4, 0.2, 0.1), action, 0.2, 1 + 0.25*0.5},
+		{"leaning against", profile(-0.4, -0.2, 0), action, -0.2, 1 - 0.25*0.4},
+		{"lean clamped at 1", profile(1, 1, 1), action, 0.5, 1.25},
+		{"lean clamped at -1", profile(-1, -1, -1), action, -0.5, 0.75},
+		{"tone not in the quote", profile(0, 0, 1), map[string]float64{"tone:reflective": 1}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			boost := tt.profile.Boost(quote, tt.features)
+			if math.Abs(boost.Genre-tt.genre) > 1e-9 {
+				t.Errorf("got genre preference %.3f, want %.3f", boost.Genre, tt.genre)
+			}
+			if math.Abs(boost.Factor-tt.factor) > 1e-9 {
+				t.Errorf("got factor %.3f, want %.3f", boost.Factor, tt.factor)
+			}
+			if boost.Factor < 0.75 || boost.Factor > 1.25 {
+				t.Errorf("factor %.3f is outside 1±strength", boost.Factor)
+			}
+		})
+	}
+}
+
+func TestRankStagesApply(t *testing.T) {
+	quote := Quote{ID: "q-1", Text: "Keep fighting.", Movie: "Rocky"}
+	favored := &PreferenceProfile{Movies: map[string]Preference{"Rocky": {Score: 1}}, strength: 0.5}
+	seen := NewNovelty(map[string][]time.Time{"q-1": {time.Now()}}, time.Now(), NoveltyOptions{Penalty: 0.5, HalfLife: time.Hour})
+
+	tests := []struct {
+		name   string
+		stages rankStages
+		score  float64
+		want   float64
+	}{
+		{"no stages", rankStages{}, 0.6, 0.6},
+		{"profile", rankStages{profile: favored}, 0.6, 0.9},
+		{"capped at 1", rankStages{profile: favored}, 0.8, 1},
+		{"novelty", rankStages{novelty: seen}, 0.6, 0.45},
+		{"both", rankStages{novelty: seen, profile: favored}, 0.6, 0.675},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stages.apply(quote, nil, tt.score); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyReload does nothing where there is no SIGHUP; use --watch or the
+// reload endpoint instead
+func notifyReload(c chan<- os.Signal) {}
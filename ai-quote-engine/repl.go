@@ -0,0 +1,491 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reloader is implemented by services that can re-read their data
+type Reloader interface {
+	Reload() error
+}
+
+// sessionCommand is a colon-prefixed command available in interactive mode
+type sessionCommand struct {
+	name  string
+	args  string
+	help  string
+	run   func(c *CLI, args []string) error
+	quits bool
+}
+
+var sessionCommands []sessionCommand
+
+func init() {
+	sessionCommands = []sessionCommand{
+		{name: "more", help: "show the next page of results", run: (*CLI).cmdMore},
+		{name: "why", args: "N", help: "explain why result N matched", run: (*CLI).cmdWhy},
+		{name: "save", args: "N [LIST]", help: "save result N to your favorites, or to the named collection", run: (*CLI).cmdSave},
+		{name: "unsave", args: "ID [LIST]", help: "remove a quote (by id or list number) from your favorites or a collection", run: (*CLI).cmdUnsave},
+		{name: "favorites", args: "[LIST]", help: "list your saved quotes, or the named collection", run: (*CLI).cmdFavorites},
+		{name: "collections", help: "list your collections", run: (*CLI).cmdCollections},
+		{name: "export", args: "FILE [LIST]", help: "write your favorites or a collection as a quotes file (.json or .csv)", run: (*CLI).cmdExport},
+		{name: "rate", args: "N RATING", help: "rate result N helpful, not-helpful or inappropriate, then an optional comment", run: (*CLI).cmdRate},
+		{name: "history", help: "list the queries from this session", run: (*CLI).cmdHistory},
+		{name: "journal", args: "[PERIOD]", help: "show your mood journal for the last week, or e.g. 30d or since 2026-10-01", run: (*CLI).cmdJournal},
+		{name: "top", args: "N", help: "show N results per page", run: (*CLI).cmdTop},
+		{name: "min-score", args: "X", help: "hide results scoring below X (0-1)", run: (*CLI).cmdMinScore},
+		{name: "context", args: "[on|off]", help: "show or toggle conversation context", run: (*CLI).cmdContext},
+		{name: "reset", help: "forget earlier messages and shown quotes", run: (*CLI).cmdReset},
+		{name: "reload", help: "reload the quotes and lexicon files", run: (*CLI).cmdReload},
+		{name: "help", help: "show this list", run: (*CLI).cmdHelp},
+		{name: "quit", help: "leave interactive mode", quits: true},
+	}
+}
+
+// handleCommand runs a REPL command line and reports whether to quit
+func (c *CLI) handleCommand(line string) bool {
+	fields := strings.Fields(strings.TrimPrefix(line, ":"))
+	if len(fields) == 0 {
+		fmt.Println("Type :help for the list of commands.")
+		return false
+	}
+
+	name := strings.ToLower(fields[0])
+	for _, cmd := range sessionCommands {
+		if cmd.name != name {
+			continue
+		}
+		if cmd.quits {
+			return true
+		}
+		if err := cmd.run(c, fields[1:]); err != nil {
+			fmt.Printf("\n❌ %s\n", err.Error())
+		}
+		return false
+	}
+
+	fmt.Printf("\n❌ Unknown command :%s. Type :help for the list of commands.\n", name)
+	return false
+}
+
+// resultArg parses a 1-based result number from the command arguments
+func (c *CLI) resultArg(args []string) (SearchResult, error) {
+	if len(c.shown) == 0 {
+		return SearchResult{}, fmt.Errorf("no results yet - describe how you feel first")
+	}
+	if len(args) != 1 {
+		return SearchResult{}, fmt.Errorf("expected a result number between 1 and %d", len(c.shown))
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(c.shown) {
+		return SearchResult{}, fmt.Errorf("expected a result number between 1 and %d", len(c.shown))
+	}
+	return c.shown[n-1], nil
+}
+
+func (c *CLI) cmdMore(args []string) error {
+	if c.lastQuery == "" {
+		return fmt.Errorf("no search yet - describe how you feel first")
+	}
+	c.displayNextPage()
+	return nil
+}
+
+func (c *CLI) cmdWhy(args []string) error {
+	result, err := c.resultArg(args)
+	if err != nil {
+		return err
+	}
+
+	explainer, ok := c.service.(QuoteExplainer)
+	if !ok {
+		return fmt.Errorf("explanations are not available for this search engine")
+	}
+	explanation, err := explainer.ExplainMatch(c.lastQuery, result.Quote)
+	if err != nil {
+		return err
+	}
+	c.stages().explainWith(c.service, explanation)
+
+	fmt.Printf("\n\"%s\" scored %.2f\n", result.Quote.Text, explanation.Score)
+	if summary := metadataSummary(result.Quote); summary != "" {
+		fmt.Printf("   %s (%s)\n", result.Quote.Movie, summary)
+	}
+	if result.Quote.Context != "" {
+		fmt.Printf("   Scene: %s\n", result.Quote.Context)
+	}
+	if c.conversation != nil && c.conversation.Turns() > 1 {
+		fmt.Println("   (explained against your last message alone, without earlier context)")
+	}
+	fmt.Println()
+	if len(explanation.Shared) == 0 {
+		fmt.Println("   No emotions or themes in common with your description.")
+	} else {
+		fmt.Println("   What your description and the quote share:")
+		for _, shared := range explanation.Shared {
+			fmt.Printf("   • %-24s +%.2f\n", shared.Feature, shared.Contribution)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("   Feature similarity:  %.2f\n", explanation.Cosine)
+	fmt.Printf("   Sentiment:           you %s, quote %s (×%.1f)\n",
+		explanation.QuerySentiment, explanation.QuoteSentiment, explanation.SentimentPenalty)
+	if explanation.TonePenalty != 1 {
+		fmt.Printf("   Tone mismatch:       ×%.1f\n", explanation.TonePenalty)
+	}
+	if shown := c.novelty.Shown(result.Quote); shown > 0 {
+		fmt.Printf("   Seen before:         %d time(s), lately counting %.2f (×%.2f)\n", shown, explanation.Exposure, explanation.NoveltyPenalty)
+	}
+	if boost := explanation.Profile; boost != nil && boost.Factor != 1 {
+		fmt.Printf("   Your preferences:    genre %+.2f, movie %+.2f, tone %+.2f (×%.2f)\n", boost.Genre, boost.Movie, boost.Tone, boost.Factor)
+	}
+	return nil
+}
+
+func (c *CLI) cmdSave(args []string) error {
+	if c.favorites == nil {
+		return fmt.Errorf("favorites are not available in this session")
+	}
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("expected :save N [collection]")
+	}
+	result, err := c.resultArg(args[:1])
+	if err != nil {
+		return err
+	}
+
+	name := FavoritesCollection
+	var added bool
+	if len(args) == 2 {
+		collections, err := c.collections()
+		if err != nil {
+			return err
+		}
+		if name, err = NormalizeCollectionName(args[1]); err != nil {
+			return err
+		}
+		added, err = collections.AddToCollection(name, result.Quote)
+		if err != nil {
+			return err
+		}
+	} else if added, err = c.favorites.Add(result.Quote); err != nil {
+		return err
+	}
+
+	if added {
+		fmt.Printf("\n⭐ Saved \"%s\" to %s\n", result.Quote.Text, name)
+	} else {
+		fmt.Printf("\n⭐ \"%s\" is already in %s\n", result.Quote.Text, name)
+	}
+	return nil
+}
+
+// collections is the favorites store, if it also keeps named collections
+func (c *CLI) collections() (CollectionStore, error) {
+	collections, ok := c.favorites.(CollectionStore)
+	if !ok {
+		return nil, fmt.Errorf("collections are not available in this session")
+	}
+	return collections, nil
+}
+
+// collectionArg reads an optional collection name, defaulting to the favorites
+func collectionArg(args []string, at int) string {
+	if len(args) > at {
+		return args[at]
+	}
+	return FavoritesCollection
+}
+
+func (c *CLI) cmdFavorites(args []string) error {
+	if c.favorites == nil {
+		return fmt.Errorf("favorites are not available in this session")
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("expected :favorites [collection]")
+	}
+
+	name := collectionArg(args, 0)
+	var quotes []Quote
+	var err error
+	if len(args) == 1 {
+		collections, err := c.collections()
+		if err != nil {
+			return err
+		}
+		if name, err = NormalizeCollectionName(name); err != nil {
+			return err
+		}
+		quotes, err = collections.Collection(name)
+		if err != nil {
+			return err
+		}
+	} else if quotes, err = c.favorites.List(); err != nil {
+		return err
+	}
+	if len(quotes) == 0 {
+		fmt.Println("\nNo favorites yet. Use :save N to keep a quote.")
+		return nil
+	}
+
+	if name == FavoritesCollection {
+		fmt.Println("\n⭐ Your favorites:")
+	} else {
+		fmt.Printf("\n⭐ %s:\n", name)
+	}
+	for i, quote := range quotes {
+		fmt.Printf("%d. \"%s\"\n", i+1, quote.Text)
+		fmt.Printf("   — %s (%s) · %s\n", quote.Character, quote.Movie, quote.ID)
+	}
+	return nil
+}
+
+// cmdUnsave removes a quote, given by ID or by its number in the collection
+func (c *CLI) cmdUnsave(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("expected :unsave ID|N [collection]")
+	}
+	collections, err := c.collections()
+	if err != nil {
+		return err
+	}
+	name, err := NormalizeCollectionName(collectionArg(args, 1))
+	if err != nil {
+		return err
+	}
+
+	id := args[0]
+	if n, err := strconv.Atoi(id); err == nil {
+		quotes, err := collections.Collection(name)
+		if err != nil {
+			return err
+		}
+		if n < 1 || n > len(quotes) {
+			return fmt.Errorf("expected a number between 1 and %d, or a quote id", len(quotes))
+		}
+		id = quoteRef(quotes[n-1])
+	}
+	if err := collections.RemoveFromCollection(name, id); err != nil {
+		return err
+	}
+	fmt.Printf("\nRemoved %s from %s\n", id, name)
+	return nil
+}
+
+func (c *CLI) cmdCollections(args []string) error {
+	collections, err := c.collections()
+	if err != nil {
+		return err
+	}
+	summaries, err := collections.Collections()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nYour collections:")
+	for _, summary := range summaries {
+		fmt.Printf("   %-20s %d quote(s)\n", summary.Name, summary.Quotes)
+	}
+	return nil
+}
+
+// cmdExport writes a collection as a quotes file, CSV if the name ends in .csv
+func (c *CLI) cmdExport(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("expected :export FILE [collection]")
+	}
+	collections, err := c.collections()
+	if err != nil {
+		return err
+	}
+	name, err := NormalizeCollectionName(collectionArg(args, 1))
+	if err != nil {
+		return err
+	}
+	quotes, err := collections.Collection(name)
+	if err != nil {
+		return err
+	}
+
+	format := "json"
+	if strings.EqualFold(filepath.Ext(args[0]), ".csv") {
+		format = "csv"
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := ExportCollection(file, quotes, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	fmt.Printf("\nExported %d quote(s) from %s to %s\n", len(quotes), name, args[0])
+	return nil
+}
+
+func (c *CLI) cmdRate(args []string) error {
+	if c.feedback == nil {
+		return fmt.Errorf("feedback is not available in this session")
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("expected :rate N helpful|not-helpful|inappropriate [comment]")
+	}
+	result, err := c.resultArg(args[:1])
+	if err != nil {
+		return err
+	}
+	rating, err := ParseFeedbackRating(args[1])
+	if err != nil {
+		return err
+	}
+
+	entry := newFeedbackEntry(c.service, c.lastQuery, result.Quote, rating, strings.Join(args[2:], " "))
+	if err := c.feedback.Record(entry); err != nil {
+		return err
+	}
+	if c.journal != nil && c.journalEntry != "" {
+		err := c.journal.AddRating(c.journalEntry, JournalRating{
+			Time: entry.Time, QuoteID: result.Quote.ID, Rating: rating, Comment: entry.Comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if rating == RatingInappropriate {
+		fmt.Printf("\n⚑ Flagged \"%s\" for review. Thank you.\n", result.Quote.Text)
+	} else {
+		fmt.Println("\n🙏 Thanks for the feedback.")
+	}
+	return nil
+}
+
+func (c *CLI) cmdHistory(args []string) error {
+	if len(c.history) == 0 {
+		fmt.Println("\nNo queries yet in this session.")
+		return nil
+	}
+	fmt.Println("\nThis session:")
+	for i, query := range c.history {
+		fmt.Printf("%d. %s\n", i+1, query)
+	}
+	return nil
+}
+
+func (c *CLI) cmdJournal(args []string) error {
+	if c.journal == nil {
+		return fmt.Errorf("the journal is off; start with --journal FILE to keep one")
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("expected :journal [period]")
+	}
+	period := "7d"
+	if len(args) == 1 {
+		period = args[0]
+	}
+	since, err := ParseSince(period, time.Now())
+	if err != nil {
+		return err
+	}
+	entries, err := c.journal.Entries(since)
+	if err != nil {
+		return err
+	}
+	PrintJournalTimeline(os.Stdout, entries)
+	return nil
+}
+
+func (c *CLI) cmdTop(args []string) error {
+	if len(args) != 1 {
+		fmt.Printf("\nShowing %d results per page.\n", c.topN)
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a positive number of results")
+	}
+	c.topN = n
+	fmt.Printf("\nShowing %d results per page.\n", c.topN)
+	return nil
+}
+
+func (c *CLI) cmdMinScore(args []string) error {
+	if len(args) != 1 {
+		fmt.Printf("\nHiding results scoring below %.2f.\n", c.minScore)
+		return nil
+	}
+	x, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || x < 0 || x > 1 {
+		return fmt.Errorf("expected a score between 0 and 1")
+	}
+	c.minScore = x
+	fmt.Printf("\nHiding results scoring below %.2f.\n", c.minScore)
+	return nil
+}
+
+func (c *CLI) cmdReload(args []string) error {
+	reloader, ok := c.service.(Reloader)
+	if !ok {
+		return fmt.Errorf("this search engine cannot be reloaded")
+	}
+	if err := reloader.Reload(); err != nil {
+		return fmt.Errorf("reload failed, keeping the current quotes: %w", err)
+	}
+	// Earlier result numbers may no longer match the reloaded corpus
+	c.shown = nil
+	fmt.Println("\n🔄 Quotes reloaded.")
+	return nil
+}
+
+func (c *CLI) cmdContext(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected :context, :context on or :context off")
+	}
+	if len(args) == 1 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			if c.conversation == nil {
+				if err := c.UseConversation(NewConversation(c.contextDecay)); err != nil {
+					return err
+				}
+			}
+		case "off":
+			c.UseConversation(nil)
+		default:
+			return fmt.Errorf("expected :context, :context on or :context off")
+		}
+	}
+
+	if c.conversation == nil {
+		fmt.Println("\nConversation context is off: each message is read on its own.")
+		return nil
+	}
+	fmt.Println("\nConversation context is on: earlier messages shape the next results.")
+	fmt.Printf("   Remembering %d message(s), %d quote(s) shown, decay %.2f per message.\n",
+		c.conversation.Turns(), c.conversation.ShownCount(), c.conversation.Decay())
+	return nil
+}
+
+func (c *CLI) cmdReset(args []string) error {
+	if c.conversation == nil {
+		return fmt.Errorf("conversation context is off - turn it on with :context on")
+	}
+	c.conversation.Reset()
+	fmt.Println("\n🧹 Fresh start: earlier messages and shown quotes are forgotten.")
+	return nil
+}
+
+func (c *CLI) cmdHelp(args []string) error {
+	fmt.Println("\nCommands:")
+	for _, cmd := range sessionCommands {
+		fmt.Printf("  %-14s %s\n", ":"+strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+	}
+	fmt.Println("\nAnything else is treated as a description of how you feel.")
+	return nil
+}
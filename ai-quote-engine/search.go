@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// cancelCheckInterval is how many quotes are scored between checks for a
+// cancelled search
+const cancelCheckInterval = 256
+
+// SearchRequest carries a query and every option that shapes its results
+type SearchRequest struct {
+	Query  string
+	TopN   int
+	Filter QuoteFilter
+
+	// Explain attaches a score breakdown to each result
+	Explain bool
+
+	// Locale such as "en-US" limits results to its language when Filter
+	// does not name one
+	Locale string
+
+	// Conversation blends the query with earlier turns and skips quotes
+	// already shown; nil searches the query on its own
+	Conversation *Conversation
+
+	// Novelty lowers the scores of quotes the user has seen lately, and
+	// Profile moves them by what the user tends to like; nil leaves either
+	// stage out
+	Novelty *Novelty
+	Profile *PreferenceProfile
+}
+
+// ContextSearcher is implemented by services whose searches can be cancelled
+// or given a deadline
+type ContextSearcher interface {
+	Search(ctx context.Context, req SearchRequest) ([]SearchResult, error)
+}
+
+// Search ranks the corpus for req and returns up to req.TopN results, which
+// must be at least 1. It stops early with ctx.Err() if the context is
+// cancelled or its deadline passes.
+func (s *SemanticQuoteService) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	snap := s.current()
+	if snap == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+	if req.TopN < 1 {
+		return nil, fmt.Errorf("number of results must be at least 1, got %d", req.TopN)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Crisis detection always looks at the message itself
+	if s.detectCrisis(req.Query) {
+		return nil, ErrCrisisDetected
+	}
+
+	filter := req.Filter
+	if filter.Language == "" && req.Locale != "" {
+		filter.Language = localeLanguage(req.Locale)
+	}
+
+	queryContext := s.analyzeText(snap.lexicon, req.Query)
+	var skip func(Quote) bool
+	if conv := req.Conversation; conv != nil {
+		conv.addTurn(req.Query, queryContext)
+		queryContext = conv.blend()
+		skip = conv.hasShown
+	}
+
+	stages := rankStages{novelty: req.Novelty, profile: req.Profile}
+	results, err := s.rank(ctx, snap, queryContext, req.TopN, filter, skip, stages)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Explain {
+		for i := range results {
+			entry := snap.index[snap.byID[results[i].Quote.ID]]
+			explanation := s.similarityBreakdown(queryContext, entry.Features)
+			explanation.Quote = entry.Quote
+			explanation.Compatible = true // incompatible quotes are never ranked
+			stages.explain(explanation, entry.Features)
+			results[i].Explanation = explanation
+		}
+	}
+	return results, nil
+}
+
+// localeLanguage reduces a locale like "en-US" or "pt_BR" to its language
+func localeLanguage(locale string) string {
+	language, _, _ := strings.Cut(strings.TrimSpace(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	return strings.ToLower(language)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var testQueries = []string{
+	"I just got rejected and feel like giving up",
+	"I need motivation to keep going when things are tough",
+	"feeling happy and grateful today",
+	"I'm scared of what comes next",
+}
+
+// testCorpus repeats the sample quotes until there are n, each with an ID of
+// its own so none are merged as duplicates
+func testCorpus(t testing.TB, n int) []Quote {
+	t.Helper()
+	data, err := NewFileQuoteRepository().LoadQuotes("quotes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotes := make([]Quote, n)
+	for i := range quotes {
+		quotes[i] = data.Quotes[i%len(data.Quotes)]
+		quotes[i].ID = fmt.Sprintf("%s-%d", quotes[i].ID, i)
+		quotes[i].Source = ""
+	}
+	return quotes
+}
+
+func writeTestQuotes(t testing.TB, filename string, quotes []Quote) {
+	t.Helper()
+	content, err := json.Marshal(map[string]any{"quotes": quotes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestService serves quotes from a file in a temporary folder, returning
+// the file so tests can rewrite it
+func newTestService(t testing.TB, quotes []Quote) (*SemanticQuoteService, string) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "quotes.json")
+	writeTestQuotes(t, filename, quotes)
+	service := NewSemanticQuoteService(NewFileQuoteRepositoryWithOptions(LoadOptions{KeepDuplicates: true}))
+	if err := service.Initialize(filename); err != nil {
+		t.Fatal(err)
+	}
+	return service, filename
+}
+
+// searchUntil runs searches from several goroutines until stop is closed,
+// failing the test on any error a reload should never cause
+func searchUntil(t *testing.T, service *SemanticQuoteService, stop <-chan struct{}) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				query := testQueries[i%len(testQueries)]
+				_, err := service.Search(context.Background(), SearchRequest{Query: query, TopN: 3})
+				if err != nil && !errors.Is(err, ErrNoMatches) {
+					t.Errorf("search %q: %v", query, err)
+					return
+				}
+			}
+		}(g)
+	}
+	return &wg
+}
+
+func TestSearchDuringReload(t *testing.T) {
+	quotes := testCorpus(t, 200)
+	service, filename := newTestService(t, quotes)
+
+	stop := make(chan struct{})
+	searches := searchUntil(t, service, stop)
+	for i := 0; i < 20; i++ {
+		writeTestQuotes(t, filename, quotes[:100+i*5])
+		if err := service.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		service.UseLexicon(NewEmotionalLexicon())
+	}
+	close(stop)
+	searches.Wait()
+
+	if got, want := len(service.Index()), 195; got != want {
+		t.Errorf("got %d quotes after the last reload, want %d", got, want)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+
+	tests := []struct {
+		name string
+		req  SearchRequest
+		ok   bool
+	}{
+		{"valid", SearchRequest{Query: testQueries[0], TopN: 3}, true},
+		{"one result", SearchRequest{Query: testQueries[0], TopN: 1}, true},
+		{"more than the corpus", SearchRequest{Query: testQueries[0], TopN: 1000}, true},
+		{"empty query", SearchRequest{Query: "  ", TopN: 3}, false},
+		{"no results asked for", SearchRequest{Query: testQueries[0]}, false},
+		{"negative count", SearchRequest{Query: testQueries[0], TopN: -1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := service.Search(context.Background(), tt.req)
+			if tt.ok && (err != nil || len(results) == 0 || len(results) > tt.req.TopN) {
+				t.Fatalf("got %d results and error %v, want 1 to %d results", len(results), err, tt.req.TopN)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("got %d results, want an error", len(results))
+			}
+		})
+	}
+}
+
+func TestSearchCancelled(t *testing.T) {
+	quotes := testCorpus(t, 2*parallelMinQuotes)
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			service, _ := newTestService(t, quotes)
+			service.UseWorkers(workers)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var seen atomic.Int64
+			skip := func(Quote) bool {
+				// Cancel once scoring is under way
+				seen.Add(1)
+				cancel()
+				return false
+			}
+			query := service.analyzeText(service.activeLexicon(), testQueries[0])
+			_, err := service.rank(ctx, service.current(), query, 3, QuoteFilter{}, skip, rankStages{})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got error %v, want %v", err, context.Canceled)
+			}
+			if n := seen.Load(); n > int64(workers*cancelCheckInterval) {
+				t.Errorf("scored %d of %d quotes after cancelling", n, len(quotes))
+			}
+		})
+	}
+
+	service, _ := newTestService(t, quotes)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.Search(ctx, SearchRequest{Query: testQueries[0], TopN: 3}); !errors.Is(err, context.Canceled) {
+		t.Errorf("search with a cancelled context: got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFilterConfident(t *testing.T) {
+	scored := func(scores ...float64) []SearchResult {
+		results := make([]SearchResult, len(scores))
+		for i, score := range scores {
+			results[i] = SearchResult{Quote: Quote{ID: fmt.Sprintf("q-%d", i)}, Score: score}
+		}
+		return results
+	}
+
+	tests := []struct {
+		name     string
+		results  []SearchResult
+		minScore float64
+		want     int
+		best     float64 // best score reported when nothing is confident
+	}{
+		{"no results", nil, 0.2, 0, 0},
+		{"all above", scored(0.9, 0.5, 0.3), 0.2, 3, 0},
+		{"cut at the threshold", scored(0.9, 0.2, 0.1), 0.2, 2, 0},
+		{"no threshold", scored(0.1, 0.05), 0, 2, 0},
+		{"none confident", scored(0.15, 0.1), 0.2, 0, 0.15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := FilterConfident(tt.results, tt.minScore)
+			var lowConfidence *LowConfidenceError
+			if tt.best > 0 {
+				if !errors.As(err, &lowConfidence) || !errors.Is(err, ErrNoConfidentMatch) {
+					t.Fatalf("got error %v, want a LowConfidenceError", err)
+				}
+				if lowConfidence.BestScore != tt.best || lowConfidence.MinScore != tt.minScore {
+					t.Errorf("got best %.2f below %.2f, want %.2f below %.2f", lowConfidence.BestScore, lowConfidence.MinScore, tt.best, tt.minScore)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("got %d results, want %d", len(results), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1034 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP API - Presentation layer for programmatic access
+type Server struct {
+	service  QuoteService
+	topN     int
+	minScore float64
+	filter   QuoteFilter
+	timeout  time.Duration
+	editor   WritableQuoteRepository
+	feedback FeedbackStore
+	saved    CollectionStore
+	journal  Journal
+	exposure ExposureStore
+	novelty  NoveltyOptions
+	users    *UserData
+
+	personalize     bool
+	profileStrength float64
+}
+
+func NewServer(service QuoteService, topN int, minScore float64) *Server {
+	return &Server{service: service, topN: topN, minScore: minScore}
+}
+
+// UseFilter restricts every search. Requests may add their own filters, but
+// never a higher max rating than the server's.
+func (s *Server) UseFilter(filter QuoteFilter) error {
+	if _, ok := s.service.(FilteredSearcher); !ok && !filter.IsZero() {
+		return fmt.Errorf("filters are not available for this search engine")
+	}
+	s.filter = filter
+	return nil
+}
+
+// UseTimeout bounds how long one search may run; 0 means no limit. Searches
+// are also abandoned when the client disconnects.
+func (s *Server) UseTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// UseEditor enables adding, updating and deleting quotes through the API.
+// Each change is written to the repository and then reloaded into the
+// service, so it is searchable as soon as the response is sent.
+func (s *Server) UseEditor(editor WritableQuoteRepository) {
+	s.editor = editor
+}
+
+// UseFeedback enables POST /feedback and GET /feedback/report
+func (s *Server) UseFeedback(store FeedbackStore) {
+	s.feedback = store
+}
+
+// UseCollections enables the favorites and collections endpoints
+func (s *Server) UseCollections(store CollectionStore) {
+	s.saved = store
+}
+
+// UseJournal lets searches ask to be logged to the mood journal and enables
+// GET /journal
+func (s *Server) UseJournal(journal Journal) {
+	s.journal = journal
+}
+
+// UseExposure remembers the quotes shown to each user that searches with a
+// user ID, so their later searches favor quotes they have not seen lately
+func (s *Server) UseExposure(store ExposureStore, options NoveltyOptions) error {
+	if _, ok := s.service.(ContextSearcher); !ok {
+		return fmt.Errorf("novelty ranking is not available for this search engine")
+	}
+	s.exposure = store
+	s.novelty = options
+	return nil
+}
+
+// UseProfile personalizes every search with the preferences learned from
+// the favorites and feedback of the searching user, or of the server when it
+// does not keep users apart
+func (s *Server) UseProfile(strength float64) error {
+	_, searcher := s.service.(ContextSearcher)
+	_, analyzer := s.service.(QueryAnalyzer)
+	if !searcher || !analyzer {
+		return fmt.Errorf("personalized ranking is not available for this search engine")
+	}
+	s.personalize = true
+	s.profileStrength = strength
+	return nil
+}
+
+// UseUsers keeps each user's favorites, feedback, journal and seen quotes
+// apart. Requests that read or write them must then name their user, and
+// the stores given to UseFeedback and the like are no longer used. The
+// named user is trusted as given; authenticating clients is left to a proxy
+// in front of the server.
+func (s *Server) UseUsers(users *UserData) {
+	s.users = users
+}
+
+var errUserRequired = errors.New("this server keeps each user's data apart; name the user with the user parameter")
+
+// userStores are the stores of the user a request names, or the server's
+// own when it does not keep users apart. It responds on failure and returns
+// the user ID the stores are kept under.
+func (s *Server) userStores(w http.ResponseWriter, user string) (string, UserStores, bool) {
+	if s.users == nil {
+		return user, UserStores{Favorites: s.saved, Feedback: s.feedback, Journal: s.journal, Exposure: s.exposure}, true
+	}
+	if user == "" {
+		s.respondError(w, http.StatusBadRequest, errUserRequired)
+		return "", UserStores{}, false
+	}
+	user, err := NormalizeUserID(user)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return "", UserStores{}, false
+	}
+	stores, err := s.users.Stores(user)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return "", UserStores{}, false
+	}
+	return user, stores, true
+}
+
+// optionalUserStores is userStores for requests that also work for nobody
+// in particular: without a user they get no stores when users are kept apart
+func (s *Server) optionalUserStores(w http.ResponseWriter, user string) (string, UserStores, bool) {
+	if s.users != nil && user == "" {
+		return "", UserStores{}, true
+	}
+	return s.userStores(w, user)
+}
+
+// API request and response shapes
+type SearchAPIRequest struct {
+	Query     string   `json:"query"`
+	Top       int      `json:"top,omitempty"`
+	MinScore  *float64 `json:"min_score,omitempty"`
+	Genres    []string `json:"genres,omitempty"`
+	MaxRating string   `json:"max_rating,omitempty"`
+	Year      string   `json:"year,omitempty"` // "1995" or "1990-2010"
+	Language  string   `json:"language,omitempty"`
+	Locale    string   `json:"locale,omitempty"` // e.g. "en-US"; limits results to its language
+	Explain   bool     `json:"explain,omitempty"`
+	Journal   bool     `json:"journal,omitempty"` // log the query and results to the mood journal
+	User      string   `json:"user,omitempty"`    // whose journal, seen quotes and preferences to use
+}
+
+type QuoteResult struct {
+	ID          string            `json:"id"`
+	Text        string            `json:"text"`
+	Movie       string            `json:"movie"`
+	Character   string            `json:"character"`
+	Year        int               `json:"year,omitempty"`
+	Genres      []string          `json:"genres,omitempty"`
+	Rating      ContentRating     `json:"rating,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Context     string            `json:"context,omitempty"`
+	Score       float64           `json:"score"`
+	Explanation *MatchExplanation `json:"explanation,omitempty"`
+}
+
+type SearchResponse struct {
+	Query            string           `json:"query"`
+	Results          []QuoteResult    `json:"results"`
+	NoConfidentMatch bool             `json:"no_confident_match,omitempty"`
+	BestScore        float64          `json:"best_score,omitempty"`
+	Crisis           bool             `json:"crisis,omitempty"`
+	Resources        []CrisisResource `json:"resources,omitempty"`
+	JournalID        string           `json:"journal_id,omitempty"` // pass with feedback on these results
+}
+
+// FeedbackRequest rates a quote shown for a query
+type FeedbackRequest struct {
+	Query   string `json:"query"`
+	QuoteID string `json:"quote_id"`
+	Rating  string `json:"rating"` // helpful, not_helpful or inappropriate
+	Comment string `json:"comment,omitempty"`
+	User    string `json:"user,omitempty"` // whose feedback and journal it goes to
+
+	// The journal entry the quote was shown for, to keep the rating with it
+	JournalID string `json:"journal_id,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /search", s.handleSearchQuery)
+	mux.HandleFunc("POST /search", s.handleSearchBody)
+	mux.HandleFunc("GET /quotes", s.handleListQuotes)
+	mux.HandleFunc("POST /quotes", s.handleAddQuote)
+	mux.HandleFunc("GET /quotes/{id}", s.handleQuote)
+	mux.HandleFunc("PUT /quotes/{id}", s.handleUpdateQuote)
+	mux.HandleFunc("DELETE /quotes/{id}", s.handleDeleteQuote)
+	mux.HandleFunc("POST /reload", s.handleReload)
+	mux.HandleFunc("POST /feedback", s.handleFeedback)
+	mux.HandleFunc("GET /feedback/report", s.handleFeedbackReport)
+	mux.HandleFunc("GET /journal", s.handleJournal)
+	mux.HandleFunc("GET /journal/trends", s.handleTrends)
+	mux.HandleFunc("GET /daily", s.handleDaily)
+	mux.HandleFunc("GET /collections", s.handleCollections)
+	mux.HandleFunc("GET /collections/{name}", s.handleCollection)
+	mux.HandleFunc("GET /collections/{name}/export", s.handleExportCollection)
+	mux.HandleFunc("PUT /collections/{name}/{id}", s.handleSaveToCollection)
+	mux.HandleFunc("DELETE /collections/{name}/{id}", s.handleRemoveFromCollection)
+	return mux
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if sourced, ok := s.service.(interface{ Sources() []SourceStats }); ok {
+		for _, source := range sourced.Sources() {
+			log.Printf("Loaded %d quotes from %s (%s)", source.Quotes, source.Path, source.Format)
+		}
+	}
+	if linted, ok := s.service.(interface{ Issues() []LintIssue }); ok {
+		for _, issue := range linted.Issues() {
+			log.Printf("Quote file %v", issue)
+		}
+	}
+	if deduped, ok := s.service.(interface{ Duplicates() *DedupReport }); ok {
+		if report := deduped.Duplicates(); report.Removed > 0 {
+			log.Printf("Merged %d duplicate quotes (%d with conflicting attributions)", report.Removed, report.Conflicts())
+		}
+	}
+	log.Printf("Listening on http://%s", addr)
+	return server.ListenAndServe()
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := map[string]any{"status": "ok"}
+	if loaded, ok := s.service.(interface{ LoadedAt() time.Time }); ok {
+		health["loaded_at"] = loaded.LoadedAt()
+	}
+	s.respond(w, http.StatusOK, health)
+}
+
+// POST /reload re-reads the quotes and lexicon files. If they no longer load,
+// the server keeps answering from the current data.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	reloader, ok := s.service.(Reloader)
+	if !ok {
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("this search engine cannot be reloaded"))
+		return
+	}
+	if err := reloader.Reload(); err != nil {
+		log.Printf("Reload failed, keeping the current quotes: %v", err)
+		s.respondError(w, http.StatusUnprocessableEntity, fmt.Errorf("reload failed, keeping the current quotes: %w", err))
+		return
+	}
+
+	response := map[string]any{"status": "reloaded"}
+	if indexed, ok := s.service.(interface{ Index() []IndexedQuote }); ok {
+		response["quotes"] = len(indexed.Index())
+	}
+	log.Printf("Reloaded quotes via %s", r.URL.Path)
+	s.respond(w, http.StatusOK, response)
+}
+
+// GET /quotes/{id}
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	lookup, ok := s.service.(QuoteLookup)
+	if !ok {
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("quote lookup is not available"))
+		return
+	}
+	id := r.PathValue("id")
+	quote, found := lookup.QuoteByID(id)
+	if !found {
+		s.respondError(w, http.StatusNotFound, fmt.Errorf("no quote with id %q", id))
+		return
+	}
+	// Source paths describe the server's filesystem, not the quote
+	quote.Source = ""
+	s.respond(w, http.StatusOK, quote)
+}
+
+// GET /quotes lists the whole corpus
+func (s *Server) handleListQuotes(w http.ResponseWriter, r *http.Request) {
+	var quotes []Quote
+	switch indexed, ok := s.service.(interface{ Index() []IndexedQuote }); {
+	case s.editor != nil:
+		var err error
+		if quotes, err = s.editor.ListQuotes(); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+	case ok:
+		for _, entry := range indexed.Index() {
+			quotes = append(quotes, entry.Quote)
+		}
+	default:
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("listing quotes is not available"))
+		return
+	}
+
+	listed := make([]Quote, 0, len(quotes))
+	for _, quote := range quotes {
+		quote.Source = ""
+		listed = append(listed, quote)
+	}
+	s.respond(w, http.StatusOK, map[string]any{"quotes": listed})
+}
+
+// POST /quotes with a JSON quote body
+func (s *Server) handleAddQuote(w http.ResponseWriter, r *http.Request) {
+	quote, ok := s.editableQuote(w, r)
+	if !ok {
+		return
+	}
+	added, err := s.editor.AddQuote(quote)
+	s.respondEdit(w, http.StatusCreated, added, err)
+}
+
+// PUT /quotes/{id} replaces the quote with a JSON quote body
+func (s *Server) handleUpdateQuote(w http.ResponseWriter, r *http.Request) {
+	quote, ok := s.editableQuote(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if quote.ID != "" && quote.ID != id {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("quote id %q does not match the path", quote.ID))
+		return
+	}
+	quote.ID = id
+	updated, err := s.editor.UpdateQuote(quote)
+	s.respondEdit(w, http.StatusOK, updated, err)
+}
+
+// DELETE /quotes/{id}
+func (s *Server) handleDeleteQuote(w http.ResponseWriter, r *http.Request) {
+	if s.editor == nil {
+		s.respondError(w, http.StatusForbidden, errEditingDisabled)
+		return
+	}
+	err := s.editor.DeleteQuote(r.PathValue("id"))
+	s.respondEdit(w, http.StatusOK, Quote{ID: r.PathValue("id")}, err)
+}
+
+var errEditingDisabled = errors.New("editing is disabled; serve a quote store with --allow-edits")
+
+func (s *Server) editableQuote(w http.ResponseWriter, r *http.Request) (Quote, bool) {
+	if s.editor == nil {
+		s.respondError(w, http.StatusForbidden, errEditingDisabled)
+		return Quote{}, false
+	}
+	var quote Quote
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&quote); err != nil {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid quote: %w", err))
+		return Quote{}, false
+	}
+	return quote, true
+}
+
+// respondEdit reports a change and reloads the service so searches see it.
+// A failed reload does not undo the change; it is reported alongside it.
+func (s *Server) respondEdit(w http.ResponseWriter, status int, quote Quote, err error) {
+	var invalid *ValidationError
+	switch {
+	case errors.Is(err, ErrQuoteNotFound):
+		s.respondError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, ErrQuoteExists), errors.Is(err, ErrDuplicateQuoteID):
+		s.respondError(w, http.StatusConflict, err)
+		return
+	case errors.As(err, &invalid):
+		s.respondError(w, http.StatusUnprocessableEntity, err)
+		return
+	case err != nil:
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := map[string]any{"quote": quote}
+	if reloader, ok := s.service.(Reloader); ok {
+		if err := reloader.Reload(); err != nil {
+			log.Printf("Quote %s saved but the reload failed: %v", quote.ID, err)
+			response["reload_error"] = err.Error()
+		}
+	}
+	s.respond(w, status, response)
+}
+
+// POST /feedback with a JSON FeedbackRequest body
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	var req FeedbackRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("query cannot be empty"))
+		return
+	}
+	rating, err := ParseFeedbackRating(req.Rating)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Comment) > maxFeedbackComment {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("comment is longer than %d bytes", maxFeedbackComment))
+		return
+	}
+	_, stores, ok := s.userStores(w, req.User)
+	if !ok {
+		return
+	}
+	if stores.Feedback == nil {
+		s.respondError(w, http.StatusNotImplemented, errFeedbackDisabled)
+		return
+	}
+
+	lookup, ok := s.service.(QuoteLookup)
+	if !ok {
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("quote lookup is not available"))
+		return
+	}
+	quote, found := lookup.QuoteByID(req.QuoteID)
+	if !found {
+		s.respondError(w, http.StatusNotFound, fmt.Errorf("no quote with id %q", req.QuoteID))
+		return
+	}
+	quote.Source = ""
+	if req.JournalID != "" && stores.Journal == nil {
+		s.respondError(w, http.StatusNotImplemented, errJournalDisabled)
+		return
+	}
+
+	// The journal goes first, since it rejects unknown entry IDs
+	entry := newFeedbackEntry(s.service, req.Query, quote, rating, req.Comment)
+	if req.JournalID != "" {
+		err := stores.Journal.AddRating(req.JournalID, JournalRating{
+			Time: entry.Time, QuoteID: quote.ID, Rating: rating, Comment: entry.Comment,
+		})
+		if errors.Is(err, ErrJournalEntryNotFound) {
+			s.respondError(w, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := stores.Feedback.Record(entry); err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respond(w, http.StatusCreated, map[string]any{"status": "recorded", "quote_id": quote.ID, "rating": rating})
+}
+
+// maxFeedbackComment bounds the comment stored with one rating
+const maxFeedbackComment = 2000
+
+var errFeedbackDisabled = errors.New("feedback is not enabled on this server")
+
+// GET /feedback/report?top=N&user=U
+func (s *Server) handleFeedbackReport(w http.ResponseWriter, r *http.Request) {
+	_, stores, ok := s.userStores(w, r.URL.Query().Get("user"))
+	if !ok {
+		return
+	}
+	if stores.Feedback == nil {
+		s.respondError(w, http.StatusNotImplemented, errFeedbackDisabled)
+		return
+	}
+	top := 5
+	if value := r.URL.Query().Get("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid top: %q", value))
+			return
+		}
+		top = n
+	}
+	entries, err := stores.Feedback.List()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respond(w, http.StatusOK, BuildFeedbackReport(entries, top))
+}
+
+var errJournalDisabled = errors.New("the journal is not enabled on this server")
+
+// GET /journal?since=30d&user=U lists journal entries, oldest first
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	_, stores, ok := s.userStores(w, r.URL.Query().Get("user"))
+	if !ok {
+		return
+	}
+	if stores.Journal == nil {
+		s.respondError(w, http.StatusNotImplemented, errJournalDisabled)
+		return
+	}
+	since, err := ParseSince(r.URL.Query().Get("since"), time.Now())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := stores.Journal.Entries(since)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []JournalEntry{}
+	}
+	s.respond(w, http.StatusOK, map[string]any{"entries": entries})
+}
+
+// GET /journal/trends?since=12w&alert_weeks=N&alert_valence=X&user=U
+func (s *Server) handleTrends(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	_, stores, ok := s.userStores(w, params.Get("user"))
+	if !ok {
+		return
+	}
+	if stores.Journal == nil {
+		s.respondError(w, http.StatusNotImplemented, errJournalDisabled)
+		return
+	}
+	period := params.Get("since")
+	if !params.Has("since") {
+		period = "12w"
+	}
+	since, err := ParseSince(period, time.Now())
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	options := TrendOptions{AlertWeeks: DefaultAlertWeeks, AlertValence: DefaultAlertValence}
+	if value := params.Get("alert_weeks"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid alert_weeks: %q", value))
+			return
+		}
+		options.AlertWeeks = n
+	}
+	if value := params.Get("alert_valence"); value != "" {
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil || x < -1 || x > 1 {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid alert_valence: %q", value))
+			return
+		}
+		options.AlertValence = x
+	}
+
+	entries, err := stores.Journal.Entries(since)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respond(w, http.StatusOK, BuildMoodTrends(entries, time.Now(), options))
+}
+
+// GET /daily?user=U&date=2026-10-18&mood_days=N&avoid_days=N picks the
+// quote of the day, from the journal's recent mood when the server keeps one
+func (s *Server) handleDaily(w http.ResponseWriter, r *http.Request) {
+	picker, ok := s.service.(DailyPicker)
+	if !ok {
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("the quote of the day is not available for this search engine"))
+		return
+	}
+	params := r.URL.Query()
+	user, stores, ok := s.optionalUserStores(w, params.Get("user"))
+	if !ok {
+		return
+	}
+	options := DailyOptions{
+		User:      user,
+		Date:      time.Now(),
+		MoodDays:  DefaultDailyMoodDays,
+		AvoidDays: DefaultDailyAvoidDays,
+		Filter:    s.filter,
+	}
+	if date := params.Get("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid date: %q (expected e.g. 2026-10-18)", date))
+			return
+		}
+		options.Date = day
+	}
+	for name, value := range map[string]*int{"mood_days": &options.MoodDays, "avoid_days": &options.AvoidDays} {
+		if param := params.Get(name); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 0 || (n == 0 && name == "mood_days") {
+				s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %q", name, param))
+				return
+			}
+			*value = n
+		}
+	}
+
+	daily, err := picker.DailyQuote(stores.Journal, options)
+	if errors.Is(err, ErrInvalidDailyOptions) {
+		s.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, ErrNoMatches) {
+		s.respondError(w, http.StatusNotFound, fmt.Errorf("no quote matches the server's filters"))
+		return
+	}
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	daily.Quote.Source = ""
+	s.respond(w, http.StatusOK, daily)
+}
+
+var errCollectionsDisabled = errors.New("collections are not enabled on this server")
+
+// collections are the favorites and collections of the request's user,
+// responding when there are none to use
+func (s *Server) collections(w http.ResponseWriter, r *http.Request) (CollectionStore, bool) {
+	_, stores, ok := s.userStores(w, r.URL.Query().Get("user"))
+	if !ok {
+		return nil, false
+	}
+	if stores.Favorites == nil {
+		s.respondError(w, http.StatusNotImplemented, errCollectionsDisabled)
+		return nil, false
+	}
+	return stores.Favorites, true
+}
+
+// GET /collections?user=U lists the favorites and the named collections
+func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.collections(w, r)
+	if !ok {
+		return
+	}
+	summaries, err := saved.Collections()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respond(w, http.StatusOK, map[string]any{"collections": summaries})
+}
+
+// GET /collections/{name}
+func (s *Server) handleCollection(w http.ResponseWriter, r *http.Request) {
+	name, quotes, ok := s.collection(w, r)
+	if !ok {
+		return
+	}
+	s.respond(w, http.StatusOK, map[string]any{"name": name, "quotes": quotes})
+}
+
+// GET /collections/{name}/export?format=json|csv downloads a quotes file
+func (s *Server) handleExportCollection(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid format: %q (expected json or csv)", format))
+		return
+	}
+	name, quotes, ok := s.collection(w, r)
+	if !ok {
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	if err := ExportCollection(w, quotes, format); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// collection reads the collection named in the path, responding on failure
+func (s *Server) collection(w http.ResponseWriter, r *http.Request) (string, []Quote, bool) {
+	saved, ok := s.collections(w, r)
+	if !ok {
+		return "", nil, false
+	}
+	name, err := NormalizeCollectionName(r.PathValue("name"))
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return "", nil, false
+	}
+	quotes, err := saved.Collection(name)
+	if err != nil {
+		s.respondCollectionError(w, err)
+		return "", nil, false
+	}
+	if quotes == nil {
+		quotes = []Quote{}
+	}
+	return name, quotes, true
+}
+
+// PUT /collections/{name}/{id} saves a quote, creating the collection if needed
+func (s *Server) handleSaveToCollection(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.collections(w, r)
+	if !ok {
+		return
+	}
+	lookup, ok := s.service.(QuoteLookup)
+	if !ok {
+		s.respondError(w, http.StatusNotImplemented, fmt.Errorf("quote lookup is not available"))
+		return
+	}
+	id := r.PathValue("id")
+	quote, found := lookup.QuoteByID(id)
+	if !found {
+		s.respondError(w, http.StatusNotFound, fmt.Errorf("no quote with id %q", id))
+		return
+	}
+
+	added, err := saved.AddToCollection(r.PathValue("name"), quote)
+	if err != nil {
+		s.respondCollectionError(w, err)
+		return
+	}
+	if !added {
+		s.respond(w, http.StatusOK, map[string]any{"status": "already saved", "quote_id": id})
+		return
+	}
+	s.respond(w, http.StatusCreated, map[string]any{"status": "saved", "quote_id": id})
+}
+
+// DELETE /collections/{name}/{id}
+func (s *Server) handleRemoveFromCollection(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.collections(w, r)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	if err := saved.RemoveFromCollection(r.PathValue("name"), id); err != nil {
+		s.respondCollectionError(w, err)
+		return
+	}
+	s.respond(w, http.StatusOK, map[string]any{"status": "removed", "quote_id": id})
+}
+
+func (s *Server) respondCollectionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrNotInCollection):
+		s.respondError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrInvalidCollectionName):
+		s.respondError(w, http.StatusBadRequest, err)
+	default:
+		s.respondError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// GET /search?q=...&top=N&min_score=X&genre=G&max_rating=R&year=Y&language=L&locale=L&explain=true&journal=true&user=U
+func (s *Server) handleSearchQuery(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	req := SearchAPIRequest{
+		Query:     params.Get("q"),
+		Genres:    ParseGenres(strings.Join(params["genre"], ",")),
+		MaxRating: params.Get("max_rating"),
+		Year:      params.Get("year"),
+		Language:  params.Get("language"),
+		Locale:    params.Get("locale"),
+		User:      params.Get("user"),
+	}
+	if explain := params.Get("explain"); explain != "" {
+		b, err := strconv.ParseBool(explain)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid explain: %q", explain))
+			return
+		}
+		req.Explain = b
+	}
+	if journal := params.Get("journal"); journal != "" {
+		b, err := strconv.ParseBool(journal)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid journal: %q", journal))
+			return
+		}
+		req.Journal = b
+	}
+	if top := params.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid top: %q", top))
+			return
+		}
+		req.Top = n
+	}
+	if minScore := params.Get("min_score"); minScore != "" {
+		x, err := strconv.ParseFloat(minScore, 64)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid min_score: %q", minScore))
+			return
+		}
+		req.MinScore = &x
+	}
+	s.search(w, r, req)
+}
+
+// POST /search with a JSON SearchAPIRequest body
+func (s *Server) handleSearchBody(w http.ResponseWriter, r *http.Request) {
+	var req SearchAPIRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	s.search(w, r, req)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, req SearchAPIRequest) {
+	if strings.TrimSpace(req.Query) == "" {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("query cannot be empty"))
+		return
+	}
+
+	topN := s.topN
+	if req.Top != 0 {
+		topN = req.Top
+	}
+	if topN < 1 {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("top must be at least 1"))
+		return
+	}
+
+	minScore := s.minScore
+	if req.MinScore != nil {
+		minScore = *req.MinScore
+	}
+	if minScore < 0 || minScore > 1 {
+		s.respondError(w, http.StatusBadRequest, fmt.Errorf("min_score must be between 0 and 1"))
+		return
+	}
+
+	filter, err := s.requestFilter(req)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	user, stores, ok := s.optionalUserStores(w, req.User)
+	if !ok {
+		return
+	}
+	if req.Journal && stores.Journal == nil {
+		if s.users != nil && user == "" {
+			s.respondError(w, http.StatusBadRequest, errUserRequired)
+			return
+		}
+		s.respondError(w, http.StatusNotImplemented, errJournalDisabled)
+		return
+	}
+
+	// Without a user there is nobody to rotate quotes for
+	var tracker *ExposureTracker
+	var novelty *Novelty
+	if stores.Exposure != nil && user != "" {
+		tracker = NewExposureTracker(stores.Exposure, user, s.novelty)
+		if novelty, err = tracker.Novelty(); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	var profile *PreferenceProfile
+	if s.personalize {
+		builder := NewProfileBuilder(s.service.(QueryAnalyzer), stores.Favorites, stores.Feedback, s.profileStrength)
+		if profile, err = builder.Profile(); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	response := SearchResponse{Query: req.Query, Results: []QuoteResult{}}
+
+	results, err := s.runSearch(r.Context(), SearchRequest{
+		Query:   req.Query,
+		TopN:    topN,
+		Filter:  filter,
+		Explain: req.Explain,
+		Locale:  req.Locale,
+		Novelty: novelty,
+		Profile: profile,
+	})
+	if err == nil {
+		results, err = FilterConfident(results, minScore)
+	}
+	if err == nil && tracker != nil {
+		// The results are still worth sending if they cannot be remembered
+		if recordErr := tracker.Record(results); recordErr != nil {
+			log.Printf("Could not record the quotes shown to %q: %v", user, recordErr)
+		}
+	}
+
+	var lowConfidence *LowConfidenceError
+	switch {
+	case errors.Is(err, ErrCrisisDetected):
+		response.Crisis = true
+		response.Resources = CrisisResources
+	case errors.As(err, &lowConfidence):
+		response.NoConfidentMatch = true
+		response.BestScore = lowConfidence.BestScore
+	case errors.Is(err, ErrNoMatches):
+		// An empty result list is a valid answer, not a failure
+	case errors.Is(err, context.DeadlineExceeded):
+		s.respondError(w, http.StatusGatewayTimeout, fmt.Errorf("search timed out after %v", s.timeout))
+		return
+	case errors.Is(err, context.Canceled):
+		// The client has gone; nobody is left to answer
+		return
+	case err != nil:
+		s.respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if req.Journal {
+		entry, logErr := stores.Journal.Log(newJournalEntry(s.service, req.Query, results, err))
+		if logErr != nil {
+			s.respondError(w, http.StatusInternalServerError, logErr)
+			return
+		}
+		response.JournalID = entry.ID
+	}
+
+	for _, result := range results {
+		response.Results = append(response.Results, QuoteResult{
+			ID:          result.Quote.ID,
+			Text:        result.Quote.Text,
+			Movie:       result.Quote.Movie,
+			Character:   result.Quote.Character,
+			Year:        result.Quote.Year,
+			Genres:      result.Quote.Genres,
+			Rating:      result.Quote.Rating,
+			Language:    result.Quote.Language,
+			Context:     result.Quote.Context,
+			Score:       result.Score,
+			Explanation: result.Explanation,
+		})
+	}
+
+	s.respond(w, http.StatusOK, response)
+}
+
+// runSearch searches within the server's timeout, falling back to the older
+// interfaces for services that cannot be cancelled
+func (s *Server) runSearch(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	searcher, ok := s.service.(ContextSearcher)
+	if !ok {
+		if req.Explain || req.Locale != "" {
+			return nil, fmt.Errorf("explain and locale are not available for this search engine")
+		}
+		if req.Filter.IsZero() {
+			return s.service.SearchQuotes(req.Query, req.TopN)
+		}
+		return s.service.(FilteredSearcher).SearchFiltered(req.Query, req.TopN, req.Filter)
+	}
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return searcher.Search(ctx, req)
+}
+
+// requestFilter layers the request's filters over the server's
+func (s *Server) requestFilter(req SearchAPIRequest) (QuoteFilter, error) {
+	filter := s.filter
+	if len(req.Genres) > 0 {
+		filter.Genres = ParseGenres(strings.Join(req.Genres, ","))
+	}
+	if req.Language != "" {
+		filter.Language = req.Language
+	}
+	if req.Year != "" {
+		from, to, err := ParseYearRange(req.Year)
+		if err != nil {
+			return filter, err
+		}
+		filter.YearFrom, filter.YearTo = from, to
+	}
+	if req.MaxRating != "" {
+		rating, err := ParseContentRating(req.MaxRating)
+		if err != nil {
+			return filter, err
+		}
+		if filter.MaxRating == "" || rating.level() < filter.MaxRating.level() {
+			filter.MaxRating = rating
+		}
+	}
+	if _, ok := s.service.(FilteredSearcher); !ok && !filter.IsZero() {
+		return filter, fmt.Errorf("filters are not available for this search engine")
+	}
+	return filter, nil
+}
+
+func (s *Server) respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := writeJSON(w, body); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func (s *Server) respondError(w http.ResponseWriter, status int, err error) {
+	s.respond(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// request sends a request to the server's handler and decodes a JSON reply
+// into reply, when given
+func request(t *testing.T, server *Server, method, target, body string, reply any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if reply != nil && recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), reply); err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestServerSearchConfidence(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 20))
+	server := NewServer(service, 3, DefaultMinScore)
+	query := "q=" + strings.ReplaceAll(testQueries[1], " ", "+")
+
+	tests := []struct {
+		name       string
+		target     string
+		status     int
+		results    int // most results expected; fewer may pass the threshold
+		noneFitted bool
+	}{
+		{"server defaults", "/search?" + query, http.StatusOK, 3, false},
+		{"top", "/search?" + query + "&top=1", http.StatusOK, 1, false},
+		{"nothing is confident enough", "/search?" + query + "&min_score=0.99", http.StatusOK, 0, true},
+		{"no threshold", "/search?" + query + "&min_score=0", http.StatusOK, 3, false},
+		{"top below 1", "/search?" + query + "&top=0", http.StatusOK, 3, false}, // 0 means the server's default
+		{"negative top", "/search?" + query + "&top=-1", http.StatusBadRequest, 0, false},
+		{"min score above 1", "/search?" + query + "&min_score=1.5", http.StatusBadRequest, 0, false},
+		{"min score not a number", "/search?" + query + "&min_score=high", http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response SearchResponse
+			if status := request(t, server, "GET", tt.target, "", &response); status != tt.status {
+				t.Fatalf("got status %d, want %d", status, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if len(response.Results) > tt.results || (!tt.noneFitted && len(response.Results) == 0) {
+				t.Errorf("got %d results, want 1 to %d", len(response.Results), tt.results)
+			}
+			if response.NoConfidentMatch != tt.noneFitted {
+				t.Errorf("got no_confident_match %v, want %v", response.NoConfidentMatch, tt.noneFitted)
+			}
+			if tt.noneFitted && (len(response.Results) != 0 || response.BestScore <= 0 || response.BestScore >= 0.99) {
+				t.Errorf("got %d results with best score %.2f, want none and the best score below 0.99", len(response.Results), response.BestScore)
+			}
+		})
+	}
+
+	// The JSON body takes the same options
+	var response SearchResponse
+	body := `{"query": "` + testQueries[1] + `", "top": 2, "min_score": 0.05}`
+	if status := request(t, server, "POST", "/search", body, &response); status != http.StatusOK || len(response.Results) != 2 {
+		t.Errorf("got status %d and %d results, want 200 and 2", status, len(response.Results))
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceStats describes one file that contributed to a corpus
+type SourceStats struct {
+	Path   string      `json:"path"`
+	Format QuoteFormat `json:"format"`
+	Quotes int         `json:"quotes"`
+}
+
+// Extensions picked up when a directory is loaded
+var quoteFileExtensions = map[string]bool{
+	".json": true, ".jsonl": true, ".ndjson": true,
+	".csv": true, ".tsv": true, ".tab": true,
+	storeExtension: true,
+}
+
+// resolveQuoteFiles expands a quotes path into the files it names: a single
+// file, every quote file under a directory, or the matches of a glob pattern
+func resolveQuoteFiles(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quotes pattern %q: %w", path, err)
+		}
+		var files []string
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no quote files match %q", path)
+		}
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quotes file: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		hidden := strings.HasPrefix(entry.Name(), ".") && file != path
+		if entry.IsDir() {
+			if hidden {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !hidden && quoteFileExtensions[strings.ToLower(filepath.Ext(file))] {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quotes directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no quote files found in %s", path)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadCorpus merges every file named by path into one corpus, tagging each
+// quote with the file it came from. All failing files are reported together.
+func (r *FileQuoteRepository) loadCorpus(path string) (*QuoteData, error) {
+	files, err := resolveQuoteFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	corpus := &QuoteData{}
+	var errs []error
+	for _, file := range files {
+		data, format, err := r.loadFile(file)
+		if err != nil {
+			if len(files) > 1 {
+				err = fmt.Errorf("%s: %w", file, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		corpus.Issues = append(corpus.Issues, data.Issues...)
+		for i, quote := range data.Quotes {
+			if quote.Source == "" {
+				quote.Source = file
+			}
+			for _, issue := range lintQuote(file, i+1, quote) {
+				issue.quote = len(corpus.Quotes) + 1
+				corpus.Issues = append(corpus.Issues, issue)
+			}
+			corpus.Quotes = append(corpus.Quotes, quote)
+		}
+		if corpus.Query == "" {
+			corpus.Query = data.Query
+		}
+		corpus.Sources = append(corpus.Sources, SourceStats{Path: file, Format: format, Quotes: len(data.Quotes)})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return corpus, nil
+}
+
+func (r *FileQuoteRepository) loadFile(filename string) (*QuoteData, QuoteFormat, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open quotes file: %w", err)
+	}
+
+	format := r.options.Format
+	if format == FormatAuto {
+		format = DetectFormat(filename, content)
+	}
+
+	data, err := parseQuotes(filename, content, LoadOptions{Format: format, Columns: r.options.Columns})
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to parse quotes file: %w", err)
+	}
+	return data, format, nil
+}
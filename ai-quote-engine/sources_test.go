@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under dir, with their folders
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveQuoteFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"b.json":             "{}",
+		"a.csv":              "",
+		"more/c.jsonl":       "",
+		"more/d.TSV":         "",
+		"notes.txt":          "",
+		".hidden.json":       "",
+		".drafts/e.json":     "",
+		"empty/readme.md":    "",
+		"globbed/x-1.json":   "",
+		"globbed/x-2.csv":    "",
+		"globbed/y.json":     "",
+		"globbed/x-3/z.json": "",
+	})
+
+	tests := []struct {
+		name string
+		path string
+		want []string // relative to dir
+		err  string
+	}{
+		{"file", "notes.txt", []string{"notes.txt"}, ""},
+		{"directory, sorted, without hidden or other files", "more", []string{"more/c.jsonl", "more/d.TSV"}, ""},
+		{"nested directories", ".", []string{"a.csv", "b.json", "globbed/x-1.json", "globbed/x-2.csv", "globbed/x-3/z.json", "globbed/y.json", "more/c.jsonl", "more/d.TSV"}, ""},
+		{"glob skips directories", "globbed/x-*", []string{"globbed/x-1.json", "globbed/x-2.csv"}, ""},
+		{"glob without matches", "globbed/w-*", nil, "no quote files match"},
+		{"directory without quote files", "empty", nil, "no quote files found"},
+		{"missing", "missing.json", nil, "failed to open quotes file"},
+		{"bad pattern", "globbed/[", nil, "invalid quotes pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := resolveQuoteFiles(filepath.Join(dir, tt.path))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, _ := filepath.Rel(dir, file)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"classics.json": `{"quotes": [{"text": "Here's looking at you, kid.", "movie": "Casablanca", "character": "Rick"}]}`,
+		"more/sci-fi.csv": "text,movie,character\n" +
+			"May the Force be with you,Star Wars,Han\n" +
+			"I'll be back,The Terminator,T-800\n",
+	})
+	repo := NewFileQuoteRepositoryWithOptions(LoadOptions{KeepDuplicates: true})
+
+	data, err := repo.LoadQuotes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Quotes) != 3 {
+		t.Fatalf("got %d quotes, want 3", len(data.Quotes))
+	}
+	want := []SourceStats{
+		{Path: filepath.Join(dir, "classics.json"), Format: FormatJSON, Quotes: 1},
+		{Path: filepath.Join(dir, "more", "sci-fi.csv"), Format: FormatCSV, Quotes: 2},
+	}
+	if !reflect.DeepEqual(data.Sources, want) {
+		t.Errorf("got sources %+v, want %+v", data.Sources, want)
+	}
+	if source := data.Quotes[2].Source; source != want[1].Path {
+		t.Errorf("got source %q for the last quote, want %q", source, want[1].Path)
+	}
+
+	// Every file that fails is named
+	writeFiles(t, dir, map[string]string{"broken.json": "{", "more/broken.jsonl": "{\n"})
+	_, err = repo.LoadQuotes(dir)
+	if err == nil {
+		t.Fatal("loaded a corpus with broken files")
+	}
+	for _, name := range []string{"broken.json", "broken.jsonl"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("the error does not name %s: %v", name, err)
+		}
+	}
+}
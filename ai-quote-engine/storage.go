@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// loadJSONFile decodes a JSON file into value. A missing file is reported
+// with an error wrapping os.ErrNotExist so callers can treat it as empty.
+func loadJSONFile(filename string, value any) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return nil
+}
+
+// saveJSONFile writes value as indented JSON through a temporary file and a
+// rename, so a failed write never leaves a truncated file behind. A new file
+// gets perm; user data should be 0o600.
+func saveJSONFile(filename string, perm os.FileMode, value any) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filename, err)
+	}
+
+	if dir := filepath.Dir(filename); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// openAppendFile opens a line-per-record file for appending, creating it and
+// its directory as needed. A torn last line is cut off first, so the new
+// record does not continue it.
+func openAppendFile(filename string, perm os.FileMode) (*os.File, error) {
+	if dir := filepath.Dir(filename); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_RDWR, perm)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncateTornLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to repair %s: %w", filename, err)
+	}
+	return file, nil
+}
+
+// truncateTornLine cuts an unterminated last line, left by a crash
+// mid-append, off a line-per-record file, reading back from the end only as
+// far as the last newline
+func truncateTornLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	end := info.Size()
+	buf := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		n := int64(len(buf))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err := file.ReadAt(buf[:n], offset); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			if complete := offset + int64(i) + 1; complete < end {
+				return file.Truncate(complete)
+			}
+			return nil
+		}
+	}
+	if end > 0 {
+		return file.Truncate(0)
+	}
+	return nil
+}
+
+// tornLine is the number of the unterminated last line of a line-per-record
+// file's content, or 0 when the content ends with a newline. Readers skip
+// that line when it does not parse; the next append cuts it off.
+func tornLine(content []byte) int {
+	if len(content) == 0 || content[len(content)-1] == '\n' {
+		return 0
+	}
+	return bytes.Count(content, []byte("\n")) + 1
+}
+
+// rewriteFile replaces a file with what write produces, through a temporary
+// file and a rename like saveJSONFile
+func rewriteFile(filename string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp := filename + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
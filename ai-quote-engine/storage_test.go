@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserFilesArePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	dir := t.TempDir()
+	quote := Quote{ID: "q-1", Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"}
+	now := time.Now()
+
+	tests := []struct {
+		file  string
+		write func(filename string) error
+	}{
+		{"favorites.json", func(filename string) error {
+			_, err := NewFileFavoritesStore(filename).Add(quote)
+			return err
+		}},
+		{"feedback.jsonl", func(filename string) error {
+			store := NewFileFeedbackStore(filename)
+			if err := store.Record(FeedbackEntry{Time: now.Add(-time.Hour), Quote: quote, Rating: "helpful"}); err != nil {
+				return err
+			}
+			if err := store.Record(FeedbackEntry{Time: now, Quote: quote, Rating: "helpful"}); err != nil {
+				return err
+			}
+			_, err := store.Purge(now) // rewrites the file
+			return err
+		}},
+		{"exposure.json", func(filename string) error {
+			return NewFileExposureStore(filename).RecordShown("", []string{quote.ID}, now)
+		}},
+		{"journal.jsonl", func(filename string) error {
+			_, err := NewFileJournal(filename).Log(JournalEntry{Time: now, Query: "hello"})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.file)
+			if err := tt.write(filename); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := info.Mode().Perm(); mode != 0o600 {
+				t.Errorf("got mode %#o, want 0600", mode)
+			}
+		})
+	}
+}
+
+func TestTruncateTornLine(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	tests := []struct {
+		name, content, want string
+	}{
+		{"empty", "", ""},
+		{"complete", "a\nb\n", "a\nb\n"},
+		{"torn", "a\nb\n{\"ti", "a\nb\n"},
+		{"only a torn line", "{\"ti", ""},
+		{"torn line longer than a read", "a\n" + long, "a\n"},
+		{"complete line longer than a read", long + "\n", long + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "log.jsonl")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			file, err := openAppendFile(filename, 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+			if content, _ := os.ReadFile(filename); string(content) != tt.want {
+				t.Errorf("got %q, want %q", content, tt.want)
+			}
+		})
+	}
+}
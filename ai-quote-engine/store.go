@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrQuoteNotFound reports an ID that is not in the store
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// ErrQuoteExists reports an added quote whose text is already stored
+var ErrQuoteExists = errors.New("quote already exists")
+
+// storeExtension marks quote files written by QuoteStore
+const storeExtension = ".qdb"
+
+// WritableQuoteRepository is a QuoteRepository whose quotes can be edited in
+// place instead of by rewriting a quotes file
+type WritableQuoteRepository interface {
+	QuoteRepository
+	ListQuotes() ([]Quote, error)
+	AddQuote(quote Quote) (Quote, error)
+	UpdateQuote(quote Quote) (Quote, error)
+	DeleteQuote(id string) error
+}
+
+// storeRecord is one line of the store's log
+type storeRecord struct {
+	Op    string    `json:"op"` // "put" or "delete"
+	ID    string    `json:"id"`
+	Quote *Quote    `json:"quote,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// QuoteStore keeps a corpus in an append-only log of JSON lines: every add or
+// update appends the whole quote and every delete appends a tombstone. The
+// log is compacted once most of it is history. Each change re-reads the log
+// first, so the CLI can edit a store while a server reads it; two processes
+// writing at the same moment are not coordinated.
+//
+// QuoteStore is safe for concurrent use within one process.
+type QuoteStore struct {
+	mu    sync.Mutex
+	path  string
+	files *FileQuoteRepository
+}
+
+// OpenQuoteStore opens the store at path, creating an empty one if needed.
+// Loading goes through the same checks as any quotes file.
+func OpenQuoteStore(path string, options LoadOptions) (*QuoteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quote store: %w", err)
+	}
+	file.Close()
+
+	options.Format = FormatStore
+	return &QuoteStore{path: path, files: NewFileQuoteRepositoryWithOptions(options)}, nil
+}
+
+func (s *QuoteStore) Path() string {
+	return s.path
+}
+
+// LoadQuotes loads the store for searching. path must be the store's own.
+func (s *QuoteStore) LoadQuotes(path string) (*QuoteData, error) {
+	if path != s.path {
+		return nil, fmt.Errorf("quote store %s cannot load %s", s.path, path)
+	}
+	return s.files.LoadQuotes(path)
+}
+
+// ListQuotes returns the stored quotes in the order they were first added
+func (s *QuoteStore) ListQuotes() ([]Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, _, err := s.read()
+	return quotes, err
+}
+
+// GetQuote looks up one stored quote
+func (s *QuoteStore) GetQuote(id string) (Quote, error) {
+	quotes, err := s.ListQuotes()
+	if err != nil {
+		return Quote{}, err
+	}
+	for _, quote := range quotes {
+		if quote.ID == id {
+			return quote, nil
+		}
+	}
+	return Quote{}, fmt.Errorf("%w: %q", ErrQuoteNotFound, id)
+}
+
+// AddQuote stores a new quote, deriving its ID from the text unless one is
+// given, and returns it as stored
+func (s *QuoteStore) AddQuote(quote Quote) (Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, records, err := s.readForAppend()
+	if err != nil {
+		return Quote{}, err
+	}
+	if quote, err = s.prepare(quote); err != nil {
+		return Quote{}, err
+	}
+
+	key := quoteKey(quote)
+	for _, existing := range quotes {
+		if quoteKey(existing) == key {
+			return Quote{}, fmt.Errorf("%w as %s: %q", ErrQuoteExists, existing.ID, existing.Text)
+		}
+	}
+	if quote.ID == "" {
+		quote.ID = DeriveQuoteID(quote)
+	}
+	for _, existing := range quotes {
+		if existing.ID == quote.ID {
+			return Quote{}, fmt.Errorf("%w %q: already used by %q", ErrDuplicateQuoteID, quote.ID, existing.Text)
+		}
+	}
+
+	if err := s.append(storeRecord{Op: "put", ID: quote.ID, Quote: &quote}); err != nil {
+		return Quote{}, err
+	}
+	return quote, s.maybeCompact(len(quotes)+1, records+1)
+}
+
+// UpdateQuote replaces the stored quote with the same ID
+func (s *QuoteStore) UpdateQuote(quote Quote) (Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, records, err := s.readForAppend()
+	if err != nil {
+		return Quote{}, err
+	}
+	if quote, err = s.prepare(quote); err != nil {
+		return Quote{}, err
+	}
+
+	found := false
+	key := quoteKey(quote)
+	for _, existing := range quotes {
+		switch {
+		case existing.ID == quote.ID:
+			found = true
+		case quoteKey(existing) == key:
+			return Quote{}, fmt.Errorf("%w as %s: %q", ErrQuoteExists, existing.ID, existing.Text)
+		}
+	}
+	if !found {
+		return Quote{}, fmt.Errorf("%w: %q", ErrQuoteNotFound, quote.ID)
+	}
+
+	if err := s.append(storeRecord{Op: "put", ID: quote.ID, Quote: &quote}); err != nil {
+		return Quote{}, err
+	}
+	return quote, s.maybeCompact(len(quotes), records+1)
+}
+
+// DeleteQuote removes a quote by ID
+func (s *QuoteStore) DeleteQuote(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, records, err := s.readForAppend()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, existing := range quotes {
+		if existing.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrQuoteNotFound, id)
+	}
+
+	if err := s.append(storeRecord{Op: "delete", ID: id}); err != nil {
+		return err
+	}
+	return s.maybeCompact(len(quotes)-1, records+1)
+}
+
+// Compact rewrites the log with one record per stored quote
+func (s *QuoteStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, _, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.compact(quotes)
+}
+
+// prepare checks a quote the way lint would and puts its metadata in
+// canonical form. The store is the quote's source, so Source is dropped.
+func (s *QuoteStore) prepare(quote Quote) (Quote, error) {
+	quote.ID = strings.TrimSpace(quote.ID)
+	quote.Text = strings.TrimSpace(quote.Text)
+	quote.Source = ""
+	if strings.IndexFunc(quote.ID, unicode.IsSpace) >= 0 {
+		return Quote{}, fmt.Errorf("quote id %q must not contain spaces", quote.ID)
+	}
+
+	var invalid []LintIssue
+	for _, issue := range lintQuote(s.path, 0, quote) {
+		if issue.Severity == SeverityError {
+			invalid = append(invalid, issue)
+		}
+	}
+	if len(invalid) > 0 {
+		return Quote{}, &ValidationError{Path: s.path, Issues: invalid}
+	}
+
+	quotes := []Quote{quote}
+	normalizeMetadata(quotes)
+	return quotes[0], nil
+}
+
+// read replays the log, returning the live quotes and the number of records.
+// A torn last line is left in the file; only writers drop it.
+func (s *QuoteStore) read() ([]Quote, int, error) {
+	quotes, records, _, err := s.replay()
+	return quotes, records, err
+}
+
+// readForAppend is read for a change about to be appended. A torn last line
+// is cut off first, so the new record does not continue it.
+func (s *QuoteStore) readForAppend() ([]Quote, int, error) {
+	quotes, records, complete, err := s.replay()
+	if err != nil || complete < 0 {
+		return quotes, records, err
+	}
+	if err := os.Truncate(s.path, complete); err != nil {
+		return nil, 0, fmt.Errorf("failed to repair quote store: %w", err)
+	}
+	return quotes, records, nil
+}
+
+// replay reads and replays the log. complete is the length of the log up to
+// a torn last line, or -1 when there is none.
+func (s *QuoteStore) replay() (quotes []Quote, records int, complete int64, err error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, 0, -1, fmt.Errorf("failed to read quote store: %w", err)
+	}
+	quotes, records, err = replayStore(s.path, content)
+	if err != nil {
+		return nil, 0, -1, err
+	}
+	complete = -1
+	if tornLine(content) > 0 {
+		complete = int64(bytes.LastIndexByte(content, '\n') + 1)
+	}
+	return quotes, records, complete, nil
+}
+
+func (s *QuoteStore) append(record storeRecord) error {
+	record.At = time.Now().UTC()
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode quote: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open quote store: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write quote store: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write quote store: %w", err)
+	}
+	return file.Close()
+}
+
+// maybeCompact compacts once history outweighs the live quotes
+func (s *QuoteStore) maybeCompact(live, records int) error {
+	if records < 64 || records < 2*live {
+		return nil
+	}
+	quotes, _, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.compact(quotes)
+}
+
+// compact writes the live quotes to a new log and renames it over the old
+// one, so a failed compaction leaves the store as it was
+func (s *QuoteStore) compact(quotes []Quote) error {
+	var b bytes.Buffer
+	now := time.Now().UTC()
+	for i := range quotes {
+		line, err := json.Marshal(storeRecord{Op: "put", ID: quotes[i].ID, Quote: &quotes[i], At: now})
+		if err != nil {
+			return fmt.Errorf("failed to encode quote: %w", err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to compact quote store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact quote store: %w", err)
+	}
+	return nil
+}
+
+// replayStore applies a store log in order. An update keeps the quote's
+// original position. A torn last line, left by a crash mid-write, is ignored.
+func replayStore(filename string, content []byte) ([]Quote, int, error) {
+	var quotes []Quote
+	position := make(map[string]int)
+	deleted := 0
+	var errs ParseErrors
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line, records, torn := 0, 0, tornLine(content)
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var record storeRecord
+		if err := json.Unmarshal(text, &record); err != nil {
+			if line == torn {
+				break
+			}
+			errs = append(errs, &ParseError{File: filename, Line: line, Err: err})
+			continue
+		}
+		records++
+
+		switch record.Op {
+		case "put":
+			if record.Quote == nil || record.ID == "" {
+				errs = append(errs, &ParseError{File: filename, Line: line, Err: errors.New("put record without a quote")})
+				continue
+			}
+			quote := *record.Quote
+			quote.ID = record.ID
+			if i, ok := position[record.ID]; ok {
+				quotes[i] = quote
+			} else {
+				position[record.ID] = len(quotes)
+				quotes = append(quotes, quote)
+			}
+		case "delete":
+			if i, ok := position[record.ID]; ok {
+				quotes[i].ID = "" // removed below, keeping positions stable meanwhile
+				delete(position, record.ID)
+				deleted++
+			}
+		default:
+			errs = append(errs, &ParseError{File: filename, Line: line, Err: fmt.Errorf("unknown operation %q", record.Op)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(errs) > 0 {
+		return nil, 0, errs
+	}
+
+	if deleted > 0 {
+		live := quotes[:0]
+		for _, quote := range quotes {
+			if quote.ID != "" {
+				live = append(live, quote)
+			}
+		}
+		quotes = live
+	}
+	return quotes, records, nil
+}
+
+// parseStoreQuotes reads a store log like any other quotes file
+func parseStoreQuotes(filename string, content []byte) (*QuoteData, error) {
+	quotes, _, err := replayStore(filename, content)
+	if err != nil {
+		return nil, err
+	}
+	return &QuoteData{Quotes: quotes}, nil
+}
+
+// Writable returns the service's repository when its quotes can be edited
+func (s *SemanticQuoteService) Writable() (WritableQuoteRepository, bool) {
+	repo, ok := s.repository.(WritableQuoteRepository)
+	return repo, ok
+}
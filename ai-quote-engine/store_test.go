@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayStore(t *testing.T) {
+	put := func(id, text string) string {
+		return `{"op":"put","id":"` + id + `","quote":{"text":"` + text + `","movie":"M","character":"C"},"at":"2026-01-01T00:00:00Z"}` + "\n"
+	}
+	del := func(id string) string {
+		return `{"op":"delete","id":"` + id + `","at":"2026-01-01T00:00:00Z"}` + "\n"
+	}
+
+	tests := []struct {
+		name    string
+		log     string
+		texts   []string // live quotes, in order
+		records int
+		errLine int // line of the parse error, 0 for none
+	}{
+		{"empty", "", nil, 0, 0},
+		{"puts", put("a", "one") + put("b", "two"), []string{"one", "two"}, 2, 0},
+		{"update keeps position", put("a", "one") + put("b", "two") + put("a", "uno"), []string{"uno", "two"}, 3, 0},
+		{"delete", put("a", "one") + put("b", "two") + del("a"), []string{"two"}, 3, 0},
+		{"put after delete goes last", put("a", "one") + put("b", "two") + del("a") + put("a", "uno"), []string{"two", "uno"}, 4, 0},
+		{"delete of a missing quote", put("a", "one") + del("b"), []string{"one"}, 2, 0},
+		{"torn last line", put("a", "one") + `{"op":"put","id":"b","quo`, []string{"one"}, 1, 0},
+		{"malformed line", put("a", "one") + "{oops}\n" + put("b", "two"), nil, 0, 2},
+		{"put without a quote", `{"op":"put","id":"a"}` + "\n", nil, 0, 1},
+		{"unknown operation", put("a", "one") + `{"op":"move","id":"a"}` + "\n", nil, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes, records, err := replayStore("quotes.qdb", []byte(tt.log))
+			if tt.errLine != 0 {
+				var errs ParseErrors
+				if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Line != tt.errLine {
+					t.Fatalf("got error %v, want one parse error on line %d", err, tt.errLine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records != tt.records {
+				t.Errorf("got %d records, want %d", records, tt.records)
+			}
+			var texts []string
+			for _, quote := range quotes {
+				texts = append(texts, quote.Text)
+			}
+			if strings.Join(texts, "|") != strings.Join(tt.texts, "|") {
+				t.Errorf("got quotes %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
+
+func openTestStore(t *testing.T) *QuoteStore {
+	t.Helper()
+	store, err := OpenQuoteStore(filepath.Join(t.TempDir(), "quotes.qdb"), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func countLines(t *testing.T, filename string) int {
+	t.Helper()
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(content, []byte("\n"))
+}
+
+func TestQuoteStoreTornLine(t *testing.T) {
+	store := openTestStore(t)
+	if _, err := store.AddQuote(Quote{Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(store.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"op":"put","id":"torn","quo`)
+	file.Close()
+	torn, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading leaves the file as it is
+	if quotes, err := store.ListQuotes(); err != nil || len(quotes) != 1 {
+		t.Fatalf("got %d quotes and error %v, want 1 quote", len(quotes), err)
+	}
+	if _, err := store.LoadQuotes(store.Path()); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(store.Path()); !bytes.Equal(content, torn) {
+		t.Fatalf("reading rewrote the store:\n%s", content)
+	}
+
+	// Writing cuts the torn line off before appending
+	if _, err := store.AddQuote(Quote{Text: "I'll be back.", Movie: "The Terminator", Character: "T-800"}); err != nil {
+		t.Fatal(err)
+	}
+	quotes, err := store.ListQuotes()
+	if err != nil || len(quotes) != 2 {
+		t.Fatalf("got %d quotes and error %v, want 2 quotes", len(quotes), err)
+	}
+	if n := countLines(t, store.Path()); n != 2 {
+		t.Errorf("got %d lines after the write, want 2", n)
+	}
+}
+
+func TestQuoteStoreCompaction(t *testing.T) {
+	store := openTestStore(t)
+	quote, err := store.AddQuote(Quote{Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.AddQuote(Quote{Text: "I'll be back.", Movie: "The Terminator", Character: "T-800"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// History below the threshold is kept
+	for i := 0; i < 50; i++ {
+		if _, err := store.UpdateQuote(quote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLines(t, store.Path()); n != 52 {
+		t.Fatalf("got %d lines before compaction, want 52", n)
+	}
+
+	// Once history outweighs the live quotes the log is rewritten
+	for i := 0; i < 12; i++ {
+		if _, err := store.UpdateQuote(quote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLines(t, store.Path()); n != 2 {
+		t.Fatalf("got %d lines after compaction, want 2", n)
+	}
+
+	if err := store.DeleteQuote(quote.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	quotes, err := store.ListQuotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotes) != 1 || quotes[0].ID != other.ID {
+		t.Errorf("got %v after deleting and compacting, want only %s", quotes, other.ID)
+	}
+	if n := countLines(t, store.Path()); n != 1 {
+		t.Errorf("got %d lines after compacting, want 1", n)
+	}
+}
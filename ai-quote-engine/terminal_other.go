@@ -0,0 +1,20 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+type rawTerminal struct{}
+
+func openTerminal() (*rawTerminal, error) {
+	return nil, errors.New("the terminal UI is only available on Unix-like systems")
+}
+
+func (t *rawTerminal) Size() (int, int, error) { return 0, 0, errors.New("not supported") }
+
+func (t *rawTerminal) Resized() <-chan os.Signal { return nil }
+
+func (t *rawTerminal) Restore() {}
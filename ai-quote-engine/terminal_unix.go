@@ -0,0 +1,63 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// rawTerminal puts the controlling terminal in raw mode through stty, which
+// keeps the terminal UI free of non-standard-library dependencies
+type rawTerminal struct {
+	saved   string
+	resized chan os.Signal
+}
+
+func openTerminal() (*rawTerminal, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("the terminal UI needs an interactive terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+
+	t := &rawTerminal{saved: strings.TrimSpace(saved), resized: make(chan os.Signal, 1)}
+	signal.Notify(t.resized, syscall.SIGWINCH)
+	return t, nil
+}
+
+// Size returns the terminal dimensions in rows and columns
+func (t *rawTerminal) Size() (int, int, error) {
+	out, err := stty("size")
+	if err != nil {
+		return 0, 0, err
+	}
+	var rows, cols int
+	if _, err := fmt.Sscan(out, &rows, &cols); err != nil {
+		return 0, 0, fmt.Errorf("unexpected stty size output %q", out)
+	}
+	return rows, cols, nil
+}
+
+// Resized delivers a value whenever the terminal window changes size
+func (t *rawTerminal) Resized() <-chan os.Signal {
+	return t.resized
+}
+
+func (t *rawTerminal) Restore() {
+	signal.Stop(t.resized)
+	stty(t.saved)
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+)
+
+// minTrainingExamples is the least feedback, per label, worth fitting to
+const minTrainingExamples = 5
+
+// trainingRegularization pulls the weights towards the defaults, so a little
+// feedback cannot move them far
+const trainingRegularization = 0.01
+
+// TrainOptions control how ranking weights are fitted
+type TrainOptions struct {
+	Holdout float64 // share of the feedback kept aside to measure the result
+	Rounds  int     // coordinate search passes over the weights
+}
+
+// TrainMetrics measure how well scores separate helpful from unhelpful results
+type TrainMetrics struct {
+	LogLoss float64 `json:"log_loss"` // of the score mapped to a probability, lower is better
+	AUC     float64 `json:"auc"`      // chance a helpful result outscores an unhelpful one
+}
+
+// TrainReport describes a training run
+type TrainReport struct {
+	Examples int            `json:"examples"`
+	Training int            `json:"training"`
+	HeldOut  int            `json:"held_out"`
+	Before   *TrainMetrics  `json:"before"` // held-out, with the starting weights
+	After    *TrainMetrics  `json:"after"`  // held-out, with the fitted weights; nil without both labels held out
+	Start    RankingWeights `json:"start"`
+	Weights  RankingWeights `json:"weights"`
+}
+
+// trainingExample is one rated result: the features of the query and of the
+// quote, and whether the user found it helpful
+type trainingExample struct {
+	query, quote map[string]float64
+	helpful      bool
+}
+
+// TrainRankingWeights fits the service's ranking weights to feedback. Ratings
+// of helpful count as positives; not helpful and inappropriate as negatives.
+// A deterministic share of the entries is held out to compare the starting
+// and fitted weights on feedback the fit never saw.
+//
+// The fit is a coordinate search that minimizes the log loss of a logistic
+// curve over the similarity score, refitting the curve for every candidate,
+// with a penalty for straying from the starting weights.
+func TrainRankingWeights(service *SemanticQuoteService, entries []FeedbackEntry, options TrainOptions) (*TrainReport, error) {
+	if options.Holdout < 0 || options.Holdout >= 1 {
+		return nil, fmt.Errorf("holdout must be at least 0 and below 1")
+	}
+	if options.Rounds < 1 {
+		options.Rounds = 50
+	}
+
+	quoteFeatures := make(map[string]map[string]float64)
+	for _, entry := range service.Index() {
+		quoteFeatures[entry.Quote.ID] = entry.Features
+	}
+
+	var train, heldOut []trainingExample
+	for _, entry := range entries {
+		example := trainingExample{query: entry.Features, helpful: entry.Rating == RatingHelpful}
+		switch entry.Rating {
+		case RatingHelpful, RatingNotHelpful, RatingInappropriate:
+		default:
+			continue
+		}
+		if example.query == nil {
+			example.query = service.AnalyzeQuery(entry.Query)
+		}
+		if features, ok := quoteFeatures[entry.Quote.ID]; ok {
+			example.quote = features
+		} else {
+			example.quote = service.AnalyzeQuery(entry.Quote.Text)
+		}
+
+		if isHeldOut(entry, options.Holdout) {
+			heldOut = append(heldOut, example)
+		} else {
+			train = append(train, example)
+		}
+	}
+
+	positives := 0
+	for _, example := range train {
+		if example.helpful {
+			positives++
+		}
+	}
+	if positives < minTrainingExamples || len(train)-positives < minTrainingExamples {
+		return nil, fmt.Errorf("not enough feedback to train: need at least %d helpful and %d unhelpful ratings outside the held-out share, have %d and %d",
+			minTrainingExamples, minTrainingExamples, positives, len(train)-positives)
+	}
+
+	start := *service.rankingWeights()
+	objective := func(weights RankingWeights) float64 {
+		scores := scoreExamples(service, &weights, train)
+		a, b := fitLogistic(scores, train)
+		return logLoss(scores, train, a, b) + trainingRegularization*weightDistance(weights, start)
+	}
+
+	weights := start
+	best := objective(weights)
+	step := 0.5
+	for round := 0; round < options.Rounds && step > 0.01; round++ {
+		improved := false
+		for i := range weights.params() {
+			for _, factor := range []float64{1 + step, 1 / (1 + step)} {
+				candidate := weights
+				param := candidate.params()[i]
+				*param = clampWeight(i, *param*factor)
+				if loss := objective(candidate); loss < best-1e-9 {
+					weights, best, improved = candidate, loss, true
+					break
+				}
+			}
+		}
+		if !improved {
+			step /= 2
+		}
+	}
+
+	report := &TrainReport{
+		Examples: len(train) + len(heldOut),
+		Training: len(train),
+		HeldOut:  len(heldOut),
+		Start:    start,
+		Weights:  weights,
+	}
+	report.Before = evaluateWeights(service, &start, train, heldOut)
+	report.After = evaluateWeights(service, &weights, train, heldOut)
+	return report, nil
+}
+
+// isHeldOut assigns an entry to the held-out share by hashing it, so the
+// split is the same on every run over the same feedback
+func isHeldOut(entry FeedbackEntry, share float64) bool {
+	if share <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d\x00%s\x00%s", entry.Time.UnixNano(), entry.Query, quoteRef(entry.Quote))
+	return float64(h.Sum32()%10000) < share*10000
+}
+
+func clampWeight(i int, value float64) float64 {
+	if isPenaltyParam(i) {
+		return math.Min(math.Max(value, 0.05), 1)
+	}
+	return math.Min(math.Max(value, 0.1), 20)
+}
+
+// weightDistance is the squared distance between two sets of weights on a
+// log scale, so doubling and halving a weight cost the same
+func weightDistance(a, b RankingWeights) float64 {
+	distance := 0.0
+	bp := b.params()
+	for i, p := range a.params() {
+		d := math.Log(*p / *bp[i])
+		distance += d * d
+	}
+	return distance
+}
+
+func scoreExamples(service *SemanticQuoteService, weights *RankingWeights, examples []trainingExample) []float64 {
+	scores := make([]float64, len(examples))
+	for i, example := range examples {
+		scores[i] = service.weightedBreakdown(weights, example.query, example.quote).Score
+	}
+	return scores
+}
+
+// evaluateWeights measures weights on the held-out examples, mapping scores
+// to probabilities with a curve fitted on the training examples. It returns
+// nil unless both helpful and unhelpful ratings were held out.
+func evaluateWeights(service *SemanticQuoteService, weights *RankingWeights, train, heldOut []trainingExample) *TrainMetrics {
+	scores := scoreExamples(service, weights, heldOut)
+	auc, ok := rankingAUC(scores, heldOut)
+	if !ok {
+		return nil
+	}
+	a, b := fitLogistic(scoreExamples(service, weights, train), train)
+	return &TrainMetrics{LogLoss: logLoss(scores, heldOut, a, b), AUC: auc}
+}
+
+// fitLogistic fits P(helpful) = sigmoid(a*score + b) by Newton's method
+func fitLogistic(scores []float64, examples []trainingExample) (a, b float64) {
+	for iteration := 0; iteration < 25; iteration++ {
+		var ga, gb, haa, hab, hbb float64
+		for i, score := range scores {
+			p := sigmoid(a*score + b)
+			y := 0.0
+			if examples[i].helpful {
+				y = 1
+			}
+			ga += (p - y) * score
+			gb += p - y
+			w := p * (1 - p)
+			haa += w * score * score
+			hab += w * score
+			hbb += w
+		}
+		// A small ridge keeps the step finite when the data separate perfectly
+		haa += 1e-3
+		hbb += 1e-3
+		det := haa*hbb - hab*hab
+		if det <= 0 {
+			break
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+		a -= da
+		b -= db
+		if math.Abs(da)+math.Abs(db) < 1e-9 {
+			break
+		}
+	}
+	return a, b
+}
+
+func logLoss(scores []float64, examples []trainingExample, a, b float64) float64 {
+	const epsilon = 1e-12
+	loss := 0.0
+	for i, score := range scores {
+		p := math.Min(math.Max(sigmoid(a*score+b), epsilon), 1-epsilon)
+		if examples[i].helpful {
+			loss -= math.Log(p)
+		} else {
+			loss -= math.Log(1 - p)
+		}
+	}
+	return loss / float64(len(scores))
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// rankingAUC is the share of helpful/unhelpful pairs in which the helpful
+// result scores higher, counting ties as half
+func rankingAUC(scores []float64, examples []trainingExample) (float64, bool) {
+	type scored struct {
+		score   float64
+		helpful bool
+	}
+	ranked := make([]scored, len(scores))
+	for i, score := range scores {
+		ranked[i] = scored{score, examples[i].helpful}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score < ranked[j].score })
+
+	// Sum of the positives' ranks, with tied scores sharing their mean rank
+	positives, negatives := 0, 0
+	rankSum := 0.0
+	for i := 0; i < len(ranked); {
+		j := i
+		for j < len(ranked) && ranked[j].score == ranked[i].score {
+			j++
+		}
+		meanRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if ranked[k].helpful {
+				positives++
+				rankSum += meanRank
+			} else {
+				negatives++
+			}
+		}
+		i = j
+	}
+	if positives == 0 || negatives == 0 {
+		return 0, false
+	}
+	return (rankSum - float64(positives*(positives+1))/2) / float64(positives*negatives), true
+}
+
+// Print compares the starting and fitted weights and their held-out metrics
+func (r *TrainReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "Feedback:  %d rated results (%d for training, %d held out)\n\n", r.Examples, r.Training, r.HeldOut)
+
+	if r.Before != nil && r.After != nil {
+		fmt.Fprintf(w, "%-22s %8s %8s\n", "Held out", "before", "after")
+		fmt.Fprintf(w, "%-22s %8.3f %8.3f\n", "  Log loss", r.Before.LogLoss, r.After.LogLoss)
+		fmt.Fprintf(w, "%-22s %8.3f %8.3f\n", "  Ranking AUC", r.Before.AUC, r.After.AUC)
+	} else {
+		fmt.Fprintln(w, "Held out: not measured (needs both helpful and unhelpful ratings held out)")
+	}
+	fmt.Fprintln(w)
+
+	names := []string{"emotion", "theme", "other", "negative_to_positive", "positive_to_negative", "neutral_to_emotional", "joy_conflict"}
+	start, fitted := r.Start.params(), r.Weights.params()
+	fmt.Fprintf(w, "%-22s %8s %8s\n", "Weights", "before", "after")
+	for i, name := range names {
+		fmt.Fprintf(w, "  %-20s %8.3f %8.3f\n", name, *start[i], *fitted[i])
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRankingAUC(t *testing.T) {
+	tests := []struct {
+		name    string
+		scores  []float64
+		helpful []bool
+		auc     float64
+		ok      bool
+	}{
+		{"separated", []float64{0.9, 0.8, 0.2, 0.1}, []bool{true, true, false, false}, 1, true},
+		{"reversed", []float64{0.1, 0.2, 0.8, 0.9}, []bool{true, true, false, false}, 0, true},
+		{"ties count half", []float64{0.5, 0.5}, []bool{true, false}, 0.5, true},
+		{"mixed", []float64{0.9, 0.5, 0.7, 0.1}, []bool{true, true, false, false}, 0.75, true},
+		{"helpful only", []float64{0.9, 0.1}, []bool{true, true}, 0, false},
+		{"empty", nil, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			examples := make([]trainingExample, len(tt.helpful))
+			for i, helpful := range tt.helpful {
+				examples[i].helpful = helpful
+			}
+			auc, ok := rankingAUC(tt.scores, examples)
+			if ok != tt.ok || math.Abs(auc-tt.auc) > 1e-9 {
+				t.Errorf("got %v, %v, want %v, %v", auc, ok, tt.auc, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFitLogistic(t *testing.T) {
+	scores := []float64{0.9, 0.7, 0.6, 0.4, 0.3, 0.1}
+	examples := make([]trainingExample, len(scores))
+	for i := range examples {
+		examples[i].helpful = i < 3 || i == 4
+	}
+
+	a, b := fitLogistic(scores, examples)
+	if a <= 0 {
+		t.Errorf("got slope %v, want higher scores to be more likely helpful", a)
+	}
+	if loss, chance := logLoss(scores, examples, a, b), math.Ln2; loss >= chance {
+		t.Errorf("got log loss %v, want below chance at %v", loss, chance)
+	}
+}
+
+// trainingFeedback rates the best results of each test query helpful and the
+// worst not helpful, the way a user agreeing with the ranking would
+func trainingFeedback(t *testing.T, service *SemanticQuoteService) []FeedbackEntry {
+	t.Helper()
+	var entries []FeedbackEntry
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, query := range testQueries {
+		results, err := service.Search(context.Background(), SearchRequest{Query: query, TopN: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) < 6 {
+			t.Fatalf("%q: got %d results, want at least 6", query, len(results))
+		}
+		rate := func(result SearchResult, rating string) {
+			at = at.Add(time.Minute)
+			entries = append(entries, FeedbackEntry{Time: at, Query: query, Quote: result.Quote, Rating: rating})
+		}
+		for _, result := range results[:3] {
+			rate(result, RatingHelpful)
+		}
+		for _, result := range results[len(results)-3:] {
+			rate(result, RatingNotHelpful)
+		}
+		rate(results[3], "") // unrated, left out
+	}
+	return entries
+}
+
+func TestTrainRankingWeights(t *testing.T) {
+	service, _ := newTestService(t, testCorpus(t, 40))
+	feedback := trainingFeedback(t, service)
+
+	tests := []struct {
+		name     string
+		entries  []FeedbackEntry
+		options  TrainOptions
+		examples int
+		ok       bool
+	}{
+		{"all for training", feedback, TrainOptions{}, 24, true},
+		{"held out", feedback, TrainOptions{Holdout: 0.2}, 24, true},
+		{"negative holdout", feedback, TrainOptions{Holdout: -0.1}, 0, false},
+		{"holdout of everything", feedback, TrainOptions{Holdout: 1}, 0, false},
+		{"too little feedback", feedback[:10], TrainOptions{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := TrainRankingWeights(service, tt.entries, tt.options)
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("got a report, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Examples != tt.examples || report.Training+report.HeldOut != report.Examples {
+				t.Errorf("got %d examples (%d training, %d held out), want %d", report.Examples, report.Training, report.HeldOut, tt.examples)
+			}
+			if tt.options.Holdout == 0 && report.HeldOut != 0 {
+				t.Errorf("held out %d examples without a holdout", report.HeldOut)
+			}
+			if report.Start != DefaultRankingWeights {
+				t.Errorf("started from %+v, want the defaults", report.Start)
+			}
+			if err := report.Weights.validate(); err != nil {
+				t.Errorf("fitted invalid weights %+v: %v", report.Weights, err)
+			}
+
+			again, err := TrainRankingWeights(service, tt.entries, tt.options)
+			if err != nil || !reflect.DeepEqual(again, report) {
+				t.Errorf("a second run over the same feedback gave a different report")
+			}
+		})
+	}
+}
+
+func TestIsHeldOut(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	held := 0
+	for i := 0; i < 1000; i++ {
+		entry := FeedbackEntry{Time: at.Add(time.Duration(i) * time.Minute), Query: "q"}
+		if isHeldOut(entry, 0.2) != isHeldOut(entry, 0.2) {
+			t.Fatal("the split is not deterministic")
+		}
+		if isHeldOut(entry, 0) {
+			t.Fatal("held out an entry with no holdout")
+		}
+		if isHeldOut(entry, 0.2) {
+			held++
+		}
+	}
+	if held < 150 || held > 250 {
+		t.Errorf("held out %d of 1000 entries, want about 200", held)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults for negative valence alerts
+const (
+	DefaultAlertWeeks    = 2
+	DefaultAlertValence  = -0.25
+	trendFeaturesPerWeek = 3
+)
+
+// TrendOptions tune when persistent negative valence raises an alert
+type TrendOptions struct {
+	AlertWeeks   int     // consecutive weeks it takes, at least 1
+	AlertValence float64 // a week's mean valence at or below this counts as negative
+}
+
+// FeatureCount is how many entries showed a feature, e.g. "emotion:sad"
+type FeatureCount struct {
+	Feature string `json:"feature"`
+	Entries int    `json:"entries"`
+}
+
+// WeekTrend summarizes the journal entries of one week, starting Monday
+type WeekTrend struct {
+	Week     string         `json:"week"` // the Monday, e.g. 2026-10-12
+	Entries  int            `json:"entries"`
+	Valence  float64        `json:"valence"` // mean from -1 (negative) to 1 (positive)
+	Emotions []FeatureCount `json:"emotions"`
+	Themes   []FeatureCount `json:"themes"`
+
+	start time.Time
+}
+
+// RecurringTheme is a theme that came up in more than one week
+type RecurringTheme struct {
+	Feature string `json:"feature"`
+	Weeks   int    `json:"weeks"`
+	Entries int    `json:"entries"`
+}
+
+// MoodAlert reports a run of weeks with negative valence
+type MoodAlert struct {
+	From    string  `json:"from"` // first and last week of the run
+	To      string  `json:"to"`
+	Weeks   int     `json:"weeks"`
+	Valence float64 `json:"valence"` // mean over the run
+	Ongoing bool    `json:"ongoing"` // the run includes the current week
+	Message string  `json:"message"`
+}
+
+// MoodTrends describes how the mood in a journal changed week by week
+type MoodTrends struct {
+	Entries         int              `json:"entries"`
+	Weeks           []WeekTrend      `json:"weeks"`
+	RecurringThemes []RecurringTheme `json:"recurring_themes"`
+	Alerts          []MoodAlert      `json:"alerts"`
+}
+
+// entryValence scores an entry from -1 to 1 by its sentiment words, falling
+// back to the valence recorded in its emotional context
+func entryValence(entry JournalEntry) float64 {
+	positive, negative := entry.Features["sentiment:positive"], entry.Features["sentiment:negative"]
+	if positive+negative > 0 {
+		return (positive - negative) / (positive + negative)
+	}
+	switch entry.Context.Valence {
+	case "positive":
+		return 1
+	case "negative":
+		return -1
+	}
+	return 0
+}
+
+// BuildMoodTrends groups journal entries by week. Weeks without entries are
+// left out and break a run of negative weeks. A run is ongoing only if it
+// reaches the week of now.
+func BuildMoodTrends(entries []JournalEntry, now time.Time, options TrendOptions) *MoodTrends {
+	if options.AlertWeeks < 1 {
+		options.AlertWeeks = DefaultAlertWeeks
+	}
+	trends := &MoodTrends{Entries: len(entries), Weeks: []WeekTrend{}, RecurringThemes: []RecurringTheme{}, Alerts: []MoodAlert{}}
+
+	type weekTally struct {
+		trend    WeekTrend
+		valence  float64
+		emotions map[string]int
+		themes   map[string]int
+	}
+	var weeks []*weekTally
+	byWeek := make(map[time.Time]*weekTally)
+	themeWeeks := make(map[string]int)
+	themeEntries := make(map[string]int)
+
+	for _, entry := range entries {
+		start := weekStart(entry.Time.Local())
+		week := byWeek[start]
+		if week == nil {
+			week = &weekTally{
+				trend:    WeekTrend{Week: start.Format("2006-01-02"), start: start},
+				emotions: make(map[string]int),
+				themes:   make(map[string]int),
+			}
+			byWeek[start] = week
+			weeks = append(weeks, week)
+		}
+		week.trend.Entries++
+		week.valence += entryValence(entry)
+
+		for _, emotion := range queryEmotions(entry.Features) {
+			if emotion != noEmotion {
+				week.emotions["emotion:"+emotion]++
+			}
+		}
+		if entry.Features == nil && entry.Context.PrimaryEmotion != "" {
+			week.emotions["emotion:"+entry.Context.PrimaryEmotion]++
+		}
+		for feature, weight := range entry.Features {
+			if strings.HasPrefix(feature, "theme:") && weight > 0 {
+				if week.themes[feature] == 0 {
+					themeWeeks[feature]++
+				}
+				week.themes[feature]++
+				themeEntries[feature]++
+			}
+		}
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].trend.start.Before(weeks[j].trend.start) })
+
+	for _, week := range weeks {
+		week.trend.Valence = week.valence / float64(week.trend.Entries)
+		week.trend.Emotions = topFeatures(week.emotions, trendFeaturesPerWeek)
+		week.trend.Themes = topFeatures(week.themes, trendFeaturesPerWeek)
+		trends.Weeks = append(trends.Weeks, week.trend)
+	}
+
+	for feature, count := range themeWeeks {
+		if count > 1 {
+			trends.RecurringThemes = append(trends.RecurringThemes, RecurringTheme{Feature: feature, Weeks: count, Entries: themeEntries[feature]})
+		}
+	}
+	sort.Slice(trends.RecurringThemes, func(i, j int) bool {
+		a, b := trends.RecurringThemes[i], trends.RecurringThemes[j]
+		if a.Weeks != b.Weeks {
+			return a.Weeks > b.Weeks
+		}
+		if a.Entries != b.Entries {
+			return a.Entries > b.Entries
+		}
+		return a.Feature < b.Feature
+	})
+
+	trends.Alerts = negativeRuns(trends.Weeks, weekStart(now.Local()), options)
+	return trends
+}
+
+// negativeRuns finds runs of consecutive calendar weeks whose valence is at
+// or below the alert level and that last at least the alert length. A run
+// that ends in the current week is ongoing.
+func negativeRuns(weeks []WeekTrend, current time.Time, options TrendOptions) []MoodAlert {
+	alerts := []MoodAlert{}
+	flush := func(run []WeekTrend) {
+		if len(run) < options.AlertWeeks {
+			return
+		}
+		ongoing := run[len(run)-1].start.Equal(current)
+		total := 0.0
+		for _, week := range run {
+			total += week.Valence
+		}
+		alert := MoodAlert{
+			From:    run[0].Week,
+			To:      run[len(run)-1].Week,
+			Weeks:   len(run),
+			Valence: total / float64(len(run)),
+			Ongoing: ongoing,
+		}
+		if ongoing {
+			alert.Message = fmt.Sprintf("Your entries have leaned negative for %d weeks in a row, since the week of %s. "+
+				"It may help to talk to someone you trust.", alert.Weeks, run[0].start.Format("2 Jan"))
+		} else {
+			alert.Message = fmt.Sprintf("Your entries leaned negative for %d weeks in a row, from the week of %s to the week of %s.",
+				alert.Weeks, run[0].start.Format("2 Jan"), run[len(run)-1].start.Format("2 Jan"))
+		}
+		alerts = append(alerts, alert)
+	}
+
+	var run []WeekTrend
+	for _, week := range weeks {
+		consecutive := len(run) > 0 && run[len(run)-1].start.AddDate(0, 0, 7).Equal(week.start)
+		if len(run) > 0 && (!consecutive || week.Valence > options.AlertValence) {
+			flush(run)
+			run = nil
+		}
+		if week.Valence <= options.AlertValence {
+			run = append(run, week)
+		}
+	}
+	if len(run) > 0 {
+		flush(run)
+	}
+	return alerts
+}
+
+// topFeatures lists the most frequent features, ties by name
+func topFeatures(counts map[string]int, n int) []FeatureCount {
+	features := make([]FeatureCount, 0, len(counts))
+	for feature, count := range counts {
+		features = append(features, FeatureCount{Feature: feature, Entries: count})
+	}
+	sort.Slice(features, func(i, j int) bool {
+		if features[i].Entries != features[j].Entries {
+			return features[i].Entries > features[j].Entries
+		}
+		return features[i].Feature < features[j].Feature
+	})
+	if len(features) > n {
+		features = features[:n]
+	}
+	return features
+}
+
+// valenceBarWidth is the number of cells on each side of the chart's axis
+const valenceBarWidth = 10
+
+// valenceBar draws valence as a bar growing left of the axis when negative
+// and right of it when positive
+func valenceBar(valence float64) string {
+	cells := int(valence*valenceBarWidth + 0.5*sign(valence))
+	left, right := strings.Repeat(" ", valenceBarWidth), strings.Repeat(" ", valenceBarWidth)
+	if cells < 0 {
+		left = strings.Repeat(" ", valenceBarWidth+cells) + strings.Repeat("█", -cells)
+	} else if cells > 0 {
+		right = strings.Repeat("█", cells) + strings.Repeat(" ", valenceBarWidth-cells)
+	}
+	return left + "│" + right
+}
+
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+// featureNames strips the feature kind, e.g. "emotion:sad" becomes "sad"
+func featureNames(features []FeatureCount) string {
+	names := make([]string, len(features))
+	for i, feature := range features {
+		_, names[i], _ = strings.Cut(feature.Feature, ":")
+	}
+	return strings.Join(names, ", ")
+}
+
+// Print draws the weekly valence as a chart beside the dominant emotions,
+// then lists the recurring themes and any alerts
+func (t *MoodTrends) Print(w io.Writer) {
+	if t.Entries == 0 {
+		fmt.Fprintln(w, "The journal has no entries for this period.")
+		return
+	}
+	fmt.Fprintf(w, "Mood trends: %d entries over %d week(s)\n\n", t.Entries, len(t.Weeks))
+
+	axis := strings.Repeat(" ", valenceBarWidth-4)
+	fmt.Fprintf(w, "%-12s %7s  %s  %s\n", "Week of", "Entries", "-"+axis+"valence"+axis+"+", "Dominant emotions")
+	for _, week := range t.Weeks {
+		emotions := featureNames(week.Emotions)
+		if emotions == "" {
+			emotions = "-"
+		}
+		fmt.Fprintf(w, "%-12s %7d  %s  %s\n", week.start.Format("02 Jan 2006"), week.Entries, valenceBar(week.Valence), emotions)
+	}
+
+	if len(t.RecurringThemes) > 0 {
+		fmt.Fprintln(w, "\nRecurring themes:")
+		for _, theme := range t.RecurringThemes {
+			fmt.Fprintf(w, "   %-20s %d weeks, %d entries\n", theme.Feature, theme.Weeks, theme.Entries)
+		}
+	}
+
+	for _, alert := range t.Alerts {
+		fmt.Fprintf(w, "\n⚠️  %s\n", alert.Message)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildMoodTrends(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local) // a Wednesday
+	entry := func(weeksAgo int, valence string, themes ...string) JournalEntry {
+		features := map[string]float64{"sentiment:" + valence: 1}
+		for _, theme := range themes {
+			features["theme:"+theme] = 1
+		}
+		return JournalEntry{Time: now.AddDate(0, 0, -7*weeksAgo), Features: features}
+	}
+
+	type alert struct {
+		from, to string
+		ongoing  bool
+	}
+	tests := []struct {
+		name      string
+		entries   []JournalEntry
+		options   TrendOptions
+		weeks     []string
+		alerts    []alert
+		recurring []string
+	}{
+		{
+			name: "no entries",
+		},
+		{
+			name:    "negative up to this week",
+			entries: []JournalEntry{entry(1, "negative"), entry(0, "negative")},
+			weeks:   []string{"2026-10-05", "2026-10-12"},
+			alerts:  []alert{{"2026-10-05", "2026-10-12", true}},
+		},
+		{
+			name:    "negative until last week with nothing since",
+			entries: []JournalEntry{entry(3, "negative"), entry(2, "negative")},
+			weeks:   []string{"2026-09-21", "2026-09-28"},
+			alerts:  []alert{{"2026-09-21", "2026-09-28", false}},
+		},
+		{
+			name:    "positive this week ends the run",
+			entries: []JournalEntry{entry(2, "negative"), entry(1, "negative"), entry(0, "positive")},
+			weeks:   []string{"2026-09-28", "2026-10-05", "2026-10-12"},
+			alerts:  []alert{{"2026-09-28", "2026-10-05", false}},
+		},
+		{
+			name:    "a week without entries breaks the run",
+			entries: []JournalEntry{entry(2, "negative"), entry(0, "negative")},
+			weeks:   []string{"2026-09-28", "2026-10-12"},
+		},
+		{
+			name:    "mixed week above the alert level",
+			entries: []JournalEntry{entry(1, "negative"), entry(0, "negative"), entry(0, "positive")},
+			weeks:   []string{"2026-10-05", "2026-10-12"},
+		},
+		{
+			name:    "one week is enough when asked",
+			entries: []JournalEntry{entry(1, "positive"), entry(0, "negative")},
+			options: TrendOptions{AlertWeeks: 1, AlertValence: DefaultAlertValence},
+			weeks:   []string{"2026-10-05", "2026-10-12"},
+			alerts:  []alert{{"2026-10-12", "2026-10-12", true}},
+		},
+		{
+			name:      "recurring themes",
+			entries:   []JournalEntry{entry(1, "positive", "work", "family"), entry(0, "positive", "work"), entry(0, "positive", "family", "work")},
+			weeks:     []string{"2026-10-05", "2026-10-12"},
+			recurring: []string{"theme:work", "theme:family"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if options == (TrendOptions{}) {
+				options = TrendOptions{AlertWeeks: DefaultAlertWeeks, AlertValence: DefaultAlertValence}
+			}
+			trends := BuildMoodTrends(tt.entries, now, options)
+
+			if trends.Entries != len(tt.entries) {
+				t.Errorf("got %d entries, want %d", trends.Entries, len(tt.entries))
+			}
+			var weeks []string
+			for _, week := range trends.Weeks {
+				weeks = append(weeks, week.Week)
+			}
+			if len(weeks) != len(tt.weeks) {
+				t.Fatalf("got weeks %q, want %q", weeks, tt.weeks)
+			}
+			for i := range weeks {
+				if weeks[i] != tt.weeks[i] {
+					t.Errorf("got weeks %q, want %q", weeks, tt.weeks)
+				}
+			}
+
+			var alerts []alert
+			for _, a := range trends.Alerts {
+				alerts = append(alerts, alert{a.From, a.To, a.Ongoing})
+			}
+			if len(alerts) != len(tt.alerts) {
+				t.Fatalf("got alerts %v, want %v", alerts, tt.alerts)
+			}
+			for i := range alerts {
+				if alerts[i] != tt.alerts[i] {
+					t.Errorf("got alerts %v, want %v", alerts, tt.alerts)
+				}
+			}
+
+			var recurring []string
+			for _, theme := range trends.RecurringThemes {
+				recurring = append(recurring, theme.Feature)
+			}
+			if len(recurring) != len(tt.recurring) {
+				t.Fatalf("got recurring themes %q, want %q", recurring, tt.recurring)
+			}
+			for i := range recurring {
+				if recurring[i] != tt.recurring[i] {
+					t.Errorf("got recurring themes %q, want %q", recurring, tt.recurring)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,738 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used by the terminal UI
+const (
+	ansiAltScreenOn  = "\x1b[?1049h"
+	ansiAltScreenOff = "\x1b[?1049l"
+	ansiHideCursor   = "\x1b[?25l"
+	ansiShowCursor   = "\x1b[?25h"
+	ansiHome         = "\x1b[H"
+	ansiClearLine    = "\x1b[K"
+	ansiReset        = "\x1b[0m"
+	ansiBold         = "\x1b[1m"
+	ansiDim          = "\x1b[2m"
+	ansiReverse      = "\x1b[7m"
+	ansiRed          = "\x1b[31m"
+	ansiYellow       = "\x1b[33m"
+)
+
+// Smallest terminal the layout can draw into
+const (
+	tuiMinRows = 16
+	tuiMinCols = 60
+)
+
+type tuiFocus int
+
+const (
+	focusInput tuiFocus = iota
+	focusResults
+)
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyEnter
+	keyBackspace
+	keyTab
+	keyEsc
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyCtrlC
+	keyCtrlD
+	keyCtrlU
+)
+
+type keyEvent struct {
+	code keyCode
+	r    rune
+}
+
+// TUI - Full-screen presentation layer on top of QuoteService
+type TUI struct {
+	service   QuoteService
+	favorites FavoritesStore
+	feedback  FeedbackStore
+	exposure  *ExposureTracker
+	profiles  *ProfileBuilder
+	topN      int
+	minScore  float64
+	filter    QuoteFilter
+
+	rows, cols int
+	focus      tuiFocus
+	input      []rune
+	query      string
+	results    []SearchResult
+	selected   int
+	scroll     int
+	message    string
+	status     string
+	crisis     bool
+
+	novelty      *Novelty           // exposure when the query was searched
+	profile      *PreferenceProfile // preferences when the query was searched
+	explanations map[int]*MatchExplanation
+	saved        map[int]bool
+	ratings      map[int]string
+}
+
+func NewTUI(service QuoteService, topN int, minScore float64) *TUI {
+	return &TUI{service: service, topN: topN, minScore: minScore}
+}
+
+// UseFavorites enables the favorite shortcut
+func (t *TUI) UseFavorites(store FavoritesStore) {
+	t.favorites = store
+}
+
+// UseFeedback enables the helpful / not helpful / inappropriate shortcuts
+func (t *TUI) UseFeedback(store FeedbackStore) {
+	t.feedback = store
+}
+
+// UseExposure remembers the quotes shown, so later searches favor quotes
+// not seen lately. The service must implement ContextSearcher.
+func (t *TUI) UseExposure(tracker *ExposureTracker) error {
+	if _, ok := t.service.(ContextSearcher); !ok && tracker != nil {
+		return fmt.Errorf("novelty ranking is not available for this search engine")
+	}
+	t.exposure = tracker
+	return nil
+}
+
+// UseProfile personalizes results with preferences learned from the
+// user's favorites and feedback. The service must implement ContextSearcher.
+func (t *TUI) UseProfile(builder *ProfileBuilder) error {
+	if _, ok := t.service.(ContextSearcher); !ok && builder != nil {
+		return fmt.Errorf("personalized ranking is not available for this search engine")
+	}
+	t.profiles = builder
+	return nil
+}
+
+// UseFilter restricts searches to quotes matching the filter
+func (t *TUI) UseFilter(filter QuoteFilter) error {
+	if _, ok := t.service.(FilteredSearcher); !ok && !filter.IsZero() {
+		return fmt.Errorf("filters are not available for this search engine")
+	}
+	t.filter = filter
+	return nil
+}
+
+// Run takes over the terminal until the user quits
+func (t *TUI) Run() error {
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.Restore()
+
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(out, ansiAltScreenOn+ansiHideCursor)
+	defer func() {
+		fmt.Fprint(out, ansiReset+ansiShowCursor+ansiAltScreenOff)
+		out.Flush()
+	}()
+
+	if t.rows, t.cols, err = term.Size(); err != nil {
+		return fmt.Errorf("failed to read terminal size: %w", err)
+	}
+	t.message = "Describe how you feel and press Enter."
+
+	keys := make(chan keyEvent, 16)
+	go readKeys(os.Stdin, keys)
+
+	for {
+		t.draw(out)
+		if err := out.Flush(); err != nil {
+			return err
+		}
+
+		select {
+		case <-term.Resized():
+			if rows, cols, err := term.Size(); err == nil {
+				t.rows, t.cols = rows, cols
+			}
+		case key, ok := <-keys:
+			if !ok || t.handleKey(key) {
+				return nil
+			}
+		}
+	}
+}
+
+// readKeys decodes raw terminal input into key events
+func readKeys(in *os.File, keys chan<- keyEvent) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	var pending []byte
+
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		for len(pending) > 0 {
+			key, size := decodeKey(pending)
+			if size == 0 {
+				break // incomplete UTF-8 sequence; wait for more input
+			}
+			pending = pending[size:]
+			keys <- key
+		}
+	}
+}
+
+func decodeKey(b []byte) (keyEvent, int) {
+	switch b[0] {
+	case '\r', '\n':
+		return keyEvent{code: keyEnter}, 1
+	case 127, 8:
+		return keyEvent{code: keyBackspace}, 1
+	case '\t':
+		return keyEvent{code: keyTab}, 1
+	case 3:
+		return keyEvent{code: keyCtrlC}, 1
+	case 4:
+		return keyEvent{code: keyCtrlD}, 1
+	case 21:
+		return keyEvent{code: keyCtrlU}, 1
+	case 27:
+		if len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+			sequences := map[string]keyCode{
+				"A": keyUp, "B": keyDown, "H": keyHome, "F": keyEnd,
+				"5~": keyPageUp, "6~": keyPageDown, "1~": keyHome, "4~": keyEnd,
+			}
+			for seq, code := range sequences {
+				if strings.HasPrefix(string(b[2:]), seq) {
+					return keyEvent{code: code}, 2 + len(seq)
+				}
+			}
+			// Unknown sequence: swallow it up to its final byte
+			for i := 2; i < len(b); i++ {
+				if b[i] >= 0x40 && b[i] <= 0x7e {
+					return keyEvent{code: keyRune}, i + 1
+				}
+			}
+			return keyEvent{code: keyRune}, len(b)
+		}
+		return keyEvent{code: keyEsc}, 1
+	}
+
+	if !utf8.FullRune(b) {
+		return keyEvent{}, 0
+	}
+	r, size := utf8.DecodeRune(b)
+	return keyEvent{code: keyRune, r: r}, size
+}
+
+// handleKey updates the state for a key press and reports whether to quit
+func (t *TUI) handleKey(key keyEvent) bool {
+	if key.code == keyCtrlC || key.code == keyCtrlD {
+		return true
+	}
+
+	// The crisis panel stays up until dismissed explicitly
+	if t.crisis {
+		if key.code == keyEsc || key.code == keyEnter {
+			t.crisis = false
+		}
+		return false
+	}
+
+	t.status = ""
+
+	switch key.code {
+	case keyTab:
+		if t.focus == focusInput && len(t.results) > 0 {
+			t.focus = focusResults
+		} else {
+			t.focus = focusInput
+		}
+		return false
+	case keyUp:
+		t.moveSelection(-1)
+		return false
+	case keyDown:
+		t.moveSelection(1)
+		return false
+	case keyPageUp:
+		t.moveSelection(-t.listHeight())
+		return false
+	case keyPageDown:
+		t.moveSelection(t.listHeight())
+		return false
+	}
+
+	if t.focus == focusResults {
+		return t.handleResultsKey(key)
+	}
+	t.handleInputKey(key)
+	return false
+}
+
+func (t *TUI) handleInputKey(key keyEvent) {
+	switch key.code {
+	case keyEnter:
+		t.search(strings.TrimSpace(string(t.input)))
+	case keyBackspace:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case keyCtrlU, keyEsc:
+		t.input = nil
+	case keyRune:
+		if unicode.IsPrint(key.r) {
+			t.input = append(t.input, key.r)
+		}
+	}
+}
+
+func (t *TUI) handleResultsKey(key keyEvent) bool {
+	switch key.code {
+	case keyHome:
+		t.moveSelection(-len(t.results))
+	case keyEnd:
+		t.moveSelection(len(t.results))
+	case keyEsc:
+		t.focus = focusInput
+	case keyRune:
+		switch key.r {
+		case 'q':
+			return true
+		case '/', 'i':
+			t.focus = focusInput
+		case 'k':
+			t.moveSelection(-1)
+		case 'j':
+			t.moveSelection(1)
+		case 'f', 's':
+			t.saveSelected()
+		case '+', 'y':
+			t.rateSelected(RatingHelpful)
+		case '-', 'n':
+			t.rateSelected(RatingNotHelpful)
+		case '!':
+			t.rateSelected(RatingInappropriate)
+		}
+	}
+	return false
+}
+
+func (t *TUI) search(query string) {
+	if query == "" {
+		return
+	}
+
+	t.query = query
+	t.results = nil
+	t.selected = 0
+	t.scroll = 0
+	t.message = ""
+	t.explanations = make(map[int]*MatchExplanation)
+	t.saved = make(map[int]bool)
+	t.ratings = make(map[int]string)
+
+	t.novelty, t.profile = nil, nil
+
+	var results []SearchResult
+	var err error
+	switch {
+	case t.exposure != nil || t.profiles != nil:
+		var readErr error
+		if t.exposure != nil {
+			if t.novelty, readErr = t.exposure.Novelty(); readErr != nil {
+				t.status = "Could not read the quotes shown before: " + readErr.Error()
+			}
+		}
+		if t.profiles != nil {
+			if t.profile, readErr = t.profiles.Profile(); readErr != nil {
+				t.status = "Could not learn your preferences: " + readErr.Error()
+			}
+		}
+		results, err = t.service.(ContextSearcher).Search(context.Background(), SearchRequest{
+			Query:   query,
+			TopN:    t.topN,
+			Filter:  t.filter,
+			Novelty: t.novelty,
+			Profile: t.profile,
+		})
+	case t.filter.IsZero():
+		results, err = t.service.SearchQuotes(query, t.topN)
+	default:
+		results, err = t.service.(FilteredSearcher).SearchFiltered(query, t.topN, t.filter)
+	}
+	if err == nil {
+		results, err = FilterConfident(results, t.minScore)
+	}
+	if err == nil && t.exposure != nil {
+		if recordErr := t.exposure.Record(results); recordErr != nil {
+			t.status = "Could not remember the quotes shown: " + recordErr.Error()
+		}
+	}
+
+	var lowConfidence *LowConfidenceError
+	switch {
+	case errors.Is(err, ErrCrisisDetected):
+		t.crisis = true
+		t.message = "Describe how you feel and press Enter."
+	case errors.As(err, &lowConfidence):
+		t.message = fmt.Sprintf("No quote really fits that yet (best match %.2f). Try telling me a bit more.", lowConfidence.BestScore)
+	case err != nil:
+		t.message = err.Error() + ". Try describing your feelings differently."
+	default:
+		t.results = results
+		t.focus = focusResults
+		t.input = nil
+	}
+}
+
+func (t *TUI) moveSelection(delta int) {
+	if len(t.results) == 0 {
+		return
+	}
+	t.selected += delta
+	if t.selected < 0 {
+		t.selected = 0
+	}
+	if t.selected >= len(t.results) {
+		t.selected = len(t.results) - 1
+	}
+}
+
+func (t *TUI) saveSelected() {
+	if len(t.results) == 0 {
+		return
+	}
+	if t.favorites == nil {
+		t.status = "Favorites are not available in this session."
+		return
+	}
+	added, err := t.favorites.Add(t.results[t.selected].Quote)
+	switch {
+	case err != nil:
+		t.status = "Could not save: " + err.Error()
+	case added:
+		t.status = "⭐ Saved to favorites."
+	default:
+		t.status = "⭐ Already in your favorites."
+	}
+	if err == nil {
+		t.saved[t.selected] = true
+	}
+}
+
+func (t *TUI) rateSelected(rating string) {
+	if len(t.results) == 0 {
+		return
+	}
+	if t.feedback == nil {
+		t.status = "Feedback is not available in this session."
+		return
+	}
+	err := t.feedback.Record(newFeedbackEntry(t.service, t.query, t.results[t.selected].Quote, rating, ""))
+	if err != nil {
+		t.status = "Could not record feedback: " + err.Error()
+		return
+	}
+	t.ratings[t.selected] = rating
+	t.status = "Thanks for the feedback."
+}
+
+func (t *TUI) explanation(i int) *MatchExplanation {
+	if cached, ok := t.explanations[i]; ok {
+		return cached
+	}
+	var explanation *MatchExplanation
+	if explainer, ok := t.service.(QuoteExplainer); ok {
+		explanation, _ = explainer.ExplainMatch(t.query, t.results[i].Quote)
+	}
+	if explanation != nil {
+		rankStages{novelty: t.novelty, profile: t.profile}.explainWith(t.service, explanation)
+	}
+	t.explanations[i] = explanation
+	return explanation
+}
+
+// Layout: title, prompt, input, separator, body, separator, status line
+func (t *TUI) listHeight() int {
+	return t.rows - 6
+}
+
+func (t *TUI) draw(out *bufio.Writer) {
+	fmt.Fprint(out, ansiHideCursor+ansiHome)
+
+	var lines []string
+	switch {
+	case t.rows < tuiMinRows || t.cols < tuiMinCols:
+		lines = []string{fmt.Sprintf("Please enlarge the terminal to at least %dx%d.", tuiMinCols, tuiMinRows)}
+	case t.crisis:
+		lines = t.crisisLines()
+	default:
+		lines = t.screenLines()
+	}
+
+	for i := 0; i < t.rows; i++ {
+		if i < len(lines) {
+			fmt.Fprint(out, lines[i])
+		}
+		fmt.Fprint(out, ansiReset+ansiClearLine)
+		if i < t.rows-1 {
+			fmt.Fprint(out, "\r\n")
+		}
+	}
+
+	if t.focus == focusInput && !t.crisis && t.rows >= tuiMinRows && t.cols >= tuiMinCols {
+		col := 3 + utf8.RuneCountInString(t.visibleInput())
+		fmt.Fprintf(out, "\x1b[3;%dH%s", col, ansiShowCursor)
+	}
+}
+
+func (t *TUI) visibleInput() string {
+	input := t.input
+	if width := t.cols - 4; len(input) > width {
+		input = input[len(input)-width:]
+	}
+	return string(input)
+}
+
+func (t *TUI) screenLines() []string {
+	lines := []string{
+		ansiReverse + pad(" Movie Quote Search Engine", t.cols-1),
+		ansiDim + "How are you feeling? Describe your situation:",
+		ansiBold + "> " + ansiReset + t.visibleInput(),
+		ansiDim + strings.Repeat("─", t.cols),
+	}
+
+	listWidth := t.cols * 11 / 20
+	detailWidth := t.cols - listWidth - 3
+	height := t.listHeight()
+
+	list := t.listLines(listWidth, height)
+	detail := t.detailLines(detailWidth)
+	for i := 0; i < height; i++ {
+		left := pad("", listWidth)
+		if i < len(list) {
+			left = list[i]
+		}
+		right := ""
+		if i < len(detail) {
+			right = detail[i]
+		}
+		lines = append(lines, left+ansiReset+ansiDim+" │ "+ansiReset+right)
+	}
+
+	help := "Enter search · Tab switch to results · Ctrl-C quit"
+	if t.focus == focusResults {
+		help = "↑/↓ select · f favorite · + helpful · - not helpful · ! inappropriate · Tab/Esc type · q quit"
+	}
+	lines = append(lines, ansiDim+strings.Repeat("─", t.cols))
+	if t.status != "" {
+		lines = append(lines, ansiYellow+truncate(t.status, t.cols))
+	} else {
+		lines = append(lines, ansiDim+truncate(help, t.cols))
+	}
+	return lines
+}
+
+func (t *TUI) listLines(width, height int) []string {
+	if len(t.results) == 0 {
+		return wrapText(t.message, width)
+	}
+
+	// Keep the selection visible
+	if t.selected < t.scroll {
+		t.scroll = t.selected
+	}
+	if t.selected >= t.scroll+height {
+		t.scroll = t.selected - height + 1
+	}
+
+	var lines []string
+	for i := t.scroll; i < len(t.results) && len(lines) < height; i++ {
+		result := t.results[i]
+		marker := " "
+		if t.saved[i] {
+			marker = "★"
+		}
+		switch t.ratings[i] {
+		case RatingHelpful:
+			marker += "▲"
+		case RatingNotHelpful:
+			marker += "▼"
+		case RatingInappropriate:
+			marker += "⚑"
+		default:
+			marker += " "
+		}
+
+		prefix := fmt.Sprintf("%s%2d. %.2f %s ", marker, i+1, result.Score, bar(result.Score, 8))
+		line := pad(prefix+result.Quote.Text, width)
+		switch {
+		case i == t.selected && t.focus == focusResults:
+			line = ansiReverse + line
+		case i == t.selected:
+			line = ansiBold + line
+		}
+		lines = append(lines, line+ansiReset)
+	}
+	return lines
+}
+
+func (t *TUI) detailLines(width int) []string {
+	if len(t.results) == 0 {
+		return nil
+	}
+
+	result := t.results[t.selected]
+	var lines []string
+	for _, line := range wrapText("\""+result.Quote.Text+"\"", width) {
+		lines = append(lines, ansiBold+line+ansiReset)
+	}
+	lines = append(lines, truncate("— "+result.Quote.Character, width))
+	lines = append(lines, ansiDim+truncate("  "+result.Quote.Movie, width)+ansiReset)
+	if summary := metadataSummary(result.Quote); summary != "" {
+		lines = append(lines, ansiDim+truncate("  "+summary, width)+ansiReset)
+	}
+	if result.Quote.Context != "" {
+		for _, line := range wrapText("  "+result.Quote.Context, width) {
+			lines = append(lines, ansiDim+line+ansiReset)
+		}
+	}
+	lines = append(lines, ansiDim+truncate("  "+result.Quote.ID, width)+ansiReset)
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("Score %.2f %s", result.Score, bar(result.Score, 12)))
+
+	explanation := t.explanation(t.selected)
+	if explanation == nil {
+		return lines
+	}
+
+	lines = append(lines, "", ansiDim+"Why it matched:"+ansiReset)
+	if len(explanation.Shared) == 0 {
+		lines = append(lines, truncate("Nothing in common with your words.", width))
+	}
+	maxContribution := 0.0
+	for _, shared := range explanation.Shared {
+		if shared.Contribution > maxContribution {
+			maxContribution = shared.Contribution
+		}
+	}
+	for i, shared := range explanation.Shared {
+		if i == 6 {
+			break
+		}
+		name := shared.Feature
+		if labelWidth := width - 12; labelWidth > 0 {
+			name = pad(truncate(name, labelWidth), labelWidth)
+		}
+		lines = append(lines, truncate(fmt.Sprintf("%s %s", name, bar(shared.Contribution/maxContribution, 10)), width))
+	}
+	lines = append(lines, "")
+	lines = append(lines, truncate(fmt.Sprintf("Sentiment: you %s, quote %s", explanation.QuerySentiment, explanation.QuoteSentiment), width))
+	if explanation.SentimentPenalty != 1 || explanation.TonePenalty != 1 {
+		lines = append(lines, truncate(fmt.Sprintf("Tone penalty ×%.1f", explanation.SentimentPenalty*explanation.TonePenalty), width))
+	}
+	if shown := t.novelty.Shown(result.Quote); shown > 0 {
+		lines = append(lines, truncate(fmt.Sprintf("Seen %d time(s) before ×%.2f", shown, explanation.NoveltyPenalty), width))
+	}
+	if boost := explanation.Profile; boost != nil && boost.Factor != 1 {
+		lines = append(lines, truncate(fmt.Sprintf("Your preferences ×%.2f", boost.Factor), width))
+	}
+	return lines
+}
+
+func (t *TUI) crisisLines() []string {
+	width := t.cols - 4
+	body := []string{
+		ansiRed + ansiBold + "⚠️  It sounds like you might be going through a really difficult time.",
+		"",
+	}
+	body = append(body, wrapText("While movie quotes can be inspiring, what you're experiencing may need professional support. Please consider reaching out:", width)...)
+	body = append(body, "", ansiBold+"🆘 CRISIS RESOURCES:", "")
+	for _, resource := range CrisisResources {
+		body = append(body, ansiBold+"• "+truncate(resource.Name, width-2))
+		for _, detail := range resource.Details {
+			body = append(body, "  "+truncate(detail, width-2))
+		}
+		body = append(body, "")
+	}
+	body = append(body, wrapText("You don't have to go through this alone. These trained professionals are available to listen and help, any time.", width)...)
+	body = append(body, "", ansiDim+"Press Enter or Esc to go back · Ctrl-C to quit")
+
+	lines := []string{ansiRed + ansiReverse + pad(" Support is available", t.cols-1)}
+	top := (t.rows - len(body)) / 2
+	for i := 1; i < top; i++ {
+		lines = append(lines, "")
+	}
+	for _, line := range body {
+		lines = append(lines, "  "+line)
+	}
+	return lines
+}
+
+// bar draws a horizontal bar for a value between 0 and 1
+func bar(value float64, width int) string {
+	if value < 0 {
+		value = 0
+	}
+	if value > 1 {
+		value = 1
+	}
+	filled := int(value*float64(width) + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+func pad(s string, width int) string {
+	s = truncate(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, truncate(line, width))
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, truncate(line, width))
+	}
+	return lines
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUsersDir is where the CLI keeps the data of users named with --user
+const DefaultUsersDir = "users"
+
+// maxUserIDLength bounds user IDs, which become folder names
+const maxUserIDLength = 64
+
+// User data errors
+var (
+	ErrInvalidUserID = errors.New("invalid user id")
+	ErrUserNotFound  = errors.New("no data stored for user")
+)
+
+// NormalizeUserID lowercases a user ID and checks that it is safe to use as
+// a folder name: letters, digits, and - _ . @ not at the start
+func NormalizeUserID(id string) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return "", fmt.Errorf("%w: the id cannot be empty", ErrInvalidUserID)
+	}
+	if len(id) > maxUserIDLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidUserID, maxUserIDLength)
+	}
+	for i, r := range id {
+		letter := r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+		if !letter && (i == 0 || !strings.ContainsRune("-_.@", r)) {
+			return "", fmt.Errorf("%w %q: use letters, digits, - _ . and @, starting with a letter or digit", ErrInvalidUserID, id)
+		}
+	}
+	return id, nil
+}
+
+// UserStores are where one user's data is kept. A nil store is disabled.
+type UserStores struct {
+	Favorites CollectionStore
+	Feedback  FeedbackStore
+	Journal   Journal
+	Exposure  ExposureStore
+}
+
+// Purge drops the feedback, journal entries and quotes shown from before a
+// time, returning how much was dropped
+func (s UserStores) Purge(before time.Time) (int, error) {
+	total := 0
+	for _, store := range []any{s.Feedback, s.Journal, s.Exposure} {
+		n, err := purge(store, before)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// UserFiles name the files of a user's data within their folder; an empty
+// name leaves that store out
+type UserFiles struct {
+	Favorites string
+	Feedback  string
+	Journal   string
+	Exposure  string
+}
+
+// DefaultUserFiles are the names the users command reads unless told otherwise
+var DefaultUserFiles = UserFiles{
+	Favorites: "favorites.json",
+	Feedback:  "feedback.jsonl",
+	Journal:   "journal.jsonl",
+	Exposure:  "exposure.json",
+}
+
+// UserData keeps each user's favorites, feedback, journal and the quotes
+// they were shown in a folder of their own, so no user's data is mixed with
+// another's and all of it can be exported or deleted together. Safe for
+// concurrent use within one process.
+type UserData struct {
+	dir     string
+	files   UserFiles
+	privacy PrivacyPolicy
+
+	mu   sync.Mutex
+	open map[string]UserStores // by user, so each file has one store
+}
+
+func NewUserData(dir string, files UserFiles) *UserData {
+	return &UserData{dir: dir, files: files, open: make(map[string]UserStores)}
+}
+
+// UsePrivacy applies a privacy policy to every user's stores
+func (d *UserData) UsePrivacy(policy PrivacyPolicy) {
+	d.privacy = policy
+}
+
+// Dir is the folder of a user's data. It is only created once something is
+// written, but the folder holding every user's is created here, private to
+// the account running the engine.
+func (d *UserData) Dir(user string) (string, error) {
+	user, err := NormalizeUserID(user)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(d.dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", d.dir, err)
+	}
+	return filepath.Join(d.dir, user), nil
+}
+
+// Stores opens a user's stores
+func (d *UserData) Stores(user string) (UserStores, error) {
+	dir, err := d.Dir(user)
+	if err != nil {
+		return UserStores{}, err
+	}
+	user = filepath.Base(dir)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if stores, ok := d.open[user]; ok {
+		return stores, nil
+	}
+
+	var stores UserStores
+	if d.files.Favorites != "" {
+		stores.Favorites = NewFileFavoritesStore(filepath.Join(dir, d.files.Favorites))
+	}
+	if d.files.Feedback != "" {
+		stores.Feedback = NewFileFeedbackStore(filepath.Join(dir, d.files.Feedback))
+	}
+	if d.files.Journal != "" {
+		stores.Journal = NewFileJournal(filepath.Join(dir, d.files.Journal))
+	}
+	if d.files.Exposure != "" {
+		stores.Exposure = NewFileExposureStore(filepath.Join(dir, d.files.Exposure))
+	}
+	stores = d.privacy.Stores(stores)
+	d.open[user] = stores
+	return stores, nil
+}
+
+// Users lists the users with data stored, by ID
+func (d *UserData) Users() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	var users []string
+	for _, entry := range entries {
+		if _, err := NormalizeUserID(entry.Name()); err == nil && entry.IsDir() {
+			users = append(users, entry.Name())
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// UserExport is everything stored for one user
+type UserExport struct {
+	User        string                 `json:"user"`
+	ExportedAt  time.Time              `json:"exported_at"`
+	Collections map[string][]Quote     `json:"collections"` // the favorites and named collections
+	Feedback    []FeedbackEntry        `json:"feedback"`
+	Journal     []JournalEntry         `json:"journal"`
+	Shown       map[string][]time.Time `json:"shown"` // when each quote was shown, by quote ID
+	Files       []string               `json:"files"` // every file in the user's folder
+}
+
+// Export reads back all of a user's data
+func (d *UserData) Export(user string) (*UserExport, error) {
+	user, err := NormalizeUserID(user)
+	if err != nil {
+		return nil, err
+	}
+	files, err := d.listFiles(user)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := d.Stores(user)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserExport{
+		User:        user,
+		ExportedAt:  time.Now(),
+		Collections: map[string][]Quote{},
+		Feedback:    []FeedbackEntry{},
+		Journal:     []JournalEntry{},
+		Shown:       map[string][]time.Time{},
+		Files:       files,
+	}
+	if stores.Favorites != nil {
+		summaries, err := stores.Favorites.Collections()
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			if summary.Quotes == 0 {
+				continue
+			}
+			if export.Collections[summary.Name], err = stores.Favorites.Collection(summary.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if stores.Feedback != nil {
+		entries, err := stores.Feedback.List()
+		if err != nil {
+			return nil, err
+		}
+		export.Feedback = append(export.Feedback, entries...)
+	}
+	if stores.Journal != nil {
+		entries, err := stores.Journal.Entries(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		export.Journal = append(export.Journal, entries...)
+	}
+	if stores.Exposure != nil {
+		shown, err := stores.Exposure.Shown(user)
+		if err != nil {
+			return nil, err
+		}
+		for id, times := range shown {
+			export.Shown[id] = times
+		}
+	}
+	return export, nil
+}
+
+// Purge drops every user's feedback, journal entries and quotes shown from
+// before a time, returning how much was dropped
+func (d *UserData) Purge(before time.Time) (int, error) {
+	users, err := d.Users()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, user := range users {
+		stores, err := d.Stores(user)
+		if err != nil {
+			return total, err
+		}
+		n, err := stores.Purge(before)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("failed to purge the data of %s: %w", user, err)
+		}
+	}
+	return total, nil
+}
+
+// Delete removes a user's folder and everything in it
+func (d *UserData) Delete(user string) error {
+	user, err := NormalizeUserID(user)
+	if err != nil {
+		return err
+	}
+	if _, err := d.listFiles(user); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.open, user)
+	if err := os.RemoveAll(filepath.Join(d.dir, user)); err != nil {
+		return fmt.Errorf("failed to delete the data of %s: %w", user, err)
+	}
+	return nil
+}
+
+// listFiles lists the files in a user's folder, reporting ErrUserNotFound when
+// there is no folder
+func (d *UserData) listFiles(user string) ([]string, error) {
+	dir := filepath.Join(d.dir, user)
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, user)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the data of %s: %w", user, err)
+	}
+	if files == nil {
+		files = []string{}
+	}
+	return files, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizeUserID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+		ok   bool
+	}{
+		{"sam", "sam", true},
+		{"  Sam@Example.com ", "sam@example.com", true},
+		{"team-1_a.b", "team-1_a.b", true},
+		{"", "", false},
+		{".hidden", "", false},
+		{"../etc", "", false},
+		{"a/b", "", false},
+		{"sam smith", "", false},
+		{"0123456789012345678901234567890123456789012345678901234567890123", "0123456789012345678901234567890123456789012345678901234567890123", true},
+		{"01234567890123456789012345678901234567890123456789012345678901234", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeUserID(tt.id)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("NormalizeUserID(%q) = %q, %v, want %q", tt.id, got, err, tt.want)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidUserID) {
+			t.Errorf("NormalizeUserID(%q) = %q, %v, want an invalid user id", tt.id, got, err)
+		}
+	}
+}
+
+func TestUserDataExportAndDelete(t *testing.T) {
+	files := UserFiles{Favorites: "saved.json", Feedback: "ratings.jsonl", Journal: "mood.jsonl", Exposure: "seen.json"}
+	data := NewUserData(t.TempDir(), files)
+	quote := Quote{ID: "q-1", Text: "Just keep swimming.", Movie: "Finding Nemo", Character: "Dory"}
+	now := time.Now()
+
+	stores, err := data.Stores("Sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stores.Favorites.Add(quote); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Feedback.Record(FeedbackEntry{Time: now, Query: "keep going", Quote: quote, Rating: RatingHelpful}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stores.Journal.Log(JournalEntry{Time: now, Query: "keep going"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := stores.Exposure.RecordShown("sam", []string{quote.ID}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.Stores("alex"); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := data.Export("sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Collections) != 1 || len(export.Feedback) != 1 || len(export.Journal) != 1 || len(export.Shown[quote.ID]) != 1 {
+		t.Errorf("exported %d collections, %d feedback, %d journal entries and %d showings, want one of each",
+			len(export.Collections), len(export.Feedback), len(export.Journal), len(export.Shown[quote.ID]))
+	}
+	if len(export.Files) != 4 {
+		t.Errorf("exported files %q, want the 4 written", export.Files)
+	}
+
+	users, err := data.Users()
+	if err != nil || len(users) != 1 || users[0] != "sam" {
+		t.Errorf("got users %q, %v, want only sam, who has data", users, err)
+	}
+	if err := data.Delete("SAM"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.Export("sam"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("exporting a deleted user: got error %v, want %v", err, ErrUserNotFound)
+	}
+	if err := data.Delete("sam"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("deleting a deleted user: got error %v, want %v", err, ErrUserNotFound)
+	}
+}
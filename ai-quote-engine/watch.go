@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// fingerprintFiles summarizes the files a reload would read. Adding or
+// removing a file in a quotes directory changes it as well as editing one.
+func fingerprintFiles(quotesPath, lexiconFile string) (string, error) {
+	files, err := resolveQuoteFiles(quotesPath)
+	if err != nil {
+		return "", err
+	}
+	if lexiconFile != "" {
+		files = append(files, lexiconFile)
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s\t%d\t%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// WatchFiles polls the quotes and lexicon files every interval and reloads
+// when they change, until ctx is done. report receives the outcome of each
+// reload; a failed reload keeps the current quotes and is retried on the
+// next change.
+func (s *SemanticQuoteService) WatchFiles(ctx context.Context, interval time.Duration, report func(error)) {
+	last, _ := fingerprintFiles(s.watchedFiles())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := fingerprintFiles(s.watchedFiles())
+		if err != nil || current == last {
+			// A file that is briefly missing is usually being saved; look again next tick
+			continue
+		}
+		last = current
+		report(s.Reload())
+	}
+}
+
+// autoReload reloads the service on SIGHUP and, when interval is positive,
+// whenever its files change. The returned function stops both.
+func autoReload(service *SemanticQuoteService, interval time.Duration, report func(error)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	notifyReload(signals)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				report(service.Reload())
+			}
+		}
+	}()
+
+	if interval > 0 {
+		go service.WatchFiles(ctx, interval, report)
+	}
+
+	return func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
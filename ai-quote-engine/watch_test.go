@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchFilesReloadsDuringSearches(t *testing.T) {
+	quotes := testCorpus(t, 200)
+	service, filename := newTestService(t, quotes[:100])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan error, 10)
+	go service.WatchFiles(ctx, 5*time.Millisecond, func(err error) { reloads <- err })
+	// Let the watcher take its first look before anything changes
+	time.Sleep(50 * time.Millisecond)
+
+	stop := make(chan struct{})
+	searches := searchUntil(t, service, stop)
+	for _, n := range []int{150, 200} {
+		writeTestQuotes(t, filename, quotes[:n])
+		select {
+		case err := <-reloads:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the change was not picked up")
+		}
+		if got := len(service.Index()); got != n {
+			t.Errorf("got %d quotes after the reload, want %d", got, n)
+		}
+	}
+	close(stop)
+	searches.Wait()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RankingWeights are the tunable numbers in calculateSimilarity: how much
+// each kind of feature counts towards the cosine, and the penalties applied
+// to mismatched sentiment and tone. The defaults are the hand-tuned values;
+// the train command fits them to collected feedback.
+type RankingWeights struct {
+	Emotion float64 `json:"emotion"` // emotion:* features
+	Theme   float64 `json:"theme"`   // theme:* features
+	Other   float64 `json:"other"`   // sentiment:* and tone:* features
+
+	// Score multipliers, between 0 and 1
+	NegativeToPositive float64 `json:"negative_to_positive"` // negative query, positive quote
+	PositiveToNegative float64 `json:"positive_to_negative"` // positive query, negative quote
+	NeutralToEmotional float64 `json:"neutral_to_emotional"` // neutral query, emotional quote
+	JoyConflict        float64 `json:"joy_conflict"`         // joyful query, quote about challenge or truth
+}
+
+// DefaultRankingWeights are used unless a weights file is given
+var DefaultRankingWeights = RankingWeights{
+	Emotion:            3.0,
+	Theme:              2.5,
+	Other:              1.0,
+	NegativeToPositive: 0.4,
+	PositiveToNegative: 0.3,
+	NeutralToEmotional: 0.8,
+	JoyConflict:        0.3,
+}
+
+// featureWeight is the multiplier for one feature
+func (w *RankingWeights) featureWeight(feature string) float64 {
+	switch {
+	case strings.HasPrefix(feature, "emotion:"):
+		return w.Emotion
+	case strings.HasPrefix(feature, "theme:"):
+		return w.Theme
+	}
+	return w.Other
+}
+
+// params lists the weights in a fixed order, for the trainer to adjust
+func (w *RankingWeights) params() []*float64 {
+	return []*float64{
+		&w.Emotion, &w.Theme, &w.Other,
+		&w.NegativeToPositive, &w.PositiveToNegative, &w.NeutralToEmotional, &w.JoyConflict,
+	}
+}
+
+// isPenaltyParam reports whether params()[i] is a multiplier capped at 1
+func isPenaltyParam(i int) bool {
+	return i >= 3
+}
+
+func (w RankingWeights) validate() error {
+	if w.Emotion <= 0 || w.Theme <= 0 || w.Other <= 0 {
+		return fmt.Errorf("feature weights must be positive")
+	}
+	for i, p := range w.params() {
+		if isPenaltyParam(i) && (*p <= 0 || *p > 1) {
+			return fmt.Errorf("penalties must be greater than 0 and at most 1")
+		}
+	}
+	return nil
+}
+
+// LoadRankingWeights reads a weights file written by the train command.
+// Weights omitted from the file keep their defaults.
+func LoadRankingWeights(filename string) (*RankingWeights, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open weights file: %w", err)
+	}
+	defer file.Close()
+
+	weights := DefaultRankingWeights
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&weights); err != nil {
+		return nil, fmt.Errorf("failed to parse weights file: %w", err)
+	}
+	if err := weights.validate(); err != nil {
+		return nil, fmt.Errorf("invalid weights file %s: %w", filename, err)
+	}
+	return &weights, nil
+}
+
+// UseWeights replaces the ranking weights. Like UseWorkers, it must be called
+// before the service is shared.
+func (s *SemanticQuoteService) UseWeights(weights RankingWeights) error {
+	if err := weights.validate(); err != nil {
+		return err
+	}
+	s.weights = &weights
+	return nil
+}
+
+// rankingWeights are the weights searches use
+func (s *SemanticQuoteService) rankingWeights() *RankingWeights {
+	if s.weights != nil {
+		return s.weights
+	}
+	return &DefaultRankingWeights
+}